@@ -0,0 +1,40 @@
+// clock tells time in a way that can be faked in tests.
+package clock
+
+import "time"
+
+// Clock tells the time and waits for time to pass.
+type Clock interface {
+	// Now gets the current time.
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine once d has passed.
+	AfterFunc(d time.Duration, f func()) Timer
+	// After sends the time on the returned channel once d has passed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// Timer is a pending call of Clock.AfterFunc().
+type Timer interface {
+	// Stop prevents the call if it's still pending. It reports whether
+	// the call was stopped.
+	Stop() bool
+}
+
+// Real gets the Clock of the system, backed by the time package.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
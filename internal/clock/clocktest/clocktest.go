@@ -0,0 +1,157 @@
+// clocktest provides a fake clock for tests.
+//
+// Time only moves when a Clock is advanced, firing the timers that come
+// due along the way in order:
+//
+//	clk := clocktest.NewClock(time.Date(2024, 3, 10, 0, 30, 0, 0, loc))
+//	clk.AfterFunc(time.Hour, f)
+//	clk.Advance(time.Hour) // f is called
+package clocktest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/clock"
+)
+
+// Clock is a clock.Clock whose time only moves when it's advanced.
+type Clock struct {
+	mu      sync.Mutex
+	changed *sync.Cond // Broadcast when timers are added
+	now     time.Time
+	timers  []*timer
+}
+
+// timer is a pending call of AfterFunc() or send of After().
+type timer struct {
+	clk  *Clock
+	when time.Time
+	fire func(now time.Time)
+}
+
+// NewClock creates a Clock starting at now.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now gets the time the clock is at.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc calls f in its own goroutine once the clock is advanced by d.
+func (c *Clock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	return c.add(d, func(time.Time) { go f() })
+}
+
+// After sends the time on the returned channel once the clock is
+// advanced by d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.add(d, func(now time.Time) { ch <- now })
+	return ch
+}
+
+// add adds a timer that fires once d has passed. It fires right away if
+// d isn't positive.
+func (c *Clock) add(d time.Duration, fire func(now time.Time)) *timer {
+	c.mu.Lock()
+	t := &timer{clk: c, when: c.now.Add(d), fire: fire}
+	if d <= 0 {
+		now := c.now
+		c.mu.Unlock()
+		fire(now)
+		return t
+	}
+	c.timers = append(c.timers, t)
+	c.changed.Broadcast()
+	c.mu.Unlock()
+	return t
+}
+
+// Stop removes the timer if it hasn't fired yet.
+func (t *timer) Stop() bool {
+	c := t.clk
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.Index(c.timers, t)
+	if i == -1 {
+		return false
+	}
+	c.timers = slices.Delete(c.timers, i, i+1)
+	return true
+}
+
+// Advance moves the clock forward by d. Timers that come due are fired in
+// order, with the clock at the time each was due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		i := c.earliest()
+		if i == -1 || c.timers[i].when.After(target) {
+			break
+		}
+		t := c.timers[i]
+		c.timers = slices.Delete(c.timers, i, i+1)
+		c.now = t.when
+
+		c.mu.Unlock()
+		t.fire(t.when)
+		c.mu.Lock()
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+// AdvanceTo moves the clock forward to t, like Advance().
+func (c *Clock) AdvanceTo(t time.Time) {
+	c.Advance(t.Sub(c.Now()))
+}
+
+// Next gets when the earliest pending timer is due. ok is false if there
+// are no pending timers.
+func (c *Clock) Next() (next time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.earliest()
+	if i == -1 {
+		return time.Time{}, false
+	}
+	return c.timers[i].when, true
+}
+
+// Timers gets the number of pending timers.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil waits until at least n timers are pending. It's useful for
+// waiting on goroutines that schedule work on the clock.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+// earliest gets the index of the earliest pending timer, or -1 if there
+// isn't one. c.mu must be held.
+func (c *Clock) earliest() int {
+	i := -1
+	for j, t := range c.timers {
+		if i == -1 || t.when.Before(c.timers[i].when) {
+			i = j
+		}
+	}
+	return i
+}
@@ -1,28 +1,31 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
 	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
 )
 
 // NewAddApps creates /add_apps <appid>,<appid>,...
+// Packages and bundles are given as sub/<id> and bundle/<id>, or as store
+// URLs.
 func NewAddApps() Cmd {
 	min := float64(1)
 	return Cmd{
 		Name:        "add_apps",
-		Description: "Add apps by their appid to the tracker",
+		Description: "Add apps, packages, or bundles by their id or store URL to the tracker",
 		Handle:      addAppsHandler,
 		Options: []*discordgo.ApplicationCommandOption{
 			{
 				Type: discordgo.ApplicationCommandOptionString,
 				Name: "appids",
 				Description: "Comma separated appids to add to tracker. " +
-					"E.g., 400,440,1868140",
+					"E.g., 400,440,sub/469,bundle/232",
 				Required:  true,
 				MaxLength: 150,
 			},
@@ -34,16 +37,17 @@ func NewAddApps() Cmd {
 				MaxValue:    99,
 			},
 		},
+		ManagerOnly: true,
 	}
 }
 
 // addAppsHandler is the handler for the add_apps command
-func addAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func addAppsHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
 	DeferMsgReply(s, i)
 
 	// Parse appids
 	strs := strings.Split(i.ApplicationCommandData().Options[0].StringValue(), ",")
-	succApps, invalidAppids := strsToApps(strs)
+	succApps, invalidAppids := strsToApps(ctx, strs)
 	if len(i.ApplicationCommandData().Options) > 1 {
 		saleThreshold := int(i.ApplicationCommandData().Options[1].IntValue())
 		for _, app := range succApps {
@@ -59,13 +63,13 @@ func addAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 
 	// Add and create embed reply
-	succApps, failApps := db.AddApps(guildID, succApps)
+	succApps, failApps := store.AddApps(ctx, guildID, succApps)
 	em := &discordgo.MessageEmbed{Title: "Add Apps"}
 
 	// Add successful apps field
 	sb := strings.Builder{}
 	for _, app := range succApps {
-		sb.WriteString(fmt.Sprintf("%s (%d)\n", app.Name, app.Appid))
+		sb.WriteString(fmt.Sprintf("%s (%s)\n", app.Name, app.Item()))
 	}
 	if succStr := sb.String(); succStr != "" {
 		em.Fields = append(em.Fields, &discordgo.MessageEmbedField{
@@ -80,7 +84,7 @@ func addAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		sb.WriteString(id + "\n")
 	}
 	for _, app := range failApps {
-		sb.WriteString(strconv.Itoa(app.Appid) + "\n")
+		sb.WriteString(app.Item().String() + "\n")
 	}
 	if failStr := sb.String(); failStr != "" {
 		em.Fields = append(em.Fields, &discordgo.MessageEmbedField{
@@ -95,32 +99,54 @@ func addAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	EditReply(s, i, &discordgo.WebhookEdit{
 		Embeds: &[]*discordgo.MessageEmbed{em},
 	})
+
+	if len(succApps) > 0 {
+		added := make([]string, 0, len(succApps))
+		for _, app := range succApps {
+			added = append(added, fmt.Sprintf("%s (%s)", app.Name, app.Item()))
+		}
+		recordAudit(ctx, s, i, "add_apps", "", strings.Join(added, ", "))
+	}
 }
 
 // strsToApps iterates through ss and, tries to create
 // valid App's with them. Valid apps need to have the price_overview
-// field set or haven't been released yet
-func strsToApps(ss []string) (succ []*steam.App, fail []string) {
+// field set or haven't been released yet. See steam.ParseItem() for
+// how each of ss may reference an app, package, or bundle.
+func strsToApps(ctx context.Context, ss []string) (succ []*steam.App, fail []string) {
 	for _, s := range ss {
 		s = strings.TrimSpace(s)
 
-		// Check convertible to int
-		appid, err := strconv.Atoi(s)
-		if err != nil || appid <= 0 {
+		item, err := steam.ParseItem(s)
+		if err != nil {
 			fail = append(fail, s)
 			continue
 		}
 
-		// Check appid references a real App
-		// and is priced or hasn't released yet
-		app, err := steam.NewApp(appid)
-		if err != nil || (app.Initial == "" && app.Final == "" && !app.ComingSoon) {
+		app, err := newTrackableApp(ctx, item)
+		if err != nil {
 			fail = append(fail, s)
 			continue
 		}
 
-		succ = append(succ, &app)
+		succ = append(succ, app)
 	}
 
 	return succ, fail
 }
+
+var errUntrackable = errors.New("app isn't priced and has already released")
+
+// newTrackableApp creates an App for item if it references a real App
+// that is priced or hasn't released yet.
+func newTrackableApp(ctx context.Context, item steam.Item) (*steam.App, error) {
+	app, err := steam.NewItem(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	if !app.Trackable() {
+		return nil, errUntrackable
+	}
+
+	return &app, nil
+}
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+)
+
+// maxAddAppsFileLines is the most lines of a file /add_apps_file processes.
+const maxAddAppsFileLines = 1000
+
+// storeURLRegex matches the kind and id in a Steam store or community URL
+// of an app, package, or bundle.
+var storeURLRegex = regexp.MustCompile(
+	`(?:store\.steampowered\.com|steamcommunity\.com)/(app|sub|bundle)/(\d+)`)
+
+// addAppsFileJobs are the guilds with an /add_apps_file job running.
+var addAppsFileJobs = struct {
+	sync.Mutex
+	m map[int64]bool
+}{m: map[int64]bool{}}
+
+// NewAddAppsFile creates /add_apps_file <file> <threshold>.
+func NewAddAppsFile() Cmd {
+	min := float64(1)
+	return Cmd{
+		Name:        "add_apps_file",
+		Description: "Add apps to the tracker from a text or CSV file of appids, store URLs, or names",
+		Handle:      addAppsFileHandler,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionAttachment,
+				Name:        "file",
+				Description: "A text or CSV file with an appid, store URL, or app name on each line",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "threshold",
+				Description: "The minimum discount required to trigger a sale alert for these apps specifically",
+				MinValue:    &min,
+				MaxValue:    99,
+			},
+		},
+		ManagerOnly: true,
+	}
+}
+
+func addAppsFileHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	reply := func(description string) {
+		EditReply(s, i, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{
+				{
+					Title:       "Add Apps File",
+					Description: description,
+				},
+			},
+		})
+	}
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Parse options
+	opts := i.ApplicationCommandData().Options
+	att, err := attachmentValue(i, opts[0])
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+	var saleThreshold *int
+	if len(opts) > 1 {
+		threshold := int(opts[1].IntValue())
+		saleThreshold = &threshold
+	}
+
+	// Read lines
+	data, err := downloadAttachment(att)
+	if err != nil {
+		reply("Failed to download the file, please try again")
+		return
+	}
+	lines := fileLines(data)
+	if len(lines) == 0 {
+		reply("The file is empty")
+		return
+	}
+	if len(lines) > maxAddAppsFileLines {
+		reply(fmt.Sprintf("The file has too many lines, the most allowed is %d",
+			maxAddAppsFileLines))
+		return
+	}
+
+	// Only one job per guild at a time
+	addAppsFileJobs.Lock()
+	if addAppsFileJobs.m[guildID] {
+		addAppsFileJobs.Unlock()
+		reply("A file is already being added for this server, please wait for it to finish")
+		return
+	}
+	addAppsFileJobs.m[guildID] = true
+	addAppsFileJobs.Unlock()
+
+	// Add in the background, updating the reply as lines are processed
+	ctx = jobContext(ctx)
+	go func() {
+		defer func() {
+			addAppsFileJobs.Lock()
+			delete(addAppsFileJobs.m, guildID)
+			addAppsFileJobs.Unlock()
+		}()
+
+		progress := newProgressReporter(s, i, func(done, total int) *discordgo.MessageEmbed {
+			return &discordgo.MessageEmbed{
+				Title:       "Add Apps File",
+				Description: fmt.Sprintf("Processed %d of %d lines...", done, total),
+				Footer: &discordgo.MessageEmbedFooter{
+					Text: "Note: Adding may pause if Steam rate limits requests",
+				},
+			}
+		})
+
+		results := bytes.Buffer{}
+		added, failed := []string{}, 0
+		for idx, line := range lines {
+			app, err := addAppFromLine(ctx, guildID, line, saleThreshold)
+			if err != nil {
+				failed++
+				fmt.Fprintf(&results, "%d: %s -> Failed: %v\n", idx+1, line, err)
+			} else {
+				added = append(added, fmt.Sprintf("%s (%s)", app.Name, app.Item()))
+				fmt.Fprintf(&results, "%d: %s -> Added %s (%s)\n", idx+1, line, app.Name, app.Item())
+			}
+			progress(idx+1, len(lines))
+		}
+
+		finishJob(s, i,
+			&discordgo.MessageEmbed{
+				Title:       "Add Apps File",
+				Description: "Finished adding apps. See the attached file for the result of each line",
+				Fields: []*discordgo.MessageEmbedField{
+					{
+						Name:   "Added",
+						Value:  strconv.Itoa(len(added)),
+						Inline: true,
+					},
+					{
+						Name:   "Failed",
+						Value:  strconv.Itoa(failed),
+						Inline: true,
+					},
+				},
+			},
+			[]*discordgo.File{
+				{
+					Name:        "results.txt",
+					ContentType: "text/plain",
+					Reader:      &results,
+				},
+			},
+			nil,
+		)
+
+		if len(added) > 0 {
+			recordAudit(ctx, s, i, "add_apps_file", "", strings.Join(added, ", "))
+		}
+	}()
+}
+
+// fileLines splits data into trimmed, non-empty lines. For CSV, only the
+// first column is kept and a header row is skipped.
+func fileLines(data []byte) (lines []string) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), ",")
+		line = strings.Trim(strings.TrimSpace(line), `"`)
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) > 0 {
+		switch strings.ToLower(lines[0]) {
+		case "app_id", "appid", "name", "url":
+			lines = lines[1:]
+		}
+	}
+
+	return lines
+}
+
+// addAppFromLine adds the app referenced by line under guildID. line may be
+// an appid, a store URL, or an app name, in which case the best search
+// result is used. Rate limits are waited out.
+func addAppFromLine(ctx context.Context, guildID int64, line string,
+	saleThreshold *int) (*steam.App, error) {
+	item, err := lineItem(ctx, line)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := steam.WaitForLimit(ctx, func() (*steam.App, error) {
+		return newTrackableApp(ctx, item)
+	})
+	if err == errUntrackable {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.New("not a valid app")
+	}
+	app.SaleThreshold = saleThreshold
+
+	if _, fail := store.AddApps(ctx, guildID, []*steam.App{app}); len(fail) > 0 {
+		return nil, errSave
+	}
+
+	return app, nil
+}
+
+// lineItem finds the item referenced by line, searching Steam for an app
+// if line isn't an id or store URL.
+func lineItem(ctx context.Context, line string) (steam.Item, error) {
+	if appid, err := strconv.Atoi(line); err == nil {
+		if appid <= 0 {
+			return steam.Item{}, errors.New("not a valid appid")
+		}
+		return steam.AppItem(appid), nil
+	}
+
+	if item, err := steam.ParseItem(line); err == nil {
+		return item, nil
+	}
+
+	if m := storeURLRegex.FindStringSubmatch(line); m != nil {
+		id, err := strconv.Atoi(m[2])
+		return steam.Item{Kind: steam.ItemKind(m[1]), ID: id}, err
+	}
+
+	results, err := steam.WaitForLimit(ctx, func() ([]steam.SearchResult, error) {
+		return steam.Search(ctx, line)
+	})
+	if err != nil {
+		return steam.Item{}, errors.New("search failed")
+	}
+	if len(results) == 0 {
+		return steam.Item{}, errors.New("no app found with that name")
+	}
+
+	// Prefer an exact name match over the top result
+	for _, r := range results {
+		if strings.EqualFold(r.Name, line) {
+			return steam.AppItem(r.Appid), nil
+		}
+	}
+	return steam.AppItem(results[0].Appid), nil
+}
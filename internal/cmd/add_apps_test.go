@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
+	"github.com/stretchr/testify/suite"
+)
+
+type addAppsShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+	store     *fakeStore
+}
+
+func (s *addAppsShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+	s.store = useFakeStore(s.T())
+}
+
+func TestAddAppsShould(t *testing.T) {
+	suite.Run(t, new(addAppsShould))
+}
+
+func (s *addAppsShould) TestReplyUnexpectedOutsideGuild() {
+	i := newCommandInteraction("add_apps", stringOpt("appids", "abc"), intOpt("threshold", 50))
+	i.GuildID = ""
+
+	addAppsHandler(context.Background(), s.session, i)
+
+	s.Require().NotNil(s.responder.lastEdit())
+	s.Equal("Error: Something unexpected happened", *s.responder.lastEdit().Content)
+}
+
+func (s *addAppsShould) TestAddAppsAsSteamHasThem() {
+	useSteamServer(s.T(), steamtest.CannedApps...)
+
+	addAppsHandler(context.Background(), s.session,
+		newCommandInteraction("add_apps", stringOpt("appids", "400, 440"), intOpt("threshold", 50)))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Require().Len(em.Fields, 2)
+	s.Equal("Portal (400)\n", em.Fields[0].Value)
+	// Free apps never go on sale
+	s.Equal("440\n", em.Fields[1].Value)
+	app := s.store.app(100, 400)
+	s.Require().NotNil(app)
+	s.Equal("Portal", app.AppName)
+	s.Equal(50, app.AppSaleThreshold)
+	s.Nil(s.store.app(100, 440))
+	s.Require().Len(s.store.auditLog(), 1)
+	s.Equal("Portal (400)", s.store.auditLog()[0].After)
+}
+
+func (s *addAppsShould) TestFailAppidsThatArentPositiveInts() {
+	succ, fail := strsToApps(context.Background(), []string{" abc ", "-1", "0", ""})
+
+	s.Empty(succ)
+	s.Equal([]string{"abc", "-1", "0", ""}, fail)
+}
+
+func (s *addAppsShould) TestFailItemsOfUnknownKinds() {
+	succ, fail := strsToApps(context.Background(),
+		[]string{"dlc/400", "sub/abc", "bundle/0", "store.steampowered.com/news/400"})
+
+	s.Empty(succ)
+	s.Equal([]string{"dlc/400", "sub/abc", "bundle/0", "store.steampowered.com/news/400"}, fail)
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+)
+
+// addDLCJobs are the guilds with an /add_dlc job running.
+var addDLCJobs = struct {
+	sync.Mutex
+	m map[int64]bool
+}{m: map[int64]bool{}}
+
+var errSave = errors.New("failed to save, please try again")
+
+// NewAddDLC creates /add_dlc <appid> <threshold>.
+func NewAddDLC() Cmd {
+	min := float64(1)
+	return Cmd{
+		Name:        "add_dlc",
+		Description: "Add every DLC of a game to the tracker, including DLC it releases later",
+		Handle:      addDLCHandler,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "appid",
+				Description: "The appid of the game whose DLC to add",
+				Required:    true,
+				MinValue:    &min,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "threshold",
+				Description: "The minimum discount required to trigger a sale alert for the DLC specifically",
+				MinValue:    &min,
+				MaxValue:    99,
+			},
+		},
+		ManagerOnly: true,
+	}
+}
+
+func addDLCHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	reply := func(description string) {
+		EditReply(s, i, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{
+				{
+					Title:       "Add DLC",
+					Description: description,
+				},
+			},
+		})
+	}
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Parse options
+	opts := i.ApplicationCommandData().Options
+	appid := int(opts[0].IntValue())
+	var saleThreshold *int
+	if len(opts) > 1 {
+		threshold := int(opts[1].IntValue())
+		saleThreshold = &threshold
+	}
+
+	// Get the game's DLC
+	game, err := steam.WaitForLimit(ctx, func() (steam.App, error) {
+		return steam.NewApp(ctx, appid)
+	})
+	if err != nil {
+		reply("Failed to find a game with that appid, please try again")
+		return
+	}
+	if game.Type == "dlc" {
+		reply(fmt.Sprintf("%s is DLC, add the DLC of the game it's for instead", game.Name))
+		return
+	}
+
+	// Only one job per guild at a time
+	addDLCJobs.Lock()
+	if addDLCJobs.m[guildID] {
+		addDLCJobs.Unlock()
+		reply("DLC is already being added for this server, please wait for it to finish")
+		return
+	}
+	addDLCJobs.m[guildID] = true
+	addDLCJobs.Unlock()
+
+	// Add in the background, updating the reply as DLC is processed
+	ctx = jobContext(ctx)
+	go func() {
+		defer func() {
+			addDLCJobs.Lock()
+			delete(addDLCJobs.m, guildID)
+			addDLCJobs.Unlock()
+		}()
+
+		res, err := addDLC(ctx, s, i, guildID, game, saleThreshold)
+		if err != nil {
+			reply("Failed to save, please try again")
+			return
+		}
+
+		description := fmt.Sprintf("Finished adding the DLC of %s. New DLC will be added "+
+			"as it's released", game.Name)
+		if len(game.DLC) == 0 {
+			description = fmt.Sprintf("%s has no DLC yet. New DLC will be added as it's "+
+				"released", game.Name)
+		}
+		em := &discordgo.MessageEmbed{
+			Title:       "Add DLC",
+			Description: description,
+			Fields: []*discordgo.MessageEmbedField{
+				{
+					Name:   "Added",
+					Value:  strconv.Itoa(res.added),
+					Inline: true,
+				},
+				{
+					Name:   "Skipped (free)",
+					Value:  strconv.Itoa(res.skipped),
+					Inline: true,
+				},
+				{
+					Name:   "Failed",
+					Value:  strconv.Itoa(res.failed),
+					Inline: true,
+				},
+			},
+		}
+		var files []*discordgo.File
+		if res.results.Len() > 0 {
+			em.Footer = &discordgo.MessageEmbedFooter{
+				Text: "See the attached file for the result of each DLC",
+			}
+			files = append(files, &discordgo.File{
+				Name:        "results.txt",
+				ContentType: "text/plain",
+				Reader:      res.results,
+			})
+		}
+		finishJob(s, i, em, files, nil)
+
+		recordAudit(ctx, s, i, "add_dlc", "",
+			fmt.Sprintf("DLC of %s (%d): %d added", game.Name, game.Appid, res.added))
+	}()
+}
+
+// addDLCResult is the outcome of addDLC(). results has the outcome of each
+// DLC.
+type addDLCResult struct {
+	added, skipped, failed int
+	results                *bytes.Buffer
+}
+
+// addDLC adds game and each of its DLC under guildID, then has the game's
+// new DLC added as it's released. Free DLC is skipped since it never goes
+// on sale. DLC that failed to be added is left unknown, so it's tried again
+// on the next check. Rate limits are waited out.
+func addDLC(ctx context.Context, s Session, i *discordgo.InteractionCreate, guildID int64,
+	game steam.App, saleThreshold *int) (res addDLCResult, err error) {
+	// The game is tracked even if it's free, so its new DLC can be found
+	if _, fail := store.AddApps(ctx, guildID, []*steam.App{&game}); len(fail) > 0 {
+		return addDLCResult{}, errSave
+	}
+
+	progress := newProgressReporter(s, i, func(done, total int) *discordgo.MessageEmbed {
+		return &discordgo.MessageEmbed{
+			Title:       "Add DLC",
+			Description: fmt.Sprintf("Processed %d of %d DLC...", done, total),
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Note: Adding may pause if Steam rate limits requests",
+			},
+		}
+	})
+
+	res.results = &bytes.Buffer{}
+	known := []int{}
+	for idx, appid := range game.DLC {
+		dlc, err := steam.WaitForLimit(ctx, func() (*steam.App, error) {
+			return newTrackableApp(ctx, steam.AppItem(appid))
+		})
+		if err == nil {
+			dlc.SaleThreshold = saleThreshold
+			_, fail := store.AddDLC(ctx, guildID, game.Appid, []*steam.App{dlc})
+			if len(fail) > 0 {
+				err = errSave
+			}
+		}
+
+		switch {
+		case err == errUntrackable:
+			res.skipped++
+			known = append(known, appid)
+			fmt.Fprintf(res.results, "%d -> Skipped (free)\n", appid)
+		case err != nil:
+			res.failed++
+			fmt.Fprintf(res.results, "%d -> Failed: %v\n", appid, err)
+		default:
+			res.added++
+			known = append(known, appid)
+			fmt.Fprintf(res.results, "%d -> Added %s\n", appid, dlc.Name)
+		}
+		progress(idx+1, len(game.DLC))
+	}
+
+	err = store.SetKnownDLC(ctx, guildID, game.Appid, known)
+	return res, err
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
+	"github.com/stretchr/testify/suite"
+)
+
+type addDLCShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+	store     *fakeStore
+}
+
+func (s *addDLCShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+	s.store = useFakeStore(s.T())
+}
+
+func TestAddDLCShould(t *testing.T) {
+	suite.Run(t, new(addDLCShould))
+}
+
+// finalEmbed waits for the background job to finish and gets the embed
+// it finished with.
+func (s *addDLCShould) finalEmbed() *discordgo.MessageEmbed {
+	var em *discordgo.MessageEmbed
+	s.Eventually(func() bool {
+		em = s.responder.lastEditEmbed()
+		return em != nil && strings.HasPrefix(em.Description, "Finished") &&
+			len(s.store.auditLog()) > 0
+	}, time.Second, time.Millisecond)
+	return em
+}
+
+func (s *addDLCShould) TestReplyUnexpectedOutsideGuild() {
+	i := newCommandInteraction("add_dlc", intOpt("appid", 620), intOpt("threshold", 50))
+	i.GuildID = ""
+
+	addDLCHandler(context.Background(), s.session, i)
+
+	s.Require().NotNil(s.responder.lastEdit())
+	s.Equal("Error: Something unexpected happened", *s.responder.lastEdit().Content)
+}
+
+func (s *addDLCShould) TestAddPricedDLCAndSkipFreeDLC() {
+	useSteamServer(s.T(),
+		steamtest.App{Appid: 620, Name: "Portal 2", Type: "game", DLC: []int{323180, 323181, 323182},
+			Initial: "$9.99", Final: "$9.99"},
+		steamtest.App{Appid: 323180, Name: "Portal 2 Soundtrack", Type: "dlc",
+			Initial: "$4.99", Final: "$4.99"},
+		steamtest.App{Appid: 323181, Name: "Portal 2 Free Pack", Type: "dlc", Free: true},
+	)
+
+	addDLCHandler(context.Background(), s.session,
+		newCommandInteraction("add_dlc", intOpt("appid", 620), intOpt("threshold", 50)))
+
+	em := s.finalEmbed()
+	s.Require().Len(em.Fields, 3)
+	s.Equal("1", em.Fields[0].Value)
+	s.Equal("1", em.Fields[1].Value)
+	s.Equal("1", em.Fields[2].Value)
+	game := s.store.app(100, 620)
+	s.Require().NotNil(game)
+	s.True(game.TrackDLC)
+	// The DLC that failed is tried again on the next check
+	s.Equal([]int{323180, 323181}, game.KnownDLC)
+	dlc := s.store.app(100, 323180)
+	s.Require().NotNil(dlc)
+	s.Equal(620, dlc.DLCOf)
+	s.Equal(50, dlc.AppSaleThreshold)
+	s.Nil(s.store.app(100, 323181))
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Keys of the optional fields of sale alerts, as kept in
+// db.DiscordInfo.HiddenFields.
+const (
+	FieldReviews       = "reviews"
+	FieldDeveloper     = "developer"
+	FieldGenres        = "genres"
+	FieldPlatforms     = "platforms"
+	FieldMetacritic    = "metacritic"
+	FieldReleaseDate   = "release_date"
+	FieldLowestAlerted = "lowest_alerted"
+	FieldDescription   = "description"
+)
+
+// AlertField is an optional field of sale alerts that guilds can hide.
+type AlertField struct {
+	Key  string
+	Name string
+}
+
+// AlertFields are the optional fields of sale alerts, in the order they
+// appear in alerts.
+var AlertFields = []AlertField{
+	{Key: FieldReviews, Name: "Reviews"},
+	{Key: FieldLowestAlerted, Name: "Lowest Alerted"},
+	{Key: FieldDeveloper, Name: "Developer/Publisher"},
+	{Key: FieldGenres, Name: "Genres"},
+	{Key: FieldPlatforms, Name: "Platforms"},
+	{Key: FieldMetacritic, Name: "Metacritic"},
+	{Key: FieldReleaseDate, Name: "Release Date"},
+	{Key: FieldDescription, Name: "Description"},
+}
+
+// NewAlertFields creates /alert_fields <field> <shown>.
+func NewAlertFields() Cmd {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(AlertFields))
+	for _, field := range AlertFields {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  field.Name,
+			Value: field.Key,
+		})
+	}
+
+	return Cmd{
+		Name:        "alert_fields",
+		Description: "Show or hide fields of sale alerts",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "field",
+				Description: "The field to show or hide. Leave empty to list the fields",
+				Choices:     choices,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "shown",
+				Description: "Whether the field is shown. Leave empty to toggle it",
+			},
+		},
+		Handle:      alertFieldsHandler,
+		ManagerOnly: true,
+	}
+}
+
+func alertFieldsHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	guild, err := store.Guild(ctx, guildID)
+	if err != nil {
+		alertFieldsReply(s, i, "Failed to get the alert fields, please try again", nil)
+		return
+	}
+
+	// Parse options
+	var field string
+	var shown *bool
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "field":
+			field = opt.StringValue()
+		case "shown":
+			v := opt.BoolValue()
+			shown = &v
+		}
+	}
+
+	// Only list the fields
+	if field == "" {
+		alertFieldsReply(s, i, "", guild.HiddenFields)
+		return
+	}
+
+	hidden := toggleField(guild.HiddenFields, field, shown)
+	if err := store.SetHiddenFields(ctx, guildID, hidden); err != nil {
+		alertFieldsReply(s, i, "Failed to update the alert fields, please try again",
+			guild.HiddenFields)
+		return
+	}
+
+	alertFieldsReply(s, i, "Successfully updated the alert fields", hidden)
+	recordAudit(ctx, s, i, "alert_fields",
+		describeHiddenFields(guild.HiddenFields), describeHiddenFields(hidden))
+}
+
+// toggleField gets hidden with field shown or hidden. field is toggled if
+// shown is nil.
+func toggleField(hidden []string, field string, shown *bool) []string {
+	isHidden := slices.Contains(hidden, field)
+	if shown == nil {
+		show := isHidden
+		shown = &show
+	}
+
+	if *shown {
+		return slices.DeleteFunc(slices.Clone(hidden), func(f string) bool { return f == field })
+	}
+	if isHidden {
+		return hidden
+	}
+	return append(slices.Clone(hidden), field)
+}
+
+// describeHiddenFields describes hidden fields for the audit log.
+func describeHiddenFields(hidden []string) string {
+	if len(hidden) == 0 {
+		return "all fields shown"
+	}
+	return "hidden: " + strings.Join(hidden, ", ")
+}
+
+// alertFieldsReply replies with description and whether each field is shown.
+func alertFieldsReply(s Responder, i *discordgo.InteractionCreate, description string,
+	hidden []string) {
+	lines := make([]string, 0, len(AlertFields))
+	for _, field := range AlertFields {
+		state := "Shown"
+		if slices.Contains(hidden, field.Key) {
+			state = "Hidden"
+		}
+		lines = append(lines, field.Name+": "+state)
+	}
+
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title:       "Alert Fields",
+				Description: description,
+				Fields: []*discordgo.MessageEmbedField{
+					{
+						Name:  "Fields",
+						Value: strings.Join(lines, "\n"),
+					},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type alertFieldsShould struct {
+	suite.Suite
+}
+
+func TestAlertFieldsShould(t *testing.T) {
+	suite.Run(t, new(alertFieldsShould))
+}
+
+func (s *alertFieldsShould) TestToggleFieldWithoutShown() {
+	hidden := toggleField(nil, FieldGenres, nil)
+	s.Equal([]string{FieldGenres}, hidden)
+
+	s.Empty(toggleField(hidden, FieldGenres, nil))
+}
+
+func (s *alertFieldsShould) TestSetFieldToShown() {
+	shown, notShown := true, false
+
+	s.Equal([]string{FieldReviews},
+		toggleField([]string{FieldGenres, FieldReviews}, FieldGenres, &shown))
+	s.Equal([]string{FieldGenres}, toggleField([]string{FieldGenres}, FieldGenres, &notShown))
+	s.Empty(toggleField(nil, FieldGenres, &shown))
+}
+
+func (s *alertFieldsShould) TestNotChangeHiddenInPlace() {
+	hidden := []string{FieldGenres, FieldReviews}
+	shown := true
+
+	toggleField(hidden, FieldGenres, &shown)
+
+	s.Equal([]string{FieldGenres, FieldReviews}, hidden)
+}
+
+func (s *alertFieldsShould) TestDescribeHiddenFields() {
+	s.Equal("all fields shown", describeHiddenFields(nil))
+	s.Equal("hidden: genres, reviews", describeHiddenFields([]string{FieldGenres, FieldReviews}))
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+)
+
+var auditLogCompPage = "auditLogCompPage"
+
+// auditLogPageSize is how many changes are shown per page of /audit_log.
+const auditLogPageSize = 10
+
+// maxAuditValueLen is the most characters of an argument, before, or after
+// value shown per change in /audit_log.
+const maxAuditValueLen = 100
+
+// NewAuditLog creates /audit_log.
+func NewAuditLog() Cmd {
+	return Cmd{
+		Name:        "audit_log",
+		Description: "Show who changed this server's tracker configuration",
+		Handle:      auditLogHandler,
+		CompHandlers: []ComponentHandler{
+			{
+				Name:   auditLogCompPage,
+				Handle: auditLogCompPageHandler,
+			},
+		},
+		ManagerOnly: true,
+	}
+}
+
+func auditLogHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	em, components := auditLogPage(ctx, guildID, 0)
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{em},
+		Components: &components,
+	})
+}
+
+func auditLogCompPageHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	DeferCompReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Parse page
+	args := customIDArgs(i)
+	if len(args) != 1 {
+		EditReplyUnexpected(s, i)
+		return
+	}
+	page, err := strconv.Atoi(args[0])
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	em, components := auditLogPage(ctx, guildID, page)
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{em},
+		Components: &components,
+	})
+}
+
+// auditLogPage creates the embed and components showing a page of the
+// audit log of the guild matching guildID.
+func auditLogPage(ctx context.Context, guildID int64, page int) (*discordgo.MessageEmbed,
+	[]discordgo.MessageComponent) {
+	em := &discordgo.MessageEmbed{Title: "Audit Log"}
+	components := []discordgo.MessageComponent{}
+
+	entries, total, err := store.AuditLog(ctx, guildID, page*auditLogPageSize, auditLogPageSize)
+	switch {
+	case err != nil:
+		em.Description = "Failed to get the audit log, please try again"
+		return em, components
+
+	case total == 0:
+		em.Description = "No configuration changes have been made"
+		return em, components
+	}
+
+	sb := strings.Builder{}
+	for _, entry := range entries {
+		sb.WriteString(fmt.Sprintf("<t:%d:f> <@%d> used **/%s**",
+			entry.CreatedAt.Unix(), entry.UserID, entry.Command))
+		if entry.Args != "" {
+			sb.WriteString(" " + truncate(entry.Args, maxAuditValueLen))
+		}
+		sb.WriteString("\n")
+		if entry.Before != "" || entry.After != "" {
+			sb.WriteString(fmt.Sprintf("> %s -> %s\n",
+				orNone(truncate(entry.Before, maxAuditValueLen)),
+				orNone(truncate(entry.After, maxAuditValueLen))))
+		}
+	}
+	em.Description = sb.String()
+
+	pages := int((total + auditLogPageSize - 1) / auditLogPageSize)
+	em.Footer = &discordgo.MessageEmbedFooter{
+		Text: fmt.Sprintf("Page %d of %d, %d changes", page+1, pages, total),
+	}
+
+	if pages > 1 {
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Previous",
+					Style:    discordgo.SecondaryButton,
+					CustomID: newCustomID(auditLogCompPage, strconv.Itoa(page-1)),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Label:    "Next",
+					Style:    discordgo.SecondaryButton,
+					CustomID: newCustomID(auditLogCompPage, strconv.Itoa(page+1)),
+					Disabled: page >= pages-1,
+				},
+			},
+		})
+	}
+
+	return em, components
+}
+
+// recordAudit adds a change to the configuration made through command to
+// the audit log of the interaction's guild, and mirrors it to the guild's
+// audit channel if one is set. The arguments are read from the interaction
+// if it's a command. before and after describe what changed.
+func recordAudit(ctx context.Context, s Session, i *discordgo.InteractionCreate,
+	command, before, after string) {
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		return
+	}
+
+	var userID int64
+	if i.Member != nil && i.Member.User != nil {
+		userID, _ = strconv.ParseInt(i.Member.User.ID, 10, 64)
+	}
+
+	entry, err := store.RecordAudit(ctx, db.AuditInfo{
+		ServerID: guildID,
+		UserID:   userID,
+		Command:  command,
+		Args:     commandArgs(i),
+		Before:   before,
+		After:    after,
+	})
+	if err != nil {
+		log.Printf("Failed to record audit entry of /%s for guild %d: %v", command, guildID, err)
+		return
+	}
+
+	// Mirror to the audit channel
+	guild, err := store.Guild(ctx, guildID)
+	if err != nil || guild.AuditChannelID == 0 {
+		return
+	}
+	s.ChannelMessageSendEmbed(strconv.FormatInt(guild.AuditChannelID, 10), auditEmbed(entry))
+}
+
+// commandArgs formats the options of a command interaction as
+// "name: value" pairs. Other interactions have no arguments.
+func commandArgs(i *discordgo.InteractionCreate) string {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return ""
+	}
+
+	args := []string{}
+	for _, opt := range i.ApplicationCommandData().Options {
+		args = append(args, fmt.Sprintf("%s: %v", opt.Name, opt.Value))
+	}
+	return strings.Join(args, " ")
+}
+
+// auditEmbed creates the embed mirrored to an audit channel for entry.
+func auditEmbed(entry db.AuditInfo) *discordgo.MessageEmbed {
+	em := &discordgo.MessageEmbed{
+		Title:       "Configuration Changed",
+		Description: fmt.Sprintf("<@%d> used **/%s**", entry.UserID, entry.Command),
+		Timestamp:   entry.CreatedAt.Format(time.RFC3339),
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"Arguments", entry.Args},
+		{"Before", entry.Before},
+		{"After", entry.After},
+	} {
+		if field.value == "" {
+			continue
+		}
+		em.Fields = append(em.Fields, &discordgo.MessageEmbedField{
+			Name:  field.name,
+			Value: truncate(field.value, maxFieldLen),
+		})
+	}
+
+	return em
+}
+
+// truncate shortens s to at most n characters, marking where it was cut.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// orNone gets s, or "none" if s is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
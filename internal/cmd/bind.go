@@ -1,10 +1,11 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"strconv"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
 )
 
 func NewBind() Cmd {
@@ -20,11 +21,12 @@ func NewBind() Cmd {
 				Required:     true,
 			},
 		},
-		Handle: bindHandler,
+		Handle:      bindHandler,
+		ManagerOnly: true,
 	}
 }
 
-func bindHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func bindHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
 	DeferMsgReply(s, i)
 
 	// Parse guildID
@@ -45,14 +47,10 @@ func bindHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	channel := i.ApplicationCommandData().Options[0].ChannelValue(nil)
 
 	// Check bot is able to message in that channel
-	perms, err := s.State.UserChannelPermissions(s.State.User.ID, channel.ID)
-	if err != nil {
-		EditReply(s, i, &edit)
-		return
-	}
-	if perms&discordgo.PermissionSendMessages == 0 {
+	if !CanSendAlerts(s.State, channel.ID) {
 		(*edit.Embeds)[0].Description =
-			"Failed to bind, missing send message permissions for that channel"
+			"Failed to bind, missing view channel, send message, or embed links " +
+				"permissions for that channel"
 		EditReply(s, i, &edit)
 		return
 	}
@@ -63,10 +61,21 @@ func bindHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
+	// Get the current channel for the audit log
+	before := ""
+	if guild, err := store.Guild(ctx, guildID); err == nil && guild.ChannelID != 0 {
+		before = fmt.Sprintf("<#%d>", guild.ChannelID)
+	}
+
 	// Bind and create embed reply
-	if err := db.SetChannelID(guildID, channelID); err == nil {
+	err = store.SetChannelID(ctx, guildID, channelID)
+	if err == nil {
 		(*edit.Embeds)[0].Description = "Successfully bound to " + channel.Mention()
 	}
 
 	EditReply(s, i, &edit)
+
+	if err == nil {
+		recordAudit(ctx, s, i, "bind", before, channel.Mention())
+	}
 }
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/suite"
+)
+
+type bindShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+	store     *fakeStore
+}
+
+func (s *bindShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+	s.store = useFakeStore(s.T())
+}
+
+func TestBindShould(t *testing.T) {
+	suite.Run(t, new(bindShould))
+}
+
+// addChannel adds a channel of the test guild to the state, where the bot
+// has perms through the @everyone role.
+func (s *bindShould) addChannel(channelID string, perms int64) {
+	state := s.session.State
+	s.Require().NoError(state.GuildAdd(&discordgo.Guild{
+		ID:    testGuildID,
+		Roles: []*discordgo.Role{{ID: testGuildID, Permissions: perms}},
+	}))
+	s.Require().NoError(state.MemberAdd(&discordgo.Member{
+		GuildID: testGuildID,
+		User:    &discordgo.User{ID: testBotID},
+	}))
+	s.Require().NoError(state.ChannelAdd(&discordgo.Channel{
+		ID:      channelID,
+		GuildID: testGuildID,
+		Type:    discordgo.ChannelTypeGuildText,
+	}))
+}
+
+func (s *bindShould) TestReplyUnexpectedOutsideGuild() {
+	i := newCommandInteraction("bind", channelOpt("channel", "500"))
+	i.GuildID = ""
+
+	bindHandler(context.Background(), s.session, i)
+
+	s.Equal([]discordgo.InteractionResponseType{
+		discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}, s.responder.responseTypes())
+	s.Equal("Error: Something unexpected happened", *s.responder.lastEdit().Content)
+}
+
+func (s *bindShould) TestRefuseChannelMissingAlertPermissions() {
+	s.addChannel("500", discordgo.PermissionViewChannel|discordgo.PermissionSendMessages)
+
+	bindHandler(context.Background(), s.session,
+		newCommandInteraction("bind", channelOpt("channel", "500")))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Contains(em.Description, "missing view channel, send message, or embed links")
+}
+
+func (s *bindShould) TestRefuseUnknownChannel() {
+	bindHandler(context.Background(), s.session,
+		newCommandInteraction("bind", channelOpt("channel", "500")))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Contains(em.Description, "missing view channel, send message, or embed links")
+}
+
+func (s *bindShould) TestBindChannelWithAlertPermissions() {
+	s.addChannel("500", AlertPermissions)
+
+	bindHandler(context.Background(), s.session,
+		newCommandInteraction("bind", channelOpt("channel", "500")))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Equal("Successfully bound to <#500>", em.Description)
+	guild, _ := s.store.Guild(context.Background(), 100)
+	s.Equal(int64(500), guild.ChannelID)
+	s.Require().Len(s.store.auditLog(), 1)
+	s.Equal("<#500>", s.store.auditLog()[0].After)
+}
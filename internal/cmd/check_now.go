@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// checkNowCooldown is how long a guild must wait between uses of /check_now.
+const checkNowCooldown = time.Hour
+
+// GuildChecker checks every app tracked by the guild matching guildID for
+// sales, sending alerts like the scheduled check does. progress is called
+// after each app is checked. It never runs at the same time as the
+// scheduled check.
+type GuildChecker func(ctx context.Context, guildID int64,
+	progress func(checked, total int)) (CheckSummary, error)
+
+// CheckSummary is the outcome of a GuildChecker.
+type CheckSummary struct {
+	Checked int
+	Failed  int
+	Alerts  int
+}
+
+// checkNowLastUse is when each guild last started a /check_now.
+var checkNowLastUse = struct {
+	sync.Mutex
+	m map[int64]time.Time
+}{m: map[int64]time.Time{}}
+
+// NewCheckNow creates /check_now. check is used to check the invoking
+// guild's apps.
+func NewCheckNow(check GuildChecker) Cmd {
+	return Cmd{
+		Name:        "check_now",
+		Description: "Check this server's apps for sales now instead of waiting for the daily check",
+		Handle: func(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+			checkNowHandler(ctx, s, i, check)
+		},
+		ManagerOnly: true,
+	}
+}
+
+func checkNowHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate,
+	check GuildChecker) {
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		MsgReplyUnexpected(s, i)
+		return
+	}
+
+	// Enforce cooldown
+	checkNowLastUse.Lock()
+	if last, ok := checkNowLastUse.m[guildID]; ok && time.Since(last) < checkNowCooldown {
+		checkNowLastUse.Unlock()
+		MsgReply(s, i, &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title: "Check Now",
+					Description: fmt.Sprintf("This server was checked recently, try again <t:%d:R>",
+						last.Add(checkNowCooldown).Unix()),
+				},
+			},
+		})
+		return
+	}
+	checkNowLastUse.m[guildID] = time.Now()
+	checkNowLastUse.Unlock()
+
+	DeferMsgReply(s, i)
+
+	// Check in the background, updating the reply as apps are checked
+	ctx = jobContext(ctx)
+	go func() {
+		progress := newProgressReporter(s, i, func(checked, total int) *discordgo.MessageEmbed {
+			return &discordgo.MessageEmbed{
+				Title:       "Check Now",
+				Description: fmt.Sprintf("Checked %d of %d apps...", checked, total),
+				Footer: &discordgo.MessageEmbedFooter{
+					Text: "Note: Checking may pause if Steam rate limits requests",
+				},
+			}
+		})
+
+		summary, err := check(ctx, guildID, progress)
+		em := &discordgo.MessageEmbed{Title: "Check Now"}
+		if err != nil {
+			em.Description = "Failed to check apps, please try again later"
+		} else {
+			em.Description = "Finished checking apps"
+			em.Fields = []*discordgo.MessageEmbedField{
+				{
+					Name:   "Checked",
+					Value:  strconv.Itoa(summary.Checked),
+					Inline: true,
+				},
+				{
+					Name:   "Alerts Sent",
+					Value:  strconv.Itoa(summary.Alerts),
+					Inline: true,
+				},
+				{
+					Name:   "Failed",
+					Value:  strconv.Itoa(summary.Failed),
+					Inline: true,
+				},
+			}
+		}
+
+		finishJob(s, i, em, nil, nil)
+	}()
+}
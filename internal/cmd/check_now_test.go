@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/suite"
+)
+
+type checkNowShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+}
+
+func (s *checkNowShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+
+	checkNowLastUse.Lock()
+	checkNowLastUse.m = map[int64]time.Time{}
+	checkNowLastUse.Unlock()
+}
+
+func TestCheckNowShould(t *testing.T) {
+	suite.Run(t, new(checkNowShould))
+}
+
+// finalEmbed waits for the background check to finish and gets the
+// embed it finished with.
+func (s *checkNowShould) finalEmbed() *discordgo.MessageEmbed {
+	var em *discordgo.MessageEmbed
+	s.Eventually(func() bool {
+		em = s.responder.lastEditEmbed()
+		return em != nil && !strings.HasPrefix(em.Description, "Checked")
+	}, time.Second, time.Millisecond)
+	return em
+}
+
+func (s *checkNowShould) TestReportSummary() {
+	check := func(_ context.Context, _ int64, progress func(int, int)) (CheckSummary, error) {
+		progress(1, 2)
+		progress(2, 2)
+		return CheckSummary{Checked: 2, Failed: 1, Alerts: 1}, nil
+	}
+
+	checkNowHandler(context.Background(), s.session, newCommandInteraction("check_now"), check)
+
+	em := s.finalEmbed()
+	s.Equal("Finished checking apps", em.Description)
+	s.Require().Len(em.Fields, 3)
+	s.Equal("2", em.Fields[0].Value)
+	s.Equal("1", em.Fields[1].Value)
+	s.Equal("1", em.Fields[2].Value)
+}
+
+func (s *checkNowShould) TestReportFailure() {
+	check := func(context.Context, int64, func(int, int)) (CheckSummary, error) {
+		return CheckSummary{}, errors.New("failed")
+	}
+
+	checkNowHandler(context.Background(), s.session, newCommandInteraction("check_now"), check)
+
+	s.Equal("Failed to check apps, please try again later", s.finalEmbed().Description)
+}
+
+func (s *checkNowShould) TestEnforceCooldown() {
+	checks := 0
+	check := func(context.Context, int64, func(int, int)) (CheckSummary, error) {
+		checks++
+		return CheckSummary{}, nil
+	}
+
+	checkNowHandler(context.Background(), s.session, newCommandInteraction("check_now"), check)
+	s.finalEmbed()
+	checkNowHandler(context.Background(), s.session, newCommandInteraction("check_now"), check)
+
+	s.Equal(1, checks)
+	resp := s.responder.lastResponse()
+	s.Equal(discordgo.InteractionResponseChannelMessageWithSource, resp.Type)
+	s.Contains(resp.Data.Embeds[0].Description, "This server was checked recently")
+}
+
+func (s *checkNowShould) TestOutliveInteraction() {
+	ctx, cancel := context.WithCancel(context.Background())
+	checked := make(chan error, 1)
+	check := func(ctx context.Context, _ int64, _ func(int, int)) (CheckSummary, error) {
+		cancel()
+		checked <- ctx.Err()
+		return CheckSummary{}, nil
+	}
+
+	checkNowHandler(ctx, s.session, newCommandInteraction("check_now"), check)
+
+	s.NoError(<-checked)
+}
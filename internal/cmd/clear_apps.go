@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
 )
 
 var clearAppsCompDelete = "clearAppsCompDelete"
@@ -26,10 +28,11 @@ func NewClearApps() Cmd {
 				Handle: clearAppsCompCancelHandler,
 			},
 		},
+		ManagerOnly: true,
 	}
 }
 
-func clearAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func clearAppsHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
 	MsgReply(s, i, &discordgo.InteractionResponseData{
 		Embeds: []*discordgo.MessageEmbed{
 			{
@@ -56,7 +59,8 @@ func clearAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	})
 }
 
-func clearAppsCompDeleteHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func clearAppsCompDeleteHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
 	DeferCompReply(s, i)
 
 	// Parse guildID
@@ -66,13 +70,26 @@ func clearAppsCompDeleteHandler(s *discordgo.Session, i *discordgo.InteractionCr
 		return
 	}
 
+	// Get the current apps for the audit log
+	cleared := []string{}
+	if guildInfos, err := store.AppsOf(ctx, guildID); err == nil {
+		for _, info := range guildInfos {
+			cleared = append(cleared, fmt.Sprintf("%s (%s)", info.AppName, info.Item()))
+		}
+	}
+
 	// Clear apps and create embed reply
 	var description string
-	if err := db.ClearApps(guildID); err != nil {
+	components := []discordgo.MessageComponent{}
+	trashID, err := store.ClearApps(ctx, guildID)
+	if err != nil {
 		description = "Failed to clear some apps, please try again"
 	} else {
 		description = "Successfully cleared apps"
 	}
+	if !trashID.IsZero() {
+		components = append(components, undoRow(trashID))
+	}
 
 	EditReply(s, i, &discordgo.WebhookEdit{
 		Embeds: &[]*discordgo.MessageEmbed{
@@ -81,11 +98,16 @@ func clearAppsCompDeleteHandler(s *discordgo.Session, i *discordgo.InteractionCr
 				Description: description,
 			},
 		},
-		Components: &[]discordgo.MessageComponent{},
+		Components: &components,
 	})
+
+	if !trashID.IsZero() {
+		recordAudit(ctx, s, i, "clear_apps", strings.Join(cleared, ", "), "")
+	}
 }
 
-func clearAppsCompCancelHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func clearAppsCompCancelHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
 	CompReply(s, i, &discordgo.InteractionResponseData{
 		Embeds: []*discordgo.MessageEmbed{
 			{
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/stretchr/testify/suite"
+)
+
+type clearAppsShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+	store     *fakeStore
+}
+
+func (s *clearAppsShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+	s.store = useFakeStore(s.T())
+}
+
+func TestClearAppsShould(t *testing.T) {
+	suite.Run(t, new(clearAppsShould))
+}
+
+func (s *clearAppsShould) TestAuditClearedItems() {
+	s.store.AddApps(context.Background(), 100, []*steam.App{
+		{Appid: 400, Name: "Portal"},
+		{Appid: 469, Kind: steam.KindSub, Name: "The Orange Box"},
+	})
+
+	clearAppsCompDeleteHandler(context.Background(), s.session,
+		newComponentInteraction(clearAppsCompDelete))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Equal("Successfully cleared apps", em.Description)
+	s.Nil(s.store.app(100, 400))
+	s.Require().Len(s.store.auditLog(), 1)
+	s.Equal("Portal (400), The Orange Box (sub/469)", s.store.auditLog()[0].Before)
+}
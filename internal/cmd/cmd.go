@@ -2,6 +2,13 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -11,9 +18,39 @@ type Cmd struct {
 	Options      []*discordgo.ApplicationCommandOption
 	Handle       Handler
 	CompHandlers []ComponentHandler
+	// ManagerOnly restricts the command and its components to server
+	// managers. See IsManager().
+	ManagerOnly bool
+}
+
+// Handler handles an interaction. ctx is done once the interaction's token
+// expires or the bot shuts down. See InteractionContext().
+type Handler func(ctx context.Context, s Session, i *discordgo.InteractionCreate)
+
+// Responder sends replies to interactions, and messages to channels when
+// replying isn't possible. It's implemented by *discordgo.Session.
+type Responder interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse,
+		options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit,
+		options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed,
+		options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend,
+		options ...discordgo.RequestOption) (*discordgo.Message, error)
 }
 
-type Handler func(*discordgo.Session, *discordgo.InteractionCreate)
+// Session is what handlers use of the Discord session. Everything sent
+// goes through Responder, so handlers can be driven by a fake in tests.
+type Session struct {
+	Responder
+	State *discordgo.State
+}
+
+// NewSession creates the Session handlers use from s.
+func NewSession(s *discordgo.Session) Session {
+	return Session{Responder: s, State: s.State}
+}
 
 type ComponentHandler struct {
 	Name   string
@@ -21,31 +58,92 @@ type ComponentHandler struct {
 }
 
 func (c *Cmd) ApplicationCommand() *discordgo.ApplicationCommand {
-	return &discordgo.ApplicationCommand{
+	appCmd := &discordgo.ApplicationCommand{
 		Name:        c.Name,
 		Description: c.Description,
 		Options:     c.Options,
 	}
+	if c.ManagerOnly {
+		perms := int64(discordgo.PermissionManageServer)
+		appCmd.DefaultMemberPermissions = &perms
+	}
+
+	return appCmd
+}
+
+// interactionLifetime is how long an interaction's token is valid for
+// replying to and editing replies.
+const interactionLifetime = 15 * time.Minute
+
+// rootCtxKey is the key InteractionContext() stores its root context under.
+type rootCtxKey struct{}
+
+// InteractionContext creates the context i is handled with. It's done when
+// root is, or once i's token expires.
+func InteractionContext(root context.Context,
+	i *discordgo.InteractionCreate) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(interactionLifetime)
+	if created, err := discordgo.SnowflakeTimestamp(i.ID); err == nil {
+		deadline = created.Add(interactionLifetime)
+	}
+
+	return context.WithDeadline(context.WithValue(root, rootCtxKey{}, root), deadline)
+}
+
+// jobContext gets the context for a background job started while handling
+// an interaction with ctx. Jobs may outlive the interaction's token, e.g.,
+// when delayed by rate limiting, so they're only stopped when the bot
+// shuts down.
+func jobContext(ctx context.Context) context.Context {
+	if root, ok := ctx.Value(rootCtxKey{}).(context.Context); ok {
+		return root
+	}
+
+	return context.WithoutCancel(ctx)
+}
+
+// customIDSep separates the component handler name in a custom ID
+// from the args it carries.
+const customIDSep = ":"
+
+// newCustomID creates a custom ID for a component that is routed to the
+// component handler matching name. args are carried along so the handler
+// can read them back with customIDArgs().
+func newCustomID(name string, args ...string) string {
+	return strings.Join(append([]string{name}, args...), customIDSep)
+}
+
+// CustomIDName gets the name of the component handler a custom ID is
+// routed to.
+func CustomIDName(customID string) string {
+	name, _, _ := strings.Cut(customID, customIDSep)
+	return name
+}
+
+// customIDArgs gets the args carried by the custom ID of a component
+// interaction.
+func customIDArgs(i *discordgo.InteractionCreate) []string {
+	return strings.Split(i.MessageComponentData().CustomID, customIDSep)[1:]
 }
 
 // NewMsgReplyHandler creates a new reply handler for a msg interaction response.
 func NewMsgReplyHandler(data *discordgo.InteractionResponseData) Handler {
-	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: data,
-		})
+	return func(_ context.Context, s Session, i *discordgo.InteractionCreate) {
+		MsgReply(s, i, data)
 	}
 }
 
 // MsgReply replies to a msg interaction with data.
-func MsgReply(s *discordgo.Session, i *discordgo.InteractionCreate,
+func MsgReply(s Responder, i *discordgo.InteractionCreate,
 	data *discordgo.InteractionResponseData) {
-	NewMsgReplyHandler(data)(s, i)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
 }
 
 // MsgReplyUnexpected replies to a msg interaction with a generic error message.
-func MsgReplyUnexpected(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func MsgReplyUnexpected(s Responder, i *discordgo.InteractionCreate) {
 	MsgReply(s, i, &discordgo.InteractionResponseData{
 		Content: "Error: Something unexpected happened",
 	})
@@ -53,7 +151,7 @@ func MsgReplyUnexpected(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 // CompReply replies to a component interaction with data. Effectively, it
 // edits the original message the commponent was attached to.
-func CompReply(s *discordgo.Session, i *discordgo.InteractionCreate,
+func CompReply(s Responder, i *discordgo.InteractionCreate,
 	data *discordgo.InteractionResponseData) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseUpdateMessage,
@@ -64,7 +162,7 @@ func CompReply(s *discordgo.Session, i *discordgo.InteractionCreate,
 // DeferMsgReply tells a msg interaction that it has been acknowledged, and a
 // reply will come at a later time. EditReply() needs to be used for the
 // message when the reply can finally be made.
-func DeferMsgReply(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func DeferMsgReply(s Responder, i *discordgo.InteractionCreate) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
@@ -73,22 +171,81 @@ func DeferMsgReply(s *discordgo.Session, i *discordgo.InteractionCreate) {
 // DeferCompReply tells a component interaction that it has been acknowledged, and a
 // reply will come at a later time. EditReply() needs to be used for the
 // message when the reply can finally be made.
-func DeferCompReply(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func DeferCompReply(s Responder, i *discordgo.InteractionCreate) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredMessageUpdate,
 	})
 }
 
 // EditReply edits a msg reply or component reply that has already been sent with data.
-func EditReply(s *discordgo.Session, i *discordgo.InteractionCreate, data *discordgo.WebhookEdit) {
+func EditReply(s Responder, i *discordgo.InteractionCreate, data *discordgo.WebhookEdit) {
 	s.InteractionResponseEdit(i.Interaction, data)
 }
 
 // EditReply edits a msg reply or component reply that has already been
 // sent with a generic error message.
-func EditReplyUnexpected(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func EditReplyUnexpected(s Responder, i *discordgo.InteractionCreate) {
 	str := "Error: Something unexpected happened"
 	EditReply(s, i, &discordgo.WebhookEdit{
 		Content: &str,
 	})
 }
+
+// AlertPermissions are the permissions the bot needs in a channel to send alerts.
+const AlertPermissions = discordgo.PermissionViewChannel |
+	discordgo.PermissionSendMessages |
+	discordgo.PermissionEmbedLinks
+
+// CanSendAlerts reports whether the bot has AlertPermissions in channelID,
+// as known by state.
+func CanSendAlerts(state *discordgo.State, channelID string) bool {
+	perms, err := state.UserChannelPermissions(state.User.ID, channelID)
+	if err != nil {
+		return false
+	}
+
+	return perms&AlertPermissions == AlertPermissions
+}
+
+// maxAttachmentSize is the largest attachment downloadAttachment() accepts.
+const maxAttachmentSize = 1 << 20
+
+// attachmentClient is the client used to download attachments.
+var attachmentClient = &http.Client{Timeout: 10 * time.Second}
+
+// attachmentValue gets the attachment an attachment option refers to.
+func attachmentValue(i *discordgo.InteractionCreate,
+	opt *discordgo.ApplicationCommandInteractionDataOption) (*discordgo.MessageAttachment, error) {
+	data := i.ApplicationCommandData()
+	if data.Resolved == nil {
+		return nil, errors.New("no resolved attachments")
+	}
+
+	id, _ := opt.Value.(string)
+	att, ok := data.Resolved.Attachments[id]
+	if !ok {
+		return nil, errors.New("attachment not found")
+	}
+
+	return att, nil
+}
+
+// downloadAttachment downloads the contents of att. Attachments larger
+// than maxAttachmentSize are rejected.
+func downloadAttachment(att *discordgo.MessageAttachment) ([]byte, error) {
+	if att.Size > maxAttachmentSize {
+		return nil, errors.New("attachment too large")
+	}
+
+	resp, err := attachmentClient.Get(att.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("failed to download attachment: " + resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxAttachmentSize))
+}
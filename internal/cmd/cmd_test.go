@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/suite"
+)
+
+type customIDShould struct {
+	suite.Suite
+}
+
+func TestCustomIDShould(t *testing.T) {
+	suite.Run(t, new(customIDShould))
+}
+
+func (s *customIDShould) TestRouteToName() {
+	s.Equal("name", CustomIDName(newCustomID("name", "a", "b")))
+}
+
+func (s *customIDShould) TestCarryArgs() {
+	i := newComponentInteraction(newCustomID("name", "a", "b"))
+
+	s.Equal([]string{"a", "b"}, customIDArgs(i))
+}
+
+func (s *customIDShould) TestCarryNoArgs() {
+	i := newComponentInteraction(newCustomID("name"))
+
+	s.Empty(customIDArgs(i))
+}
+
+type requireManagerShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+	handled   bool
+	handle    Handler
+}
+
+func (s *requireManagerShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+	s.handled = false
+	s.handle = RequireManager(func(context.Context, Session, *discordgo.InteractionCreate) {
+		s.handled = true
+	})
+}
+
+func TestRequireManagerShould(t *testing.T) {
+	suite.Run(t, new(requireManagerShould))
+}
+
+func (s *requireManagerShould) TestRunHandlerForManageServer() {
+	i := newCommandInteraction("cmd")
+	i.Member.Permissions = discordgo.PermissionManageServer
+
+	s.handle(context.Background(), s.session, i)
+
+	s.True(s.handled)
+	s.Empty(s.responder.responses)
+}
+
+func (s *requireManagerShould) TestRunHandlerForAdministrator() {
+	i := newCommandInteraction("cmd")
+	i.Member.Permissions = discordgo.PermissionAdministrator
+
+	s.handle(context.Background(), s.session, i)
+
+	s.True(s.handled)
+}
+
+func (s *requireManagerShould) TestRunHandlerForManagerRole() {
+	store := useFakeStore(s.T())
+	s.Require().NoError(store.SetManagerRole(context.Background(), 100, 600))
+	i := newCommandInteraction("cmd")
+	i.Member.Roles = []string{"600"}
+
+	s.handle(context.Background(), s.session, i)
+
+	s.True(s.handled)
+	s.Empty(s.responder.responses)
+}
+
+func (s *requireManagerShould) TestRefuseMemberWithoutManagerRole() {
+	store := useFakeStore(s.T())
+	s.Require().NoError(store.SetManagerRole(context.Background(), 100, 600))
+	i := newCommandInteraction("cmd")
+	i.Member.Roles = []string{"700"}
+
+	s.handle(context.Background(), s.session, i)
+
+	s.False(s.handled)
+	s.Require().NotNil(s.responder.lastResponse())
+	s.Equal(discordgo.MessageFlagsEphemeral, s.responder.lastResponse().Data.Flags)
+}
+
+func (s *requireManagerShould) TestRefuseOutsideGuild() {
+	i := newCommandInteraction("cmd")
+	i.Member = nil
+
+	s.handle(context.Background(), s.session, i)
+
+	s.False(s.handled)
+	s.Require().NotNil(s.responder.lastResponse())
+	s.Equal(discordgo.MessageFlagsEphemeral, s.responder.lastResponse().Data.Flags)
+}
+
+type msgReplyHandlerShould struct {
+	suite.Suite
+}
+
+func TestMsgReplyHandlerShould(t *testing.T) {
+	suite.Run(t, new(msgReplyHandlerShould))
+}
+
+func (s *msgReplyHandlerShould) TestReplyWithData() {
+	session, responder := newTestSession()
+	data := &discordgo.InteractionResponseData{Content: "Content"}
+
+	NewMsgReplyHandler(data)(context.Background(), session, newCommandInteraction("cmd"))
+
+	s.Equal([]discordgo.InteractionResponseType{
+		discordgo.InteractionResponseChannelMessageWithSource,
+	}, responder.responseTypes())
+	s.Same(data, responder.lastResponse().Data)
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+)
+
+// trackerConfig is a guild's configuration in the form /export writes
+// and /import reads.
+type trackerConfig struct {
+	ChannelID     string      `json:"channel_id,omitempty"`
+	SaleThreshold int         `json:"sale_threshold"`
+	Apps          []configApp `json:"apps"`
+}
+
+// configApp is an app of a trackerConfig, or a package or bundle if it
+// has a Kind. SaleThreshold is 0 if the app uses the general threshold.
+type configApp struct {
+	Appid         int            `json:"app_id"`
+	Kind          steam.ItemKind `json:"kind,omitempty"`
+	AppName       string         `json:"app_name"`
+	SaleThreshold int            `json:"sale_threshold,omitempty"`
+	ComingSoon    bool           `json:"coming_soon,omitempty"`
+}
+
+// item references the app of a.
+func (a configApp) item() steam.Item {
+	if a.Kind == "" {
+		return steam.AppItem(a.Appid)
+	}
+	return steam.Item{Kind: a.Kind, ID: a.Appid}
+}
+
+// configCSVHeader is the header row of a trackerConfig's apps as CSV.
+var configCSVHeader = []string{"app_id", "app_name", "sale_threshold", "coming_soon", "kind"}
+
+// NewExport creates /export.
+func NewExport() Cmd {
+	return Cmd{
+		Name:        "export",
+		Description: "Export this server's tracker configuration as JSON and CSV files",
+		Handle:      exportHandler,
+	}
+}
+
+func exportHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Build config
+	config, err := currentConfig(ctx, guildID)
+	if err != nil {
+		EditReply(s, i, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{
+				{
+					Title:       "Export",
+					Description: "Failed to export configuration, please try again",
+				},
+			},
+		})
+		return
+	}
+
+	jsonBytes, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+	csvBytes, err := configAppsCSV(config.Apps)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Create embed reply with config files attached
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title: "Export",
+				Description: "Exported the configuration. Use **/import** with the JSON file " +
+					"to copy it to another server. The CSV file only has the apps",
+			},
+		},
+		Files: []*discordgo.File{
+			{
+				Name:        "tracker.json",
+				ContentType: "application/json",
+				Reader:      bytes.NewReader(jsonBytes),
+			},
+			{
+				Name:        "tracker.csv",
+				ContentType: "text/csv",
+				Reader:      bytes.NewReader(csvBytes),
+			},
+		},
+	})
+}
+
+// currentConfig builds the trackerConfig of the guild matching guildID.
+func currentConfig(ctx context.Context, guildID int64) (trackerConfig, error) {
+	guild, err := store.Guild(ctx, guildID)
+	if err != nil {
+		return trackerConfig{}, err
+	}
+	guildInfos, err := store.AppsOf(ctx, guildID)
+	if err != nil {
+		return trackerConfig{}, err
+	}
+
+	config := trackerConfig{
+		SaleThreshold: guild.SaleThreshold,
+		Apps:          make([]configApp, 0, len(guildInfos)),
+	}
+	if guild.ChannelID != 0 {
+		config.ChannelID = strconv.FormatInt(guild.ChannelID, 10)
+	}
+	for _, info := range guildInfos {
+		app := configApp{
+			Appid:         info.Appid,
+			AppName:       info.AppName,
+			SaleThreshold: info.AppSaleThreshold,
+			ComingSoon:    info.ComingSoon,
+		}
+		// Apps are left without a kind, like in files from before packages
+		// and bundles could be tracked
+		if kind := info.Item().Kind; kind != steam.KindApp {
+			app.Kind = kind
+		}
+		config.Apps = append(config.Apps, app)
+	}
+
+	return config, nil
+}
+
+// configAppsCSV writes apps as CSV with configCSVHeader as the header.
+func configAppsCSV(apps []configApp) ([]byte, error) {
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+
+	w.Write(configCSVHeader)
+	for _, app := range apps {
+		w.Write([]string{
+			strconv.Itoa(app.Appid),
+			app.AppName,
+			strconv.Itoa(app.SaleThreshold),
+			strconv.FormatBool(app.ComingSoon),
+			string(app.Kind),
+		})
+	}
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxFailedAlertsShown is how many of the most recent dead-lettered
+// alerts are listed so the embed stays within Discord's limits.
+const maxFailedAlertsShown = 15
+
+// NewFailedAlerts creates /failed_alerts.
+func NewFailedAlerts() Cmd {
+	return Cmd{
+		Name:        "failed_alerts",
+		Description: "List alerts that could not be delivered",
+		Handle:      failedAlertsHandler,
+		ManagerOnly: true,
+	}
+}
+
+func failedAlertsHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Get dead-lettered alerts and create embed reply
+	alerts, err := store.DeadAlerts(ctx, guildID)
+	var description string
+	var footer *discordgo.MessageEmbedFooter
+	switch {
+	case err != nil:
+		description = "Failed to get failed alerts, please try again"
+
+	case len(alerts) == 0:
+		description = "No alerts have failed to deliver"
+
+	default:
+		if len(alerts) > maxFailedAlertsShown {
+			footer = &discordgo.MessageEmbedFooter{
+				Text: fmt.Sprintf("Showing the %d most recent of %d failed alerts",
+					maxFailedAlertsShown, len(alerts)),
+			}
+			alerts = alerts[len(alerts)-maxFailedAlertsShown:]
+		}
+
+		sb := strings.Builder{}
+		for _, alert := range alerts {
+			sb.WriteString(fmt.Sprintf("<t:%d:f> %s (%d) to <#%d>\n",
+				alert.CreatedAt.Unix(), alert.Kind, alert.Appid, alert.ChannelID))
+			if n := len(alert.Attempts); n > 0 {
+				sb.WriteString(fmt.Sprintf("> %d attempt(s), last error: %s\n",
+					n, alert.Attempts[n-1].Error))
+			}
+		}
+		description = sb.String()
+	}
+
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title:       "Failed Alerts",
+				Description: description,
+				Footer:      footer,
+			},
+		},
+	})
+}
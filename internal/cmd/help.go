@@ -2,17 +2,18 @@ package cmd
 
 import (
 	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/schedule"
 )
 
-// NewHelp creates the /help command.
-func NewHelp() Cmd {
+// NewHelp creates the /help command. sched is the schedule sale checks run on.
+func NewHelp(sched schedule.Schedule) Cmd {
 	return Cmd{
 		Name:        "help",
 		Description: "Show a list of commands and their descriptions",
 		Handle: NewMsgReplyHandler(&discordgo.InteractionResponseData{
 			Embeds: []*discordgo.MessageEmbed{
 				{
-					Title: "Commands and FAQ",
+					Title: "Commands",
 					Fields: []*discordgo.MessageEmbedField{
 						{
 							Name: "/bind <text_channel>",
@@ -25,14 +26,33 @@ func NewHelp() Cmd {
 								"By default, the threshold is 1%. Optionally, specify specific appids the " +
 								"threshold applies to.",
 						},
+						{
+							Name: "/set_min_review_score <score>",
+							Value: "Set the percentage of positive reviews an app needs for its sales " +
+								"to be alerted. Apps without reviews are always alerted. By default, " +
+								"reviews are ignored.",
+						},
 						{
 							Name: "/add_apps <appid,appid, ...> <threshold>",
-							Value: "Add comma separated appids to the tracker. Optionally, specify a " +
+							Value: "Add comma separated appids to the tracker. Packages and bundles " +
+								"can be added as sub/<id> and bundle/<id>, or by their store URL. " +
+								"Optionally, specify a specific discount threshold.",
+						},
+						{
+							Name: "/add_apps_file <file> <threshold>",
+							Value: "Add apps from a text or CSV file with an appid, store URL, or app name " +
+								"on each line. Optionally, specify a specific discount threshold.",
+						},
+						{
+							Name: "/add_dlc <appid> <threshold>",
+							Value: "Add every DLC of a game to the tracker. DLC the game releases " +
+								"later is added and alerted automatically. Optionally, specify a " +
 								"specific discount threshold.",
 						},
 						{
-							Name:  "/remove_apps <appid,appid, ...>",
-							Value: "Remove comma separated appids from the tracker.",
+							Name: "/remove_apps <appid,appid, ...>",
+							Value: "Remove comma separated appids, sub/<id>'s, and bundle/<id>'s " +
+								"from the tracker.",
 						},
 						{
 							Name:  "/search <query>",
@@ -45,15 +65,84 @@ func NewHelp() Cmd {
 							Name:  "/clear_apps",
 							Value: "Clear the tracking list.",
 						},
+						{
+							Name: "/restore_apps",
+							Value: "Restore the apps most recently removed or cleared. " +
+								"Removed apps can be restored for 7 days.",
+						},
+						{
+							Name:  "/export",
+							Value: "Export the channel, thresholds, and apps as JSON and CSV files.",
+						},
+						{
+							Name: "/import <file> <mode>",
+							Value: "Import a file made by /export, e.g., from another server. Previews " +
+								"the changes first. Optionally, replace the current apps instead of merging.",
+						},
+						{
+							Name: "/import_wishlist <profile> <sync>",
+							Value: "Pick apps to add from a public Steam wishlist. Optionally, sync the " +
+								"wishlist so apps wishlisted later are added after each daily check.",
+						},
+						{
+							Name: "/alert_fields <field> <shown>",
+							Value: "Show or hide a field of sale alerts, like the genres or " +
+								"lowest alerted price. Leave empty to list the fields.",
+						},
+						{
+							Name: "/check_now",
+							Value: "Check this server's apps for sales now instead of waiting for " +
+								"the daily check. Server managers only, once per hour.",
+						},
+						{
+							Name: "/set_manager_role <role>",
+							Value: "Set the role allowed to change the tracker's configuration, in addition " +
+								"to members with Manage Server. Leave empty to unset.",
+						},
+						{
+							Name:  "/status",
+							Value: "Show whether alerts can be delivered to the bound channel.",
+						},
+						{
+							Name: "/history <appid> <days>",
+							Value: "Show the alerts sent to this server in the last 30 days, or the given " +
+								"number of days. Optionally, only show alerts for one app.",
+						},
+						{
+							Name:  "/failed_alerts",
+							Value: "List alerts that could not be delivered after retrying.",
+						},
+						{
+							Name:  "/audit_log",
+							Value: "Show who changed the channel, thresholds, or apps, and when.",
+						},
+						{
+							Name: "/set_audit_channel <channel>",
+							Value: "Also log configuration changes to a channel. " +
+								"Leave empty to unset.",
+						},
+					},
+				},
+				// Discord allows at most 25 fields per embed, so the FAQ is
+				// kept in its own
+				{
+					Title: "FAQ",
+					Fields: []*discordgo.MessageEmbedField{
+						{
+							Name: "Who can change the configuration?",
+							Value: "Only server managers, i.e., members with Manage Server or the manager role. " +
+								"/list_apps, /status, /history, /export, and /help are open to everyone.",
+							Inline: true,
+						},
 						{
 							Name:   "How often does the bot check for sales?",
-							Value:  "The bot checks for sales every day at about **10:05 AM (PDT)**.",
+							Value:  "The bot checks for sales every day at about **" + sched.String() + "**.",
 							Inline: true,
 						},
 						{
 							Name: "Why aren't alerts showing up?",
 							Value: "Reconfigure your discount threshold in case it is too high. " +
-								"Additionally, try rebinding to a text channel.",
+								"Additionally, check /status and try rebinding to a text channel.",
 							Inline: true,
 						},
 						{
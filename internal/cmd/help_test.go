@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/schedule"
+	"github.com/stretchr/testify/suite"
+)
+
+type helpShould struct {
+	suite.Suite
+}
+
+func TestHelpShould(t *testing.T) {
+	suite.Run(t, new(helpShould))
+}
+
+func (s *helpShould) TestKeepEmbedsWithinFieldLimit() {
+	session, responder := newTestSession()
+
+	NewHelp(schedule.Default()).Handle(context.Background(), session, newCommandInteraction("help"))
+
+	resp := responder.lastResponse()
+	s.Require().NotNil(resp)
+	for _, em := range resp.Data.Embeds {
+		s.LessOrEqual(len(em.Fields), 25, em.Title)
+	}
+}
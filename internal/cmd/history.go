@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+)
+
+var historyCompPage = "historyCompPage"
+
+// historyPageSize is how many alerts are shown per page of /history.
+const historyPageSize = 10
+
+// defaultHistoryDays is how many days back /history looks by default.
+const defaultHistoryDays = 30
+
+// NewHistory creates /history <appid> <days>.
+func NewHistory() Cmd {
+	minAppid := float64(1)
+	minDays := float64(1)
+	return Cmd{
+		Name:        "history",
+		Description: "Show the alerts sent to this server",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "appid",
+				Description: "Only show alerts for this app",
+				MinValue:    &minAppid,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "days",
+				Description: fmt.Sprintf("How many days back to look (default %d)", defaultHistoryDays),
+				MinValue:    &minDays,
+				MaxValue:    365,
+			},
+		},
+		Handle: historyHandler,
+		CompHandlers: []ComponentHandler{
+			{
+				Name:   historyCompPage,
+				Handle: historyCompPageHandler,
+			},
+		},
+	}
+}
+
+func historyHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Parse options
+	filter := db.HistoryFilter{}
+	days := defaultHistoryDays
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "appid":
+			filter.Appid = int(opt.IntValue())
+		case "days":
+			days = int(opt.IntValue())
+		}
+	}
+	// Fixed to the second so later pages carry the same filter
+	filter.Since = time.Now().AddDate(0, 0, -days).Truncate(time.Second)
+
+	em, components := historyPage(ctx, guildID, filter, 0)
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{em},
+		Components: &components,
+	})
+}
+
+func historyCompPageHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	DeferCompReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Parse filter and page
+	args := customIDArgs(i)
+	if len(args) != 3 {
+		EditReplyUnexpected(s, i)
+		return
+	}
+	appid, err1 := strconv.Atoi(args[0])
+	since, err2 := strconv.ParseInt(args[1], 10, 64)
+	page, err3 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+	filter := db.HistoryFilter{Appid: appid, Since: time.Unix(since, 0)}
+
+	em, components := historyPage(ctx, guildID, filter, page)
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{em},
+		Components: &components,
+	})
+}
+
+// historyPage creates the embed and components showing a page of the
+// alert history of the guild matching guildID.
+func historyPage(ctx context.Context, guildID int64, filter db.HistoryFilter,
+	page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	em := &discordgo.MessageEmbed{Title: "Alert History"}
+	components := []discordgo.MessageComponent{}
+
+	alerts, total, err := store.History(ctx, guildID, filter, page*historyPageSize, historyPageSize)
+	switch {
+	case err != nil:
+		em.Description = "Failed to get the alert history, please try again"
+		return em, components
+
+	case total == 0:
+		em.Description = fmt.Sprintf("No alerts have been sent since <t:%d:D>",
+			filter.Since.Unix())
+		if filter.Appid != 0 {
+			em.Description = fmt.Sprintf("No alerts have been sent for %d since <t:%d:D>",
+				filter.Appid, filter.Since.Unix())
+		}
+		return em, components
+	}
+
+	sb := strings.Builder{}
+	for _, alert := range alerts {
+		sb.WriteString(fmt.Sprintf("<t:%d:f> %s **%s** (%s) to <#%d>\n",
+			alert.SentAt.Unix(), alert.Kind, alert.AppName, alert.Item(), alert.ChannelID))
+
+		details := []string{}
+		if alert.Discount > 0 {
+			details = append(details, fmt.Sprintf("-%d%%", alert.Discount))
+		}
+		if alert.Price != "" {
+			details = append(details, alert.Price)
+		}
+		details = append(details, string(alert.State))
+		sb.WriteString("> " + strings.Join(details, ", ") + "\n")
+	}
+	em.Description = sb.String()
+
+	pages := int((total + historyPageSize - 1) / historyPageSize)
+	em.Footer = &discordgo.MessageEmbedFooter{
+		Text: fmt.Sprintf("Page %d of %d, %d alerts", page+1, pages, total),
+	}
+
+	if pages > 1 {
+		pageID := func(page int) string {
+			return newCustomID(historyCompPage, strconv.Itoa(filter.Appid),
+				strconv.FormatInt(filter.Since.Unix(), 10), strconv.Itoa(page))
+		}
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Previous",
+					Style:    discordgo.SecondaryButton,
+					CustomID: pageID(page - 1),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Label:    "Next",
+					Style:    discordgo.SecondaryButton,
+					CustomID: pageID(page + 1),
+					Disabled: page >= pages-1,
+				},
+			},
+		})
+	}
+
+	return em, components
+}
@@ -0,0 +1,654 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var importCompApply = "importCompApply"
+var importCompCancel = "importCompCancel"
+
+// importExpiry is how long a previewed import can be applied for.
+const importExpiry = 10 * time.Minute
+
+// maxFieldLen is the most characters Discord allows in an embed field value.
+const maxFieldLen = 1024
+
+// pendingImport is an import that has been previewed but not applied yet.
+type pendingImport struct {
+	guildID int64
+	config  trackerConfig
+	replace bool
+	created time.Time
+}
+
+// pendingImports are the previewed imports, keyed by the ID of the
+// interaction that previewed them.
+var pendingImports = struct {
+	sync.Mutex
+	m map[string]pendingImport
+}{m: map[string]pendingImport{}}
+
+// configDiff is what applying a trackerConfig to a guild changes.
+type configDiff struct {
+	add           []configApp
+	remove        []configApp
+	thresholds    []configApp // Apps already tracked whose threshold changes
+	saleThreshold int         // 0 if unchanged
+	channelID     string      // "" if unchanged
+	skipChannel   bool        // Whether the channel isn't in this guild
+}
+
+func (d configDiff) empty() bool {
+	return len(d.add) == 0 && len(d.remove) == 0 && len(d.thresholds) == 0 &&
+		d.saleThreshold == 0 && d.channelID == ""
+}
+
+// NewImport creates /import <file> <mode>.
+func NewImport() Cmd {
+	return Cmd{
+		Name:        "import",
+		Description: "Import a tracker configuration from a file made by /export",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionAttachment,
+				Name:        "file",
+				Description: "The JSON or CSV file made by /export",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "mode",
+				Description: "Merge with the current apps (default) or replace them",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "merge", Value: "merge"},
+					{Name: "replace", Value: "replace"},
+				},
+			},
+		},
+		Handle: importHandler,
+		CompHandlers: []ComponentHandler{
+			{
+				Name:   importCompApply,
+				Handle: importCompApplyHandler,
+			},
+			{
+				Name:   importCompCancel,
+				Handle: importCompCancelHandler,
+			},
+		},
+		ManagerOnly: true,
+	}
+}
+
+func importHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	reply := func(description string) {
+		EditReply(s, i, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{
+				{
+					Title:       "Import",
+					Description: description,
+				},
+			},
+		})
+	}
+
+	// Parse options
+	opts := i.ApplicationCommandData().Options
+	att, err := attachmentValue(i, opts[0])
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+	replace := len(opts) > 1 && opts[1].StringValue() == "replace"
+
+	// Read config
+	data, err := downloadAttachment(att)
+	if err != nil {
+		reply("Failed to download the file, please try again")
+		return
+	}
+	config, err := parseConfig(att.Filename, data)
+	if err != nil {
+		reply("Failed to read the file. Make sure it's a JSON or CSV file made by /export: " +
+			err.Error())
+		return
+	}
+
+	// Compare with current config
+	current, err := currentConfig(ctx, guildID)
+	if err != nil {
+		reply("Failed to get the current configuration, please try again")
+		return
+	}
+	diff := diffConfig(s, i.GuildID, current, config, replace)
+	if diff.empty() {
+		reply("Nothing to import, the configuration already matches")
+		return
+	}
+
+	// Save import for when it's applied
+	pendingImports.Lock()
+	for id, p := range pendingImports.m {
+		if time.Since(p.created) > importExpiry {
+			delete(pendingImports.m, id)
+		}
+	}
+	pendingImports.m[i.ID] = pendingImport{
+		guildID: guildID,
+		config:  config,
+		replace: replace,
+		created: time.Now(),
+	}
+	pendingImports.Unlock()
+
+	// Create preview embed reply
+	em := diffEmbed(diff)
+	em.Description = "Review the changes below, then apply or cancel"
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{em},
+		Components: &[]discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Apply",
+						Style:    discordgo.PrimaryButton,
+						CustomID: newCustomID(importCompApply, i.ID),
+					},
+					discordgo.Button{
+						Label:    "Cancel",
+						Style:    discordgo.SecondaryButton,
+						CustomID: newCustomID(importCompCancel, i.ID),
+					},
+				},
+			},
+		},
+	})
+}
+
+func importCompApplyHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	DeferCompReply(s, i)
+
+	reply := func(em *discordgo.MessageEmbed, components []discordgo.MessageComponent) {
+		EditReply(s, i, &discordgo.WebhookEdit{
+			Embeds:     &[]*discordgo.MessageEmbed{em},
+			Components: &components,
+		})
+	}
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Take the pending import
+	args := customIDArgs(i)
+	if len(args) != 1 {
+		EditReplyUnexpected(s, i)
+		return
+	}
+	pendingImports.Lock()
+	pending, ok := pendingImports.m[args[0]]
+	delete(pendingImports.m, args[0])
+	pendingImports.Unlock()
+	if !ok || pending.guildID != guildID || time.Since(pending.created) > importExpiry {
+		reply(&discordgo.MessageEmbed{
+			Title:       "Import",
+			Description: "This import has expired, please use /import again",
+		}, []discordgo.MessageComponent{})
+		return
+	}
+
+	// Recompare in case the configuration changed since the preview
+	current, err := currentConfig(ctx, guildID)
+	if err != nil {
+		reply(&discordgo.MessageEmbed{
+			Title:       "Import",
+			Description: "Failed to get the current configuration, please try again",
+		}, []discordgo.MessageComponent{})
+		return
+	}
+	diff := diffConfig(s, i.GuildID, current, pending.config, pending.replace)
+
+	progressEmbed := func(done, total int) *discordgo.MessageEmbed {
+		return &discordgo.MessageEmbed{
+			Title:       "Import",
+			Description: fmt.Sprintf("Processed %d of %d apps to add...", done, total),
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Note: Importing may pause if Steam rate limits requests",
+			},
+		}
+	}
+	reply(progressEmbed(0, len(diff.add)), []discordgo.MessageComponent{})
+
+	// Apply in the background since apps are resolved through Steam,
+	// updating the reply as they are
+	ctx = jobContext(ctx)
+	go func() {
+		progress := newProgressReporter(s, i, progressEmbed)
+
+		em, trashID, applied := applyConfigDiff(ctx, guildID, diff, progress)
+		components := []discordgo.MessageComponent{}
+		if !trashID.IsZero() {
+			components = append(components, undoRow(trashID))
+		}
+		finishJob(s, i, em, nil, components)
+
+		if !applied.empty() {
+			before, after := auditConfigDiff(applied)
+			recordAudit(ctx, s, i, "import", before, after)
+		}
+	}()
+}
+
+func importCompCancelHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	if args := customIDArgs(i); len(args) == 1 {
+		pendingImports.Lock()
+		delete(pendingImports.m, args[0])
+		pendingImports.Unlock()
+	}
+
+	CompReply(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "Import",
+				Description: "Import cancelled",
+			},
+		},
+		Components: []discordgo.MessageComponent{},
+	})
+}
+
+// applyConfigDiff applies diff to the guild matching guildID and creates
+// an embed describing the outcome. trashID is the snapshot of any apps the
+// diff removed. applied is the part of diff that succeeded. progress is
+// called as each app to add is resolved.
+func applyConfigDiff(ctx context.Context, guildID int64, diff configDiff,
+	progress func(done, total int)) (
+	em *discordgo.MessageEmbed, trashID primitive.ObjectID, applied configDiff) {
+	em = &discordgo.MessageEmbed{Title: "Import"}
+	var failed []string
+
+	// Remove apps
+	if len(diff.remove) > 0 {
+		items := make([]steam.Item, 0, len(diff.remove))
+		for _, app := range diff.remove {
+			items = append(items, app.item())
+		}
+
+		var succ, fail []steam.Item
+		succ, fail, trashID = store.RemoveApps(ctx, guildID, items)
+		applied.remove = keepItems(diff.remove, succ)
+		for _, item := range fail {
+			failed = append(failed, fmt.Sprintf("Remove %s", item))
+		}
+	}
+
+	// Add apps, as Steam has them rather than as the file does. Only the
+	// threshold is taken from the file. Rate limits are waited out.
+	if len(diff.add) > 0 {
+		apps := make([]*steam.App, 0, len(diff.add))
+		for idx, app := range diff.add {
+			a, err := steam.WaitForLimit(ctx, func() (*steam.App, error) {
+				return newTrackableApp(ctx, app.item())
+			})
+			progress(idx+1, len(diff.add))
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("Add %s", app.item()))
+				continue
+			}
+			if app.SaleThreshold > 0 {
+				saleThreshold := app.SaleThreshold
+				a.SaleThreshold = &saleThreshold
+			}
+			apps = append(apps, a)
+		}
+
+		succ, fail := store.AddApps(ctx, guildID, apps)
+		added := make([]steam.Item, 0, len(succ))
+		for _, app := range succ {
+			added = append(added, app.Item())
+		}
+		applied.add = keepItems(diff.add, added)
+		for _, app := range fail {
+			failed = append(failed, fmt.Sprintf("Add %s", app.Item()))
+		}
+	}
+
+	// Update app thresholds, grouped by threshold
+	byThreshold := map[int][]steam.Item{}
+	for _, app := range diff.thresholds {
+		byThreshold[app.SaleThreshold] = append(byThreshold[app.SaleThreshold], app.item())
+	}
+	for threshold, items := range byThreshold {
+		succ, fail := store.SetThresholds(ctx, guildID, threshold, items)
+		applied.thresholds = append(applied.thresholds, keepItems(diff.thresholds, succ)...)
+		for _, item := range fail {
+			failed = append(failed, fmt.Sprintf("Threshold of %s", item))
+		}
+	}
+
+	// Update guild settings
+	if diff.saleThreshold != 0 {
+		if err := store.SetThreshold(ctx, guildID, diff.saleThreshold); err != nil {
+			failed = append(failed, "General discount threshold")
+		} else {
+			applied.saleThreshold = diff.saleThreshold
+		}
+	}
+	if diff.channelID != "" {
+		channelID, err := strconv.ParseInt(diff.channelID, 10, 64)
+		if err != nil || store.SetChannelID(ctx, guildID, channelID) != nil {
+			failed = append(failed, "Channel")
+		} else {
+			applied.channelID = diff.channelID
+		}
+	}
+
+	em.Fields = diffEmbed(diff).Fields
+	if len(failed) > 0 {
+		em.Description = "Imported with some failures, please try again"
+		em.Fields = append(em.Fields, listField("Failed", failed))
+	} else {
+		em.Description = "Successfully imported"
+	}
+
+	return em, trashID, applied
+}
+
+// keepItems gets the apps of apps that items references.
+func keepItems(apps []configApp, items []steam.Item) []configApp {
+	kept := []configApp{}
+	for _, app := range apps {
+		if slices.Contains(items, app.item()) {
+			kept = append(kept, app)
+		}
+	}
+	return kept
+}
+
+// auditConfigDiff describes diff for the audit log. before has the removed
+// apps, after has everything else.
+func auditConfigDiff(diff configDiff) (before, after string) {
+	describe := func(app configApp) string {
+		return fmt.Sprintf("%s (%s)", app.AppName, app.item())
+	}
+
+	removed := make([]string, 0, len(diff.remove))
+	for _, app := range diff.remove {
+		removed = append(removed, describe(app))
+	}
+
+	changes := []string{}
+	for _, app := range diff.add {
+		changes = append(changes, "Added "+describe(app))
+	}
+	for _, app := range diff.thresholds {
+		threshold := "general"
+		if app.SaleThreshold > 0 {
+			threshold = fmt.Sprintf("%d%%", app.SaleThreshold)
+		}
+		changes = append(changes, fmt.Sprintf("%s: %s", describe(app), threshold))
+	}
+	if diff.saleThreshold != 0 {
+		changes = append(changes,
+			fmt.Sprintf("General discount threshold: %d%%", diff.saleThreshold))
+	}
+	if diff.channelID != "" {
+		changes = append(changes, "Channel: <#"+diff.channelID+">")
+	}
+
+	return strings.Join(removed, ", "), strings.Join(changes, ", ")
+}
+
+// parseConfig reads a trackerConfig from a JSON file or the apps of a
+// trackerConfig from a CSV file. filename decides which.
+func parseConfig(filename string, data []byte) (trackerConfig, error) {
+	var config trackerConfig
+	if strings.HasSuffix(strings.ToLower(filename), ".csv") {
+		apps, err := parseConfigAppsCSV(data)
+		if err != nil {
+			return trackerConfig{}, err
+		}
+		config.Apps = apps
+	} else if err := json.Unmarshal(data, &config); err != nil {
+		return trackerConfig{}, errors.New("invalid JSON")
+	}
+
+	if config.SaleThreshold < 0 || config.SaleThreshold > 99 {
+		return trackerConfig{}, errors.New("sale_threshold must be between 1 and 99, " +
+			"or 0 to keep the current one")
+	}
+	if config.ChannelID != "" {
+		if _, err := strconv.ParseInt(config.ChannelID, 10, 64); err != nil {
+			return trackerConfig{}, errors.New("invalid channel_id")
+		}
+	}
+	for _, app := range config.Apps {
+		if app.Appid <= 0 {
+			return trackerConfig{}, fmt.Errorf("invalid app_id %d", app.Appid)
+		}
+		switch app.Kind {
+		case "", steam.KindApp, steam.KindSub, steam.KindBundle:
+		default:
+			return trackerConfig{}, fmt.Errorf("invalid kind %q of %d", app.Kind, app.Appid)
+		}
+		if app.SaleThreshold < 0 || app.SaleThreshold > 99 {
+			return trackerConfig{}, fmt.Errorf("sale_threshold %d of %s must be between 1 and 99, "+
+				"or 0 to use the general threshold", app.SaleThreshold, app.item())
+		}
+	}
+
+	return config, nil
+}
+
+// parseConfigAppsCSV reads apps from CSV whose header is configCSVHeader.
+// Only the app_id column is required.
+func parseConfigAppsCSV(data []byte) ([]configApp, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, errors.New("invalid CSV")
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty CSV")
+	}
+
+	// Map columns from the header
+	cols := map[string]int{}
+	for idx, name := range records[0] {
+		cols[strings.TrimSpace(name)] = idx
+	}
+	if _, ok := cols["app_id"]; !ok {
+		return nil, errors.New("missing app_id column")
+	}
+	field := func(record []string, name string) string {
+		if idx, ok := cols[name]; ok && idx < len(record) {
+			return strings.TrimSpace(record[idx])
+		}
+		return ""
+	}
+
+	apps := []configApp{}
+	for line, record := range records[1:] {
+		appid, err := strconv.Atoi(field(record, "app_id"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid app_id on line %d", line+2)
+		}
+
+		app := configApp{
+			Appid:   appid,
+			Kind:    steam.ItemKind(field(record, "kind")),
+			AppName: field(record, "app_name"),
+		}
+		if str := field(record, "sale_threshold"); str != "" {
+			if app.SaleThreshold, err = strconv.Atoi(str); err != nil {
+				return nil, fmt.Errorf("invalid sale_threshold on line %d", line+2)
+			}
+		}
+		if str := field(record, "coming_soon"); str != "" {
+			if app.ComingSoon, err = strconv.ParseBool(str); err != nil {
+				return nil, fmt.Errorf("invalid coming_soon on line %d", line+2)
+			}
+		}
+
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+// diffConfig compares the current config of a guild with config. When
+// replace is true, apps not in config are removed. The channel of config
+// is only used if it belongs to the guild and alerts can be sent there.
+func diffConfig(s Session, guildID string,
+	current, config trackerConfig, replace bool) configDiff {
+	var diff configDiff
+
+	currentApps := map[steam.Item]configApp{}
+	for _, app := range current.Apps {
+		currentApps[app.item()] = app
+	}
+
+	seen := map[steam.Item]bool{}
+	for _, app := range config.Apps {
+		if seen[app.item()] {
+			continue
+		}
+		seen[app.item()] = true
+
+		curr, tracked := currentApps[app.item()]
+		switch {
+		case !tracked:
+			diff.add = append(diff.add, app)
+		case curr.SaleThreshold != app.SaleThreshold:
+			diff.thresholds = append(diff.thresholds, app)
+		}
+	}
+
+	if replace {
+		for _, app := range current.Apps {
+			if !seen[app.item()] {
+				diff.remove = append(diff.remove, app)
+			}
+		}
+	}
+
+	if config.SaleThreshold != 0 && config.SaleThreshold != current.SaleThreshold {
+		diff.saleThreshold = config.SaleThreshold
+	}
+
+	if config.ChannelID != "" && config.ChannelID != current.ChannelID {
+		ch, err := s.State.Channel(config.ChannelID)
+		if err == nil && ch.GuildID == guildID && CanSendAlerts(s.State, config.ChannelID) {
+			diff.channelID = config.ChannelID
+		} else {
+			diff.skipChannel = true
+		}
+	}
+
+	return diff
+}
+
+// diffEmbed creates an embed with a field for each kind of change in diff.
+func diffEmbed(diff configDiff) *discordgo.MessageEmbed {
+	em := &discordgo.MessageEmbed{Title: "Import"}
+
+	appLines := func(apps []configApp) []string {
+		lines := make([]string, 0, len(apps))
+		for _, app := range apps {
+			line := fmt.Sprintf("%s (%s)", app.AppName, app.item())
+			if app.SaleThreshold > 0 {
+				line += fmt.Sprintf(" (%d%%)", app.SaleThreshold)
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	if len(diff.add) > 0 {
+		em.Fields = append(em.Fields,
+			listField(fmt.Sprintf("Apps to add (%d)", len(diff.add)), appLines(diff.add)))
+	}
+	if len(diff.remove) > 0 {
+		em.Fields = append(em.Fields,
+			listField(fmt.Sprintf("Apps to remove (%d)", len(diff.remove)), appLines(diff.remove)))
+	}
+	if len(diff.thresholds) > 0 {
+		lines := appLines(diff.thresholds)
+		for idx, app := range diff.thresholds {
+			if app.SaleThreshold == 0 {
+				lines[idx] += " (general threshold)"
+			}
+		}
+		em.Fields = append(em.Fields,
+			listField(fmt.Sprintf("Thresholds to change (%d)", len(lines)), lines))
+	}
+
+	settings := []string{}
+	if diff.saleThreshold != 0 {
+		settings = append(settings,
+			fmt.Sprintf("General discount threshold: %d%%", diff.saleThreshold))
+	}
+	if diff.channelID != "" {
+		settings = append(settings, "Channel: <#"+diff.channelID+">")
+	}
+	if diff.skipChannel {
+		settings = append(settings, "Channel: unchanged, the file's channel isn't usable here")
+	}
+	if len(settings) > 0 {
+		em.Fields = append(em.Fields, listField("Settings", settings))
+	}
+
+	return em
+}
+
+// listField creates an embed field listing lines. Lines that don't fit
+// within Discord's limits are summarized.
+func listField(name string, lines []string) *discordgo.MessageEmbedField {
+	sb := strings.Builder{}
+	for idx, line := range lines {
+		// Leave room to summarize the rest unless this is the last line
+		limit := maxFieldLen
+		more := fmt.Sprintf("...and %d more", len(lines)-idx)
+		if idx < len(lines)-1 {
+			limit -= len(more)
+		}
+
+		if sb.Len()+len(line)+1 > limit {
+			sb.WriteString(more)
+			break
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	return &discordgo.MessageEmbedField{
+		Name:  name,
+		Value: sb.String(),
+	}
+}
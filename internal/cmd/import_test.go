@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
+	"github.com/stretchr/testify/suite"
+)
+
+type importShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+	store     *fakeStore
+}
+
+func (s *importShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+	s.store = useFakeStore(s.T())
+}
+
+func TestImportShould(t *testing.T) {
+	suite.Run(t, new(importShould))
+}
+
+func (s *importShould) TestReadAppsUsingGeneralThreshold() {
+	config, err := parseConfig("config.csv", []byte("app_id\n400\n"))
+
+	s.Nil(err)
+	s.Equal([]configApp{{Appid: 400}}, config.Apps)
+}
+
+func (s *importShould) TestErrOnOutOfRangeAppThreshold() {
+	_, err := parseConfig("config.json",
+		[]byte(`{"apps": [{"app_id": 469, "kind": "sub", "sale_threshold": 100}]}`))
+
+	s.EqualError(err,
+		"sale_threshold 100 of sub/469 must be between 1 and 99, or 0 to use the general threshold")
+}
+
+func (s *importShould) TestErrOnOutOfRangeGeneralThreshold() {
+	_, err := parseConfig("config.json", []byte(`{"sale_threshold": -1}`))
+
+	s.EqualError(err, "sale_threshold must be between 1 and 99, or 0 to keep the current one")
+}
+
+func (s *importShould) TestAuditRemovedAppsAsBefore() {
+	before, after := auditConfigDiff(configDiff{
+		add:           []configApp{{Appid: 620, AppName: "Portal 2"}},
+		remove:        []configApp{{Appid: 400, AppName: "Portal"}},
+		thresholds:    []configApp{{Appid: 469, Kind: steam.KindSub, AppName: "Pack"}},
+		saleThreshold: 50,
+	})
+
+	s.Equal("Portal (400)", before)
+	s.Equal("Added Portal 2 (620), Pack (sub/469): general, General discount threshold: 50%",
+		after)
+}
+
+func (s *importShould) TestApplyPendingImportInBackground() {
+	useSteamServer(s.T(), steamtest.CannedApps...)
+	s.store.AddApps(context.Background(), 100, []*steam.App{{Appid: 400, Name: "Portal"}})
+	pendingImports.Lock()
+	pendingImports.m["1"] = pendingImport{
+		guildID: 100,
+		config:  trackerConfig{Apps: []configApp{{Appid: 620, SaleThreshold: 50}}},
+		replace: true,
+		created: time.Now(),
+	}
+	pendingImports.Unlock()
+
+	importCompApplyHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importCompApply, "1")))
+
+	var edit *discordgo.WebhookEdit
+	s.Require().Eventually(func() bool {
+		edit = s.responder.lastEdit()
+		return edit != nil && (*edit.Embeds)[0].Description == "Successfully imported" &&
+			len(s.store.auditLog()) > 0
+	}, time.Second, time.Millisecond)
+	s.Require().NotNil(edit.Components)
+	s.Len(*edit.Components, 1)
+	s.Nil(s.store.app(100, 400))
+	app := s.store.app(100, 620)
+	s.Require().NotNil(app)
+	s.Equal("Portal 2", app.AppName)
+	s.Equal(50, app.AppSaleThreshold)
+	s.Equal("Portal (400)", s.store.auditLog()[0].Before)
+}
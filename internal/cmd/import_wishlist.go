@@ -0,0 +1,575 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+)
+
+var importWishlistCompSelect = "importWishlistCompSelect"
+var importWishlistCompPage = "importWishlistCompPage"
+var importWishlistCompAdd = "importWishlistCompAdd"
+var importWishlistCompCancel = "importWishlistCompCancel"
+
+// wishlistPageSize is the most wishlist items shown per page of a preview.
+// It matches the most options Discord allows in a select menu.
+const wishlistPageSize = 25
+
+// pendingWishlist is a wishlist that has been previewed but not added yet.
+type pendingWishlist struct {
+	guildID  int64
+	items    []steam.WishlistItem
+	selected map[int]bool // Appids of the selected items
+	page     int
+	created  time.Time
+	sync     *bool // Whether to start or stop syncing once added, or nil to leave it
+	steamID  int64 // SteamID64 of the wishlist's owner
+	appids   []int // Appids of every item on the wishlist, tracked and free included
+}
+
+// pages gets the number of pages of the preview.
+func (p *pendingWishlist) pages() int {
+	return (len(p.items) + wishlistPageSize - 1) / wishlistPageSize
+}
+
+// pageItems gets the items on the current page of the preview.
+func (p *pendingWishlist) pageItems() []steam.WishlistItem {
+	start := p.page * wishlistPageSize
+	end := min(start+wishlistPageSize, len(p.items))
+	return p.items[start:end]
+}
+
+// pendingWishlists are the previewed wishlists, keyed by the ID of the
+// interaction that previewed them.
+var pendingWishlists = struct {
+	sync.Mutex
+	m map[string]*pendingWishlist
+}{m: map[string]*pendingWishlist{}}
+
+// NewImportWishlist creates /import_wishlist <profile> <sync>.
+func NewImportWishlist(wishlists steam.WishlistFetcher) Cmd {
+	return Cmd{
+		Name:        "import_wishlist",
+		Description: "Add apps to the tracker from a public Steam wishlist",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "profile",
+				Description: "The Steam profile URL or SteamID64 whose wishlist to import",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "sync",
+				Description: "Whether to automatically add apps wishlisted later (daily)",
+			},
+		},
+		Handle: func(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+			importWishlistHandler(ctx, s, i, wishlists)
+		},
+		CompHandlers: []ComponentHandler{
+			{
+				Name:   importWishlistCompSelect,
+				Handle: importWishlistCompSelectHandler,
+			},
+			{
+				Name:   importWishlistCompPage,
+				Handle: importWishlistCompPageHandler,
+			},
+			{
+				Name:   importWishlistCompAdd,
+				Handle: importWishlistCompAddHandler,
+			},
+			{
+				Name:   importWishlistCompCancel,
+				Handle: importWishlistCompCancelHandler,
+			},
+		},
+		ManagerOnly: true,
+	}
+}
+
+func importWishlistHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate, wishlists steam.WishlistFetcher) {
+	DeferMsgReply(s, i)
+
+	reply := func(description string) {
+		EditReply(s, i, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{
+				{
+					Title:       "Import Wishlist",
+					Description: description,
+				},
+			},
+		})
+	}
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Parse options
+	opts := i.ApplicationCommandData().Options
+	profile := opts[0].StringValue()
+	var syncOpt *bool
+	if len(opts) > 1 {
+		v := opts[1].BoolValue()
+		syncOpt = &v
+	}
+
+	// Fetch wishlist
+	steamID, err := wishlists.ResolveProfile(ctx, profile)
+	if err == steam.ErrNetTryAgainLater {
+		reply("Steam is rate limiting requests, please try again in a few minutes")
+		return
+	} else if err != nil {
+		reply("Couldn't find that profile. Use a profile URL or SteamID64")
+		return
+	}
+	items, err := wishlists.Wishlist(ctx, steamID)
+	switch {
+	case err == steam.ErrPrivateWishlist:
+		reply("That wishlist is private. The profile's game details must be public")
+		return
+	case err == steam.ErrNetTryAgainLater:
+		reply("Steam is rate limiting requests, please try again in a few minutes")
+		return
+	case err != nil:
+		reply("Failed to fetch the wishlist, please try again")
+		return
+	}
+
+	// Syncing is only updated once the preview is confirmed
+	syncNote := ""
+	if syncOpt != nil {
+		if *syncOpt {
+			syncNote = "Once confirmed, apps wishlisted later will be added automatically"
+		} else {
+			syncNote = "Once confirmed, the wishlist will stop being synced"
+		}
+	}
+
+	// Filter out free and already tracked apps
+	guildInfos, err := store.AppsOf(ctx, guildID)
+	if err != nil {
+		reply("Failed to get the tracked apps, please try again")
+		return
+	}
+	tracked := make(map[int]bool, len(guildInfos))
+	for _, info := range guildInfos {
+		tracked[info.Appid] = true
+	}
+	pending := &pendingWishlist{
+		guildID:  guildID,
+		selected: map[int]bool{},
+		created:  time.Now(),
+		sync:     syncOpt,
+		steamID:  steamID,
+		appids:   make([]int, 0, len(items)),
+	}
+	for _, item := range items {
+		pending.appids = append(pending.appids, item.Appid)
+		if !item.Free && !tracked[item.Appid] {
+			pending.items = append(pending.items, item)
+		}
+	}
+
+	if len(pending.items) == 0 && syncOpt == nil {
+		reply("Every paid app on that wishlist is already being tracked")
+		return
+	}
+
+	pendingWishlists.Lock()
+	pendingWishlists.m[i.ID] = pending
+	pendingWishlists.Unlock()
+
+	var em *discordgo.MessageEmbed
+	var components []discordgo.MessageComponent
+	if len(pending.items) > 0 {
+		em, components = wishlistPreview(i.ID, pending)
+	} else {
+		em, components = wishlistSyncPreview(i.ID)
+	}
+	if syncNote != "" {
+		em.Footer = &discordgo.MessageEmbedFooter{Text: syncNote}
+	}
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{em},
+		Components: &components,
+	})
+}
+
+// takeWishlist gets the pending wishlist carried by the custom ID of a
+// component interaction. If remove, the pending wishlist is also removed.
+func takeWishlist(i *discordgo.InteractionCreate, remove bool) (key string,
+	pending *pendingWishlist, ok bool) {
+	args := customIDArgs(i)
+	if len(args) < 1 {
+		return "", nil, false
+	}
+	key = args[0]
+
+	pendingWishlists.Lock()
+	defer pendingWishlists.Unlock()
+	pending, ok = pendingWishlists.m[key]
+	if remove {
+		delete(pendingWishlists.m, key)
+	}
+	if !ok || pending.guildID != parseGuildID(i) || time.Since(pending.created) > importExpiry {
+		delete(pendingWishlists.m, key)
+		return "", nil, false
+	}
+
+	return key, pending, true
+}
+
+// parseGuildID parses the guild ID of an interaction, or 0 if it fails.
+func parseGuildID(i *discordgo.InteractionCreate) int64 {
+	guildID, _ := strconv.ParseInt(i.GuildID, 10, 64)
+	return guildID
+}
+
+// wishlistExpired replies to a component interaction of an expired preview.
+func wishlistExpired(s Session, i *discordgo.InteractionCreate) {
+	CompReply(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "Import Wishlist",
+				Description: "This preview has expired, please use /import_wishlist again",
+			},
+		},
+		Components: []discordgo.MessageComponent{},
+	})
+}
+
+func importWishlistCompSelectHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	key, pending, ok := takeWishlist(i, false)
+	if !ok {
+		wishlistExpired(s, i)
+		return
+	}
+
+	// Replace the selection of the current page
+	pendingWishlists.Lock()
+	for _, item := range pending.pageItems() {
+		delete(pending.selected, item.Appid)
+	}
+	for _, v := range i.MessageComponentData().Values {
+		if appid, err := strconv.Atoi(v); err == nil {
+			pending.selected[appid] = true
+		}
+	}
+	em, components := wishlistPreview(key, pending)
+	pendingWishlists.Unlock()
+
+	CompReply(s, i, &discordgo.InteractionResponseData{
+		Embeds:     []*discordgo.MessageEmbed{em},
+		Components: components,
+	})
+}
+
+func importWishlistCompPageHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	key, pending, ok := takeWishlist(i, false)
+	args := customIDArgs(i)
+	if !ok || len(args) != 2 {
+		wishlistExpired(s, i)
+		return
+	}
+	page, err := strconv.Atoi(args[1])
+	if err != nil {
+		wishlistExpired(s, i)
+		return
+	}
+
+	pendingWishlists.Lock()
+	pending.page = max(0, min(page, pending.pages()-1))
+	em, components := wishlistPreview(key, pending)
+	pendingWishlists.Unlock()
+
+	CompReply(s, i, &discordgo.InteractionResponseData{
+		Embeds:     []*discordgo.MessageEmbed{em},
+		Components: components,
+	})
+}
+
+func importWishlistCompAddHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	_, pending, ok := takeWishlist(i, true)
+	args := customIDArgs(i)
+	if !ok || len(args) != 2 {
+		wishlistExpired(s, i)
+		return
+	}
+	DeferCompReply(s, i)
+
+	all := args[1] == "all"
+	items := []steam.WishlistItem{}
+	for _, item := range pending.items {
+		if all || pending.selected[item.Appid] {
+			items = append(items, item)
+		}
+	}
+
+	progressEmbed := func(done, total int) *discordgo.MessageEmbed {
+		return &discordgo.MessageEmbed{
+			Title:       "Import Wishlist",
+			Description: fmt.Sprintf("Processed %d of %d apps...", done, total),
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Note: Adding may pause if Steam rate limits requests",
+			},
+		}
+	}
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{progressEmbed(0, len(items))},
+		Components: &[]discordgo.MessageComponent{},
+	})
+
+	// Add in the background as Steam has the items rather than as the
+	// wishlist does, updating the reply as items are processed
+	ctx = jobContext(ctx)
+	go func() {
+		progress := newProgressReporter(s, i, progressEmbed)
+
+		apps := []*steam.App{}
+		failed := []string{}
+		retry := map[int]bool{} // Appids that syncing should try adding again
+		for idx, item := range items {
+			app, err := steam.WaitForLimit(ctx, func() (*steam.App, error) {
+				return newTrackableApp(ctx, steam.AppItem(item.Appid))
+			})
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s (%d)", item.Name, item.Appid))
+				if err != errUntrackable {
+					retry[item.Appid] = true
+				}
+			} else {
+				apps = append(apps, app)
+			}
+			progress(idx+1, len(items))
+		}
+
+		succ, fail := store.AddApps(ctx, pending.guildID, apps)
+		for _, app := range fail {
+			failed = append(failed, fmt.Sprintf("%s (%d)", app.Name, app.Appid))
+			retry[app.Appid] = true
+		}
+
+		em := &discordgo.MessageEmbed{Title: "Import Wishlist"}
+		var syncedBefore, syncedAfter int64
+		if pending.sync != nil {
+			var err error
+			syncedBefore, syncedAfter, err = updateWishlistSync(ctx, pending, retry)
+			switch {
+			case err != nil:
+				em.Description = "Failed to update wishlist syncing, please try again"
+			case syncedAfter != 0:
+				em.Description = "Apps wishlisted later will be added automatically"
+			default:
+				em.Description = "Stopped syncing the wishlist"
+			}
+		}
+		added := make([]string, 0, len(succ))
+		for _, app := range succ {
+			added = append(added, fmt.Sprintf("%s (%s)", app.Name, app.Item()))
+		}
+		if len(added) > 0 {
+			em.Fields = append(em.Fields, listField("Successfully added", added))
+		}
+		if len(failed) > 0 {
+			em.Fields = append(em.Fields, listField("Failed to add", failed))
+			em.Footer = &discordgo.MessageEmbedFooter{
+				Text: "Note: Make sure apps are either priced or are yet to be released",
+			}
+		}
+		finishJob(s, i, em, nil, nil)
+
+		if len(added) > 0 {
+			recordAudit(ctx, s, i, "import_wishlist", "", strings.Join(added, ", "))
+		}
+		if syncedBefore != syncedAfter {
+			recordAudit(ctx, s, i, "import_wishlist",
+				describeWishlistSync(syncedBefore), describeWishlistSync(syncedAfter))
+		}
+	}()
+}
+
+func importWishlistCompCancelHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	if args := customIDArgs(i); len(args) >= 1 {
+		pendingWishlists.Lock()
+		delete(pendingWishlists.m, args[0])
+		pendingWishlists.Unlock()
+	}
+
+	CompReply(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "Import Wishlist",
+				Description: "Import cancelled",
+			},
+		},
+		Components: []discordgo.MessageComponent{},
+	})
+}
+
+// updateWishlistSync starts or stops syncing the wishlist of pending as
+// it was previewed. Apps of retry are left unseen so that syncing tries
+// adding them again. before and after are the SteamID64s of the wishlist
+// synced before and after, or 0 for none. If it fails, they're the same.
+func updateWishlistSync(ctx context.Context, pending *pendingWishlist,
+	retry map[int]bool) (before, after int64, err error) {
+	guild, err := store.Guild(ctx, pending.guildID)
+	if err != nil {
+		return 0, 0, err
+	}
+	before = guild.WishlistSteamID
+
+	if !*pending.sync {
+		if err := store.SetWishlistSync(ctx, pending.guildID, 0, nil); err != nil {
+			return before, before, err
+		}
+		return before, 0, nil
+	}
+
+	seen := make([]int, 0, len(pending.appids))
+	for _, appid := range pending.appids {
+		if !retry[appid] {
+			seen = append(seen, appid)
+		}
+	}
+	if err := store.SetWishlistSync(ctx, pending.guildID, pending.steamID, seen); err != nil {
+		return before, before, err
+	}
+	return before, pending.steamID, nil
+}
+
+// describeWishlistSync describes syncing the wishlist of steamID,
+// or syncing none if it's 0.
+func describeWishlistSync(steamID int64) string {
+	if steamID == 0 {
+		return "Wishlist not synced"
+	}
+	return fmt.Sprintf("Wishlist of %d synced", steamID)
+}
+
+// wishlistSyncPreview creates the embed and components asking to confirm
+// updating syncing of a pending wishlist with no apps to add. key is the
+// key of the pending wishlist in pendingWishlists.
+func wishlistSyncPreview(key string) (*discordgo.MessageEmbed,
+	[]discordgo.MessageComponent) {
+	em := &discordgo.MessageEmbed{
+		Title:       "Import Wishlist",
+		Description: "Every paid app on that wishlist is already being tracked",
+	}
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Confirm",
+					Style:    discordgo.SuccessButton,
+					CustomID: newCustomID(importWishlistCompAdd, key, "all"),
+				},
+				discordgo.Button{
+					Label:    "Cancel",
+					Style:    discordgo.DangerButton,
+					CustomID: newCustomID(importWishlistCompCancel, key),
+				},
+			},
+		},
+	}
+	return em, components
+}
+
+// wishlistPreview creates the embed and components showing the current
+// page of a pending wishlist. key is the key of pending in pendingWishlists.
+func wishlistPreview(key string, pending *pendingWishlist) (*discordgo.MessageEmbed,
+	[]discordgo.MessageComponent) {
+	items := pending.pageItems()
+
+	lines := make([]string, 0, len(items))
+	options := make([]discordgo.SelectMenuOption, 0, len(items))
+	for _, item := range items {
+		line := fmt.Sprintf("%s (%d)", item.Name, item.Appid)
+		if pending.selected[item.Appid] {
+			line = "**" + line + "**"
+		}
+		lines = append(lines, line)
+
+		options = append(options, discordgo.SelectMenuOption{
+			Label:       truncate(item.Name, 100),
+			Value:       strconv.Itoa(item.Appid),
+			Description: strconv.Itoa(item.Appid),
+			Default:     pending.selected[item.Appid],
+		})
+	}
+
+	em := &discordgo.MessageEmbed{
+		Title: "Import Wishlist",
+		Description: fmt.Sprintf("Found %d apps that aren't tracked yet. Select apps to add "+
+			"from each page, or add them all. %d selected",
+			len(pending.items), len(pending.selected)),
+		Fields: []*discordgo.MessageEmbedField{
+			listField(fmt.Sprintf("Page %d of %d", pending.page+1, pending.pages()), lines),
+		},
+	}
+
+	minValues := 0
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    newCustomID(importWishlistCompSelect, key),
+					Placeholder: "Select apps to add",
+					MinValues:   &minValues,
+					MaxValues:   len(options),
+					Options:     options,
+				},
+			},
+		},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Previous",
+					Style:    discordgo.SecondaryButton,
+					CustomID: newCustomID(importWishlistCompPage, key, strconv.Itoa(pending.page-1)),
+					Disabled: pending.page == 0,
+				},
+				discordgo.Button{
+					Label:    "Next",
+					Style:    discordgo.SecondaryButton,
+					CustomID: newCustomID(importWishlistCompPage, key, strconv.Itoa(pending.page+1)),
+					Disabled: pending.page >= pending.pages()-1,
+				},
+				discordgo.Button{
+					Label:    "Add selected",
+					Style:    discordgo.PrimaryButton,
+					CustomID: newCustomID(importWishlistCompAdd, key, "selected"),
+					Disabled: len(pending.selected) == 0,
+				},
+				discordgo.Button{
+					Label:    "Add all",
+					Style:    discordgo.SuccessButton,
+					CustomID: newCustomID(importWishlistCompAdd, key, "all"),
+				},
+				discordgo.Button{
+					Label:    "Cancel",
+					Style:    discordgo.DangerButton,
+					CustomID: newCustomID(importWishlistCompCancel, key),
+				},
+			},
+		},
+	}
+
+	return em, components
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeWishlists is a steam.WishlistFetcher that returns preset results.
+type fakeWishlists struct {
+	steamID    int64
+	resolveErr error
+	items      []steam.WishlistItem
+	err        error
+}
+
+func (f *fakeWishlists) ResolveProfile(context.Context, string) (int64, error) {
+	return f.steamID, f.resolveErr
+}
+
+func (f *fakeWishlists) Wishlist(context.Context, int64) ([]steam.WishlistItem, error) {
+	return f.items, f.err
+}
+
+type importWishlistShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+	store     *fakeStore
+	guildID   int64
+}
+
+func (s *importWishlistShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+	s.store = useFakeStore(s.T())
+	s.guildID, _ = strconv.ParseInt(testGuildID, 10, 64)
+
+	pendingWishlists.Lock()
+	pendingWishlists.m = map[string]*pendingWishlist{}
+	pendingWishlists.Unlock()
+}
+
+func TestImportWishlistShould(t *testing.T) {
+	suite.Run(t, new(importWishlistShould))
+}
+
+// addPending adds a pending wishlist of n items to the test guild under key.
+func (s *importWishlistShould) addPending(key string, n int) *pendingWishlist {
+	items := make([]steam.WishlistItem, 0, n)
+	for appid := 1; appid <= n; appid++ {
+		items = append(items, steam.WishlistItem{Appid: appid, Name: "App " + strconv.Itoa(appid)})
+	}
+	pending := &pendingWishlist{
+		guildID:  s.guildID,
+		items:    items,
+		selected: map[int]bool{},
+		created:  time.Now(),
+	}
+
+	pendingWishlists.Lock()
+	pendingWishlists.m[key] = pending
+	pendingWishlists.Unlock()
+
+	return pending
+}
+
+func (s *importWishlistShould) runHandler(wishlists steam.WishlistFetcher) {
+	importWishlistHandler(context.Background(), s.session,
+		newCommandInteraction("import_wishlist", stringOpt("profile", "profile")), wishlists)
+}
+
+// syncedWishlist is a wishlist of the canned apps, synced when added.
+var syncedWishlist = &fakeWishlists{
+	steamID: 76561197960287930,
+	items: []steam.WishlistItem{
+		{Appid: 400, Name: "Portal"},
+		{Appid: 440, Name: "Team Fortress 2", Free: true},
+		{Appid: 620, Name: "Portal 2"},
+	},
+}
+
+// previewSynced previews syncedWishlist with the sync option set to sync.
+// The preview is kept under the key "1".
+func (s *importWishlistShould) previewSynced(sync bool) {
+	importWishlistHandler(context.Background(), s.session,
+		newCommandInteraction("import_wishlist",
+			stringOpt("profile", "profile"), boolOpt("sync", sync)), syncedWishlist)
+}
+
+// finalEmbed waits for the background job adding a wishlist to finish
+// and gets the embed it finished with.
+func (s *importWishlistShould) finalEmbed() *discordgo.MessageEmbed {
+	var em *discordgo.MessageEmbed
+	s.Require().Eventually(func() bool {
+		em = s.responder.lastEditEmbed()
+		return em != nil && !strings.HasPrefix(em.Description, "Processed")
+	}, time.Second, time.Millisecond)
+	return em
+}
+
+func (s *importWishlistShould) TestPreviewWithoutSyncing() {
+	s.previewSynced(true)
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Contains(em.Description, "Found 2 apps")
+	s.Equal("Once confirmed, apps wishlisted later will be added automatically",
+		em.Footer.Text)
+	guild, err := s.store.Guild(context.Background(), s.guildID)
+	s.Require().NoError(err)
+	s.Zero(guild.WishlistSteamID)
+	s.Empty(s.store.auditLog())
+}
+
+func (s *importWishlistShould) TestAddAndSyncOnConfirm() {
+	useSteamServer(s.T(), steamtest.CannedApps...)
+	s.previewSynced(true)
+
+	importWishlistCompAddHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompAdd, "1", "all")))
+
+	em := s.finalEmbed()
+	s.Equal("Apps wishlisted later will be added automatically", em.Description)
+	s.Require().Len(em.Fields, 1)
+	s.Equal("Portal (400)\nPortal 2 (620)\n", em.Fields[0].Value)
+	s.NotNil(s.store.app(s.guildID, 400))
+	s.NotNil(s.store.app(s.guildID, 620))
+	guild, err := s.store.Guild(context.Background(), s.guildID)
+	s.Require().NoError(err)
+	s.Equal(syncedWishlist.steamID, guild.WishlistSteamID)
+	s.Equal([]int{400, 440, 620}, guild.WishlistSeen)
+	s.Require().Eventually(func() bool { return len(s.store.auditLog()) == 2 },
+		time.Second, time.Millisecond)
+	s.Equal("Portal (400), Portal 2 (620)", s.store.auditLog()[0].After)
+	s.Equal("Wishlist not synced", s.store.auditLog()[1].Before)
+	s.Equal("Wishlist of 76561197960287930 synced", s.store.auditLog()[1].After)
+}
+
+func (s *importWishlistShould) TestLeaveFailedAppsUnseen() {
+	useSteamServer(s.T(), steamtest.CannedApps[0])
+	s.previewSynced(true)
+
+	importWishlistCompAddHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompAdd, "1", "all")))
+
+	s.finalEmbed()
+	guild, err := s.store.Guild(context.Background(), s.guildID)
+	s.Require().NoError(err)
+	s.Equal([]int{400, 440}, guild.WishlistSeen)
+}
+
+func (s *importWishlistShould) TestNotSyncOnCancel() {
+	s.previewSynced(true)
+
+	importWishlistCompCancelHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompCancel, "1")))
+
+	guild, err := s.store.Guild(context.Background(), s.guildID)
+	s.Require().NoError(err)
+	s.Zero(guild.WishlistSteamID)
+}
+
+func (s *importWishlistShould) TestConfirmStopSyncingWithNothingToAdd() {
+	s.Require().NoError(s.store.SetWishlistSync(context.Background(), s.guildID,
+		syncedWishlist.steamID, []int{400, 440, 620}))
+	s.store.AddApps(context.Background(), s.guildID, []*steam.App{
+		{Appid: 400, Name: "Portal"},
+		{Appid: 620, Name: "Portal 2"},
+	})
+	s.previewSynced(false)
+	edit := s.responder.lastEdit()
+	s.Require().NotNil(edit)
+	s.Equal("Every paid app on that wishlist is already being tracked",
+		(*edit.Embeds)[0].Description)
+	s.Require().Len(*edit.Components, 1)
+
+	importWishlistCompAddHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompAdd, "1", "all")))
+
+	s.Equal("Stopped syncing the wishlist", s.finalEmbed().Description)
+	guild, err := s.store.Guild(context.Background(), s.guildID)
+	s.Require().NoError(err)
+	s.Zero(guild.WishlistSteamID)
+	s.Require().Eventually(func() bool { return len(s.store.auditLog()) == 1 },
+		time.Second, time.Millisecond)
+	s.Equal("Wishlist not synced", s.store.auditLog()[0].After)
+}
+
+func (s *importWishlistShould) TestReplyRateLimited() {
+	s.runHandler(&fakeWishlists{resolveErr: steam.ErrNetTryAgainLater})
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Contains(em.Description, "rate limiting")
+}
+
+func (s *importWishlistShould) TestReplyUnknownProfile() {
+	s.runHandler(&fakeWishlists{resolveErr: errors.New("not found")})
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Contains(em.Description, "Couldn't find that profile")
+}
+
+func (s *importWishlistShould) TestReplyPrivateWishlist() {
+	s.runHandler(&fakeWishlists{steamID: 1, err: steam.ErrPrivateWishlist})
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Contains(em.Description, "That wishlist is private")
+}
+
+func (s *importWishlistShould) TestSelectOnCurrentPage() {
+	pending := s.addPending("key", 30)
+
+	importWishlistCompSelectHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompSelect, "key"), "2", "3"))
+
+	s.Equal(map[int]bool{2: true, 3: true}, pending.selected)
+	resp := s.responder.lastResponse()
+	s.Require().NotNil(resp)
+	s.Equal(discordgo.InteractionResponseUpdateMessage, resp.Type)
+	s.Contains(resp.Data.Embeds[0].Description, "2 selected")
+}
+
+func (s *importWishlistShould) TestKeepSelectionOfOtherPages() {
+	pending := s.addPending("key", 30)
+	pending.selected[26] = true
+
+	importWishlistCompSelectHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompSelect, "key"), "1"))
+
+	s.Equal(map[int]bool{1: true, 26: true}, pending.selected)
+}
+
+func (s *importWishlistShould) TestPageWithinBounds() {
+	pending := s.addPending("key", 30)
+
+	importWishlistCompPageHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompPage, "key", "5")))
+
+	s.Equal(1, pending.page)
+	s.Equal("Page 2 of 2", s.responder.lastResponse().Data.Embeds[0].Fields[0].Name)
+}
+
+func (s *importWishlistShould) TestExpireUnknownPreview() {
+	importWishlistCompPageHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompPage, "unknown", "1")))
+
+	resp := s.responder.lastResponse()
+	s.Require().NotNil(resp)
+	s.Contains(resp.Data.Embeds[0].Description, "This preview has expired")
+}
+
+func (s *importWishlistShould) TestExpirePreviewOfOtherGuild() {
+	s.addPending("key", 1).guildID = s.guildID + 1
+
+	importWishlistCompSelectHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompSelect, "key"), "1"))
+
+	s.Contains(s.responder.lastResponse().Data.Embeds[0].Description, "This preview has expired")
+}
+
+func (s *importWishlistShould) TestCancelRemovesPreview() {
+	s.addPending("key", 1)
+
+	importWishlistCompCancelHandler(context.Background(), s.session,
+		newComponentInteraction(newCustomID(importWishlistCompCancel, "key")))
+
+	s.NotContains(pendingWishlists.m, "key")
+	s.Equal("Import cancelled", s.responder.lastResponse().Data.Embeds[0].Description)
+}
@@ -1,12 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
 )
 
 // NewListApps creates /list_apps.
@@ -18,7 +20,7 @@ func NewListApps() Cmd {
 	}
 }
 
-func listAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func listAppsHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
 	DeferMsgReply(s, i)
 
 	// Parse guildID
@@ -29,7 +31,7 @@ func listAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 
 	// Get apps and create embed reply
-	records, err := db.AppsOf(guildID)
+	records, err := store.AppsOf(ctx, guildID)
 	var description string
 	var footer *discordgo.MessageEmbedFooter
 	switch {
@@ -40,15 +42,7 @@ func listAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		description = "List is empty! Try adding some apps"
 
 	default:
-		sb := strings.Builder{}
-		for _, rec := range records {
-			sb.WriteString(fmt.Sprintf("%s (%d)", rec.AppName, rec.Appid))
-			if rec.AppSaleThreshold > 0 {
-				sb.WriteString(fmt.Sprintf(" (%d%%)", rec.AppSaleThreshold))
-			}
-			sb.WriteString("\n")
-		}
-		description = sb.String()
+		description = describeApps(records)
 
 		footer = &discordgo.MessageEmbedFooter{
 			Text: fmt.Sprintf("General Discount Threshold: %d%%", records[0].SaleThreshold),
@@ -65,3 +59,42 @@ func listAppsHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		},
 	})
 }
+
+// describeApps lists records one per line, with the DLC of a listed game
+// grouped under it.
+func describeApps(records []db.GuildInfo) string {
+	games := map[int]bool{}
+	for _, rec := range records {
+		if rec.Item().Kind == steam.KindApp {
+			games[rec.Appid] = true
+		}
+	}
+	dlcOf := map[int][]db.GuildInfo{}
+	for _, rec := range records {
+		if games[rec.DLCOf] {
+			dlcOf[rec.DLCOf] = append(dlcOf[rec.DLCOf], rec)
+		}
+	}
+
+	sb := strings.Builder{}
+	describe := func(prefix string, rec db.GuildInfo) {
+		sb.WriteString(fmt.Sprintf("%s%s (%s)", prefix, rec.AppName, rec.Item()))
+		if rec.AppSaleThreshold > 0 {
+			sb.WriteString(fmt.Sprintf(" (%d%%)", rec.AppSaleThreshold))
+		}
+		sb.WriteString("\n")
+	}
+	for _, rec := range records {
+		if games[rec.DLCOf] {
+			continue
+		}
+		describe("", rec)
+		if rec.Item().Kind != steam.KindApp {
+			continue
+		}
+		for _, dlc := range dlcOf[rec.Appid] {
+			describe("- ", dlc)
+		}
+	}
+	return sb.String()
+}
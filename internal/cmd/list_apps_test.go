@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/stretchr/testify/suite"
+)
+
+type listAppsShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+	store     *fakeStore
+}
+
+func (s *listAppsShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+	s.store = useFakeStore(s.T())
+}
+
+func TestListAppsShould(t *testing.T) {
+	suite.Run(t, new(listAppsShould))
+}
+
+func (s *listAppsShould) TestListTrackedApps() {
+	threshold := 50
+	s.store.AddApps(context.Background(), 100, []*steam.App{
+		{Appid: 400, Name: "Portal"},
+		{Appid: 620, Name: "Portal 2", SaleThreshold: &threshold},
+	})
+
+	listAppsHandler(context.Background(), s.session, newCommandInteraction("list_apps"))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Equal("Portal (400)\nPortal 2 (620) (50%)\n", em.Description)
+	s.Equal("General Discount Threshold: 1%", em.Footer.Text)
+}
+
+func (s *listAppsShould) TestReplyEmptyList() {
+	listAppsHandler(context.Background(), s.session, newCommandInteraction("list_apps"))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Equal("List is empty! Try adding some apps", em.Description)
+}
+
+func (s *listAppsShould) TestGroupDLCUnderItsGame() {
+	records := []db.GuildInfo{
+		{Appid: 323180, AppName: "Portal 2 Soundtrack", DLCOf: 620},
+		{Appid: 400, AppName: "Portal"},
+		{Appid: 620, AppName: "Portal 2", TrackDLC: true},
+		{Appid: 469, Kind: steam.KindSub, AppName: "The Orange Box"},
+		{Appid: 323181, AppName: "Portal 2 Art Pack", DLCOf: 620, AppSaleThreshold: 50},
+	}
+
+	s.Equal("Portal (400)\n"+
+		"Portal 2 (620)\n"+
+		"- Portal 2 Soundtrack (323180)\n"+
+		"- Portal 2 Art Pack (323181) (50%)\n"+
+		"The Orange Box (sub/469)\n",
+		describeApps(records))
+}
+
+func (s *listAppsShould) TestListDLCOfUntrackedGamesOnTheirOwn() {
+	records := []db.GuildInfo{
+		{Appid: 400, AppName: "Portal"},
+		{Appid: 620, Kind: steam.KindSub, AppName: "Not Portal 2"},
+		{Appid: 323180, AppName: "Portal 2 Soundtrack", DLCOf: 620},
+	}
+
+	s.Equal("Portal (400)\n"+
+		"Not Portal 2 (sub/620)\n"+
+		"Portal 2 Soundtrack (323180)\n",
+		describeApps(records))
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// hasManageServer reports whether the member behind an interaction has
+// the Manage Server or Administrator permission.
+func hasManageServer(i *discordgo.InteractionCreate) bool {
+	if i.Member == nil {
+		return false
+	}
+
+	return i.Member.Permissions&
+		(discordgo.PermissionManageServer|discordgo.PermissionAdministrator) != 0
+}
+
+// IsManager reports whether the member behind an interaction is a server
+// manager. Server managers have the Manage Server permission or the guild's
+// manager role set through /set_manager_role.
+func IsManager(ctx context.Context, i *discordgo.InteractionCreate) bool {
+	if hasManageServer(i) {
+		return true
+	}
+	if i.Member == nil {
+		return false
+	}
+
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		return false
+	}
+	guild, err := store.Guild(ctx, guildID)
+	if err != nil || guild.ManagerRoleID == 0 {
+		return false
+	}
+
+	return slices.Contains(i.Member.Roles, strconv.FormatInt(guild.ManagerRoleID, 10))
+}
+
+// RequireManager creates a handler that runs handle only if the interaction
+// is from a server manager. Otherwise, it privately replies with why not.
+func RequireManager(handle Handler) Handler {
+	return func(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+		if !IsManager(ctx, i) {
+			MsgReply(s, i, &discordgo.InteractionResponseData{
+				Content: "Only server managers can do this. Server managers have the " +
+					"Manage Server permission or the role set with /set_manager_role",
+				Flags: discordgo.MessageFlagsEphemeral,
+			})
+			return
+		}
+
+		handle(ctx, s, i)
+	}
+}
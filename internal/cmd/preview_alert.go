@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+)
+
+// AlertPreviewer renders the alert the guild matching guildID would receive
+// for the app matching appid, without sending it or changing any sale state.
+type AlertPreviewer func(ctx context.Context, guildID int64,
+	appid int) (*discordgo.MessageEmbed, error)
+
+// NewPreviewAlert creates /preview_alert <appid>. preview is used to render
+// the alert. It's meant for development and is only registered to the
+// test guild.
+func NewPreviewAlert(preview AlertPreviewer) Cmd {
+	minAppid := float64(1)
+	return Cmd{
+		Name:        "preview_alert",
+		Description: "Show the alert this server would receive for an app",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "appid",
+				Description: "The app to preview an alert for",
+				MinValue:    &minAppid,
+				Required:    true,
+			},
+		},
+		Handle: func(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+			previewAlertHandler(ctx, s, i, preview)
+		},
+	}
+}
+
+func previewAlertHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate,
+	preview AlertPreviewer) {
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	appid := int(i.ApplicationCommandData().Options[0].IntValue())
+
+	em, err := preview(ctx, guildID, appid)
+	content := ""
+	switch {
+	case err == steam.ErrNetTryAgainLater:
+		content = "Steam is rate limiting requests, please try again in a few minutes"
+	case err != nil:
+		content = fmt.Sprintf("Failed to preview an alert for appid %d", appid)
+	default:
+		content = "Preview only, this alert wasn't sent and no sale state was changed"
+	}
+
+	edit := &discordgo.WebhookEdit{Content: &content}
+	if em != nil {
+		edit.Embeds = &[]*discordgo.MessageEmbed{em}
+	}
+	EditReply(s, i, edit)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/stretchr/testify/suite"
+)
+
+type previewAlertShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+}
+
+func (s *previewAlertShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+}
+
+func TestPreviewAlertShould(t *testing.T) {
+	suite.Run(t, new(previewAlertShould))
+}
+
+func (s *previewAlertShould) TestReplyWithPreviewOfGuildApp() {
+	var gotGuildID int64
+	var gotAppid int
+	em := &discordgo.MessageEmbed{Title: "Portal is on sale for 90% off!"}
+	preview := func(_ context.Context, guildID int64, appid int) (*discordgo.MessageEmbed, error) {
+		gotGuildID, gotAppid = guildID, appid
+		return em, nil
+	}
+
+	previewAlertHandler(context.Background(), s.session,
+		newCommandInteraction("preview_alert", intOpt("appid", 400)), preview)
+
+	s.Equal(int64(100), gotGuildID)
+	s.Equal(400, gotAppid)
+	s.Same(em, s.responder.lastEditEmbed())
+}
+
+func (s *previewAlertShould) TestReportRateLimit() {
+	preview := func(context.Context, int64, int) (*discordgo.MessageEmbed, error) {
+		return nil, steam.ErrNetTryAgainLater
+	}
+
+	previewAlertHandler(context.Background(), s.session,
+		newCommandInteraction("preview_alert", intOpt("appid", 400)), preview)
+
+	s.Contains(*s.responder.lastEdit().Content, "rate limiting")
+	s.Nil(s.responder.lastEdit().Embeds)
+}
+
+func (s *previewAlertShould) TestReportFailure() {
+	preview := func(context.Context, int64, int) (*discordgo.MessageEmbed, error) {
+		return nil, errors.New("invalid appid")
+	}
+
+	previewAlertHandler(context.Background(), s.session,
+		newCommandInteraction("preview_alert", intOpt("appid", 1)), preview)
+
+	s.Equal("Failed to preview an alert for appid 1", *s.responder.lastEdit().Content)
+}
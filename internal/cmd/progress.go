@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// progressInterval is the least time between progress updates
+// so that edits aren't rate limited by Discord.
+const progressInterval = 3 * time.Second
+
+// newProgressReporter creates a fn for background jobs to report their
+// progress with. Each call edits the reply to i with the embed made by
+// progressEmbed, at most once every progressInterval. The final call,
+// when done == total, is never skipped.
+func newProgressReporter(s Responder, i *discordgo.InteractionCreate,
+	progressEmbed func(done, total int) *discordgo.MessageEmbed) func(done, total int) {
+	var lastUpdate time.Time
+
+	return func(done, total int) {
+		if time.Since(lastUpdate) < progressInterval && done < total {
+			return
+		}
+		lastUpdate = time.Now()
+
+		EditReply(s, i, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{progressEmbed(done, total)},
+		})
+	}
+}
+
+// finishJob edits the reply to i with the final result of a background job.
+// The interaction token may have expired if the job was long delayed, e.g.,
+// by rate limiting, so the result is sent as a regular message instead
+// if the edit fails. components are only set if not nil.
+func finishJob(s Responder, i *discordgo.InteractionCreate, em *discordgo.MessageEmbed,
+	files []*discordgo.File, components []discordgo.MessageComponent) {
+	edit := &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{em},
+		Files:  files,
+	}
+	if components != nil {
+		edit.Components = &components
+	}
+	_, err := s.InteractionResponseEdit(i.Interaction, edit)
+	if err != nil {
+		s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+			Embeds:     []*discordgo.MessageEmbed{em},
+			Files:      files,
+			Components: components,
+		})
+	}
+}
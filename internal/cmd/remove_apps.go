@@ -1,15 +1,16 @@
 package cmd
 
 import (
-	"fmt"
+	"context"
 	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
 )
 
 // NewRemoveApps creates /remove_apps <appid>,<appid>,...
+// Packages and bundles are given as sub/<id> and bundle/<id>.
 func NewRemoveApps() Cmd {
 	return Cmd{
 		Name:        "remove_apps",
@@ -19,21 +20,22 @@ func NewRemoveApps() Cmd {
 				Type: discordgo.ApplicationCommandOptionString,
 				Name: "appids",
 				Description: "Comma separated appids to remove from tracker. " +
-					"E.g., 400,440,1868140",
+					"E.g., 400,440,sub/469,bundle/232",
 				Required:  true,
 				MaxLength: 150,
 			},
 		},
-		Handle: removeAppshandler,
+		Handle:      removeAppshandler,
+		ManagerOnly: true,
 	}
 }
 
-func removeAppshandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func removeAppshandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
 	DeferMsgReply(s, i)
 
 	// Parse appids
 	strs := strings.Split(i.ApplicationCommandData().Options[0].StringValue(), ",")
-	succ, invalidAppids := strsToAppids(strs)
+	succ, invalidAppids := strsToItems(strs)
 
 	// Parse guildID
 	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
@@ -43,13 +45,13 @@ func removeAppshandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 
 	// Remove apps and create embed reply
-	succ, fail := db.RemoveApps(guildID, succ)
+	succ, fail, trashID := store.RemoveApps(ctx, guildID, succ)
 	em := &discordgo.MessageEmbed{Title: "Remove Apps"}
 
 	// Add successfully deleted apps field
 	sb := strings.Builder{}
-	for _, appid := range succ {
-		sb.WriteString(fmt.Sprintf("%d\n", appid))
+	for _, item := range succ {
+		sb.WriteString(item.String() + "\n")
 	}
 	if succStr := sb.String(); succStr != "" {
 		em.Fields = append(em.Fields, &discordgo.MessageEmbedField{
@@ -63,8 +65,8 @@ func removeAppshandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	for _, appid := range invalidAppids {
 		sb.WriteString(appid + "\n")
 	}
-	for _, appid := range fail {
-		sb.WriteString(fmt.Sprintf("%d\n", appid))
+	for _, item := range fail {
+		sb.WriteString(item.String() + "\n")
 	}
 	if failStr := sb.String(); failStr != "" {
 		em.Fields = append(em.Fields, &discordgo.MessageEmbedField{
@@ -73,23 +75,37 @@ func removeAppshandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		})
 	}
 
-	EditReply(s, i, &discordgo.WebhookEdit{
+	edit := &discordgo.WebhookEdit{
 		Embeds: &[]*discordgo.MessageEmbed{em},
-	})
+	}
+	if !trashID.IsZero() {
+		edit.Components = &[]discordgo.MessageComponent{undoRow(trashID)}
+	}
+
+	EditReply(s, i, edit)
+
+	if len(succ) > 0 {
+		removed := make([]string, 0, len(succ))
+		for _, item := range succ {
+			removed = append(removed, item.String())
+		}
+		recordAudit(ctx, s, i, "remove_apps", strings.Join(removed, ", "), "")
+	}
 }
 
-func strsToAppids(ss []string) (succ []int, fail []string) {
+// strsToItems parses ss as items of the store, e.g., "400" or "sub/469".
+// See steam.ParseItem().
+func strsToItems(ss []string) (succ []steam.Item, fail []string) {
 	for _, s := range ss {
 		s = strings.TrimSpace(s)
 
-		// Check convertible to int
-		appid, err := strconv.Atoi(s)
-		if err != nil || appid <= 0 {
+		item, err := steam.ParseItem(s)
+		if err != nil {
 			fail = append(fail, s)
 			continue
 		}
 
-		succ = append(succ, appid)
+		succ = append(succ, item)
 	}
 
 	return succ, fail
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/stretchr/testify/suite"
+)
+
+type removeAppsShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+	store     *fakeStore
+}
+
+func (s *removeAppsShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+	s.store = useFakeStore(s.T())
+	threshold := 50
+	s.store.AddApps(context.Background(), 100, []*steam.App{
+		{Appid: 400, Name: "Portal"},
+		{Appid: 620, Name: "Portal 2", SaleThreshold: &threshold},
+	})
+}
+
+func TestRemoveAppsShould(t *testing.T) {
+	suite.Run(t, new(removeAppsShould))
+}
+
+func (s *removeAppsShould) TestRemoveTrackedApps() {
+	removeAppshandler(context.Background(), s.session,
+		newCommandInteraction("remove_apps", stringOpt("appids", "620, 730")))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Require().Len(em.Fields, 2)
+	s.Equal("620\n", em.Fields[0].Value)
+	s.Equal("730\n", em.Fields[1].Value)
+	s.Nil(s.store.app(100, 620))
+	s.NotNil(s.store.app(100, 400))
+	s.Require().Len(s.store.auditLog(), 1)
+	s.Equal("620", s.store.auditLog()[0].Before)
+}
+
+func (s *removeAppsShould) TestRestoreRemovedAppsOnUndo() {
+	removeAppshandler(context.Background(), s.session,
+		newCommandInteraction("remove_apps", stringOpt("appids", "620")))
+	edit := s.responder.lastEdit()
+	s.Require().NotNil(edit)
+	s.Require().NotNil(edit.Components)
+	undo := (*edit.Components)[0].(discordgo.ActionsRow).Components[0].(discordgo.Button)
+
+	restoreAppsCompUndoHandler(context.Background(), s.session,
+		newComponentInteraction(undo.CustomID))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Require().Len(em.Fields, 1)
+	s.Equal("Portal 2 (620) (50%)\n", em.Fields[0].Value)
+	app := s.store.app(100, 620)
+	s.Require().NotNil(app)
+	s.Equal(50, app.AppSaleThreshold)
+	s.Require().Len(s.store.auditLog(), 2)
+	s.Equal("Portal 2 (620)", s.store.auditLog()[1].After)
+}
+
+func (s *removeAppsShould) TestRestoreNothingTwice() {
+	removeAppshandler(context.Background(), s.session,
+		newCommandInteraction("remove_apps", stringOpt("appids", "620")))
+	restoreAppsHandler(context.Background(), s.session, newCommandInteraction("restore_apps"))
+
+	restoreAppsHandler(context.Background(), s.session, newCommandInteraction("restore_apps"))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Equal("Nothing to restore. Removed apps can only be restored for 7 days", em.Description)
+	s.NotNil(s.store.app(100, 620))
+}
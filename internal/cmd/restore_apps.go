@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var restoreAppsCompUndo = "restoreAppsCompUndo"
+
+// NewRestoreApps creates /restore_apps.
+func NewRestoreApps() Cmd {
+	return Cmd{
+		Name:        "restore_apps",
+		Description: "Restore the apps most recently removed or cleared in the last 7 days",
+		Handle:      restoreAppsHandler,
+		CompHandlers: []ComponentHandler{
+			{
+				Name:   restoreAppsCompUndo,
+				Handle: restoreAppsCompUndoHandler,
+			},
+		},
+		ManagerOnly: true,
+	}
+}
+
+// undoRow creates a row with a button that restores the apps
+// snapshotted in the trash matching trashID.
+func undoRow(trashID primitive.ObjectID) discordgo.ActionsRow {
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Undo",
+				Style:    discordgo.SecondaryButton,
+				CustomID: newCustomID(restoreAppsCompUndo, trashID.Hex()),
+			},
+		},
+	}
+}
+
+func restoreAppsHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Restore latest snapshot and create embed reply
+	trash, err := store.LatestTrash(ctx, guildID)
+	var apps []db.TrashedApp
+	if err == nil {
+		apps, err = store.RestoreApps(ctx, guildID, trash.ID)
+	}
+
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{restoreAppsEmbed(apps, err)},
+	})
+
+	if err == nil {
+		recordRestoreAudit(ctx, s, i, apps)
+	}
+}
+
+func restoreAppsCompUndoHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	DeferCompReply(s, i)
+
+	// Parse trashID
+	args := customIDArgs(i)
+	if len(args) != 1 {
+		EditReplyUnexpected(s, i)
+		return
+	}
+	trashID, err := primitive.ObjectIDFromHex(args[0])
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Restore snapshot and replace the original reply
+	apps, err := store.RestoreApps(ctx, guildID, trashID)
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{restoreAppsEmbed(apps, err)},
+		Components: &[]discordgo.MessageComponent{},
+	})
+
+	if err == nil {
+		recordRestoreAudit(ctx, s, i, apps)
+	}
+}
+
+// recordRestoreAudit adds the restored apps to the audit log.
+func recordRestoreAudit(ctx context.Context, s Session, i *discordgo.InteractionCreate,
+	apps []db.TrashedApp) {
+	if len(apps) == 0 {
+		return
+	}
+
+	restored := make([]string, 0, len(apps))
+	for _, app := range apps {
+		restored = append(restored, fmt.Sprintf("%s (%s)", app.AppName, app.Item()))
+	}
+	recordAudit(ctx, s, i, "restore_apps", "", strings.Join(restored, ", "))
+}
+
+func restoreAppsEmbed(apps []db.TrashedApp, err error) *discordgo.MessageEmbed {
+	em := &discordgo.MessageEmbed{Title: "Restore Apps"}
+
+	switch {
+	case err == db.ErrNothingToRestore:
+		em.Description = "Nothing to restore. Removed apps can only be restored for 7 days"
+
+	case err != nil:
+		em.Description = "Failed to restore apps, please try again"
+
+	default:
+		sb := strings.Builder{}
+		for _, app := range apps {
+			sb.WriteString(fmt.Sprintf("%s (%s)", app.AppName, app.Item()))
+			if app.SaleThreshold > 0 {
+				sb.WriteString(fmt.Sprintf(" (%d%%)", app.SaleThreshold))
+			}
+			sb.WriteString("\n")
+		}
+		em.Fields = append(em.Fields, &discordgo.MessageEmbedField{
+			Name:  "Successfully restored",
+			Value: sb.String(),
+		})
+	}
+
+	return em
+}
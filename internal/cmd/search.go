@@ -1,11 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
 	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
 )
 
@@ -33,15 +33,16 @@ func NewSearch() Cmd {
 				Handle: searchCompConfirmHandler,
 			},
 		},
+		ManagerOnly: true,
 	}
 }
 
-func searchHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func searchHandler(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
 	DeferMsgReply(s, i)
 
 	// Search for apps matching query
 	query := i.ApplicationCommandData().Options[0].StringValue()
-	res, err := steam.Search(query)
+	res, err := steam.Search(ctx, query)
 	if err != nil {
 		EditReply(s, i, &discordgo.WebhookEdit{
 			Embeds: &[]*discordgo.MessageEmbed{
@@ -92,7 +93,8 @@ func searchHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	})
 }
 
-func searchCompConfirmHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func searchCompConfirmHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
 	choice := i.MessageComponentData().Values[0]
 
 	// Check if cancel
@@ -122,7 +124,7 @@ func searchCompConfirmHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 	}
 
 	// Parse app
-	succ, _ := strsToApps([]string{choice})
+	succ, _ := strsToApps(ctx, []string{choice})
 	if len(succ) != 1 {
 		EditReply(s, i, &reply)
 		return
@@ -136,7 +138,7 @@ func searchCompConfirmHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 	}
 
 	// Add app
-	succ, _ = db.AddApps(guildID, succ)
+	succ, _ = store.AddApps(ctx, guildID, succ)
 	if len(succ) != 1 {
 		EditReply(s, i, &reply)
 		return
@@ -144,4 +146,5 @@ func searchCompConfirmHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 
 	(*reply.Embeds)[0].Description = "Successfully added app"
 	EditReply(s, i, &reply)
+	recordAudit(ctx, s, i, "search", "", fmt.Sprintf("%s (%s)", succ[0].Name, succ[0].Item()))
 }
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
+	"github.com/stretchr/testify/suite"
+)
+
+type searchShould struct {
+	suite.Suite
+	session   Session
+	responder *recordingResponder
+}
+
+func (s *searchShould) SetupTest() {
+	s.session, s.responder = newTestSession()
+}
+
+func TestSearchShould(t *testing.T) {
+	suite.Run(t, new(searchShould))
+}
+
+func (s *searchShould) TestReplyFailureWhenSearchFails() {
+	// A cancelled search fails without reaching Steam
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	searchHandler(ctx, s.session, newCommandInteraction("search", stringOpt("query", "query")))
+
+	s.Equal([]discordgo.InteractionResponseType{
+		discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}, s.responder.responseTypes())
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Equal("Failed to get search results, please try again later", em.Description)
+}
+
+func (s *searchShould) TestCancelByUpdatingMessage() {
+	searchCompConfirmHandler(context.Background(), s.session,
+		newComponentInteraction(searchCompConfirm, searchCompCancelStr))
+
+	resp := s.responder.lastResponse()
+	s.Require().NotNil(resp)
+	s.Equal(discordgo.InteractionResponseUpdateMessage, resp.Type)
+	s.Equal("Cancelled adding app", resp.Data.Embeds[0].Description)
+	s.Empty(resp.Data.Components)
+	s.Empty(s.responder.edits)
+}
+
+func (s *searchShould) TestReplyErrorOnInvalidChoice() {
+	searchCompConfirmHandler(context.Background(), s.session,
+		newComponentInteraction(searchCompConfirm, "abc"))
+
+	s.Equal([]discordgo.InteractionResponseType{
+		discordgo.InteractionResponseDeferredMessageUpdate,
+	}, s.responder.responseTypes())
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Equal("Error adding app, please try again", em.Description)
+}
+
+func (s *searchShould) TestAddChosenApp() {
+	store := useFakeStore(s.T())
+	useSteamServer(s.T(), steamtest.CannedApps...)
+
+	searchCompConfirmHandler(context.Background(), s.session,
+		newComponentInteraction(searchCompConfirm, "400"))
+
+	em := s.responder.lastEditEmbed()
+	s.Require().NotNil(em)
+	s.Equal("Successfully added app", em.Description)
+	app := store.app(100, 400)
+	s.Require().NotNil(app)
+	s.Equal("Portal", app.AppName)
+	s.Require().Len(store.auditLog(), 1)
+	s.Equal("Portal (400)", store.auditLog()[0].After)
+}
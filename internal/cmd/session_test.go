@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
+)
+
+// The IDs of the guild, channel, and bot user test interactions happen in.
+const (
+	testGuildID   = "100"
+	testChannelID = "200"
+	testBotID     = "300"
+)
+
+// recordingResponder is a Responder that records everything sent through
+// it instead of sending it to Discord.
+type recordingResponder struct {
+	mu        sync.Mutex
+	responses []*discordgo.InteractionResponse
+	edits     []*discordgo.WebhookEdit
+	messages  []sentMessage
+}
+
+// sentMessage is a message sent to a channel through a recordingResponder.
+type sentMessage struct {
+	channelID string
+	data      *discordgo.MessageSend
+}
+
+func (r *recordingResponder) InteractionRespond(_ *discordgo.Interaction,
+	resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, resp)
+	return nil
+}
+
+func (r *recordingResponder) InteractionResponseEdit(_ *discordgo.Interaction,
+	newresp *discordgo.WebhookEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.edits = append(r.edits, newresp)
+	return &discordgo.Message{}, nil
+}
+
+func (r *recordingResponder) ChannelMessageSendEmbed(channelID string,
+	embed *discordgo.MessageEmbed, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return r.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{embed},
+	})
+}
+
+func (r *recordingResponder) ChannelMessageSendComplex(channelID string,
+	data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, sentMessage{channelID: channelID, data: data})
+	return &discordgo.Message{}, nil
+}
+
+// responseTypes gets the type of each response, in order.
+func (r *recordingResponder) responseTypes() []discordgo.InteractionResponseType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]discordgo.InteractionResponseType, 0, len(r.responses))
+	for _, resp := range r.responses {
+		types = append(types, resp.Type)
+	}
+	return types
+}
+
+// lastResponse gets the last response, or nil if there were none.
+func (r *recordingResponder) lastResponse() *discordgo.InteractionResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.responses) == 0 {
+		return nil
+	}
+	return r.responses[len(r.responses)-1]
+}
+
+// lastEdit gets the last edit, or nil if there were none.
+func (r *recordingResponder) lastEdit() *discordgo.WebhookEdit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.edits) == 0 {
+		return nil
+	}
+	return r.edits[len(r.edits)-1]
+}
+
+// lastEditEmbed gets the first embed of the last edit, or nil if there
+// wasn't one.
+func (r *recordingResponder) lastEditEmbed() *discordgo.MessageEmbed {
+	edit := r.lastEdit()
+	if edit == nil || edit.Embeds == nil || len(*edit.Embeds) == 0 {
+		return nil
+	}
+	return (*edit.Embeds)[0]
+}
+
+// newTestSession creates a Session whose replies are recorded by the
+// returned responder. The state only knows the bot user.
+func newTestSession() (Session, *recordingResponder) {
+	state := discordgo.NewState()
+	state.User = &discordgo.User{ID: testBotID}
+
+	r := &recordingResponder{}
+	return Session{Responder: r, State: state}, r
+}
+
+// newCommandInteraction creates an interaction invoking the command
+// matching name with opts in the test guild.
+func newCommandInteraction(name string,
+	opts ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "1",
+			Type:      discordgo.InteractionApplicationCommand,
+			GuildID:   testGuildID,
+			ChannelID: testChannelID,
+			Member:    &discordgo.Member{User: &discordgo.User{ID: "400"}},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name:    name,
+				Options: opts,
+			},
+		},
+	}
+}
+
+// newComponentInteraction creates an interaction with the component
+// matching customID in the test guild. values are the selected values
+// of a select menu.
+func newComponentInteraction(customID string, values ...string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        "2",
+			Type:      discordgo.InteractionMessageComponent,
+			GuildID:   testGuildID,
+			ChannelID: testChannelID,
+			Member:    &discordgo.Member{User: &discordgo.User{ID: "400"}},
+			Data: discordgo.MessageComponentInteractionData{
+				CustomID: customID,
+				Values:   values,
+			},
+		},
+	}
+}
+
+func stringOpt(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionString,
+		Value: value,
+	}
+}
+
+func intOpt(name string, value int) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionInteger,
+		Value: float64(value),
+	}
+}
+
+func boolOpt(name string, value bool) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionBoolean,
+		Value: value,
+	}
+}
+
+func channelOpt(name, channelID string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionChannel,
+		Value: channelID,
+	}
+}
+
+// useSteamServer has Steam requests go to a fake Steam API serving apps
+// until t ends.
+func useSteamServer(t *testing.T, apps ...steamtest.App) *steamtest.Server {
+	server := steamtest.NewServer(apps...)
+	storeURL, communityURL := steam.StoreURL, steam.CommunityURL
+	steam.StoreURL, steam.CommunityURL = server.URL, server.URL
+	t.Cleanup(func() {
+		steam.StoreURL, steam.CommunityURL = storeURL, communityURL
+		server.Close()
+	})
+	return server
+}
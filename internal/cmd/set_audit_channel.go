@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// NewSetAuditChannel creates /set_audit_channel <channel>.
+func NewSetAuditChannel() Cmd {
+	return Cmd{
+		Name:        "set_audit_channel",
+		Description: "Set the channel where configuration changes are logged",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+				Name:         "channel",
+				Description:  "The channel to log to. Leave empty to stop logging to a channel",
+			},
+		},
+		Handle:      setAuditChannelHandler,
+		ManagerOnly: true,
+	}
+}
+
+func setAuditChannelHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	reply := func(description string) {
+		EditReply(s, i, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{
+				{
+					Title:       "Set Audit Channel",
+					Description: description,
+				},
+			},
+		})
+	}
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Parse channelID
+	var channelID int64
+	var channel *discordgo.Channel
+	if len(i.ApplicationCommandData().Options) > 0 {
+		channel = i.ApplicationCommandData().Options[0].ChannelValue(nil)
+
+		// Check bot is able to message in that channel
+		if !CanSendAlerts(s.State, channel.ID) {
+			reply("Failed to set the audit channel, missing view channel, send message, " +
+				"or embed links permissions for that channel")
+			return
+		}
+
+		channelID, err = strconv.ParseInt(channel.ID, 10, 64)
+		if err != nil {
+			EditReplyUnexpected(s, i)
+			return
+		}
+	}
+
+	// Get the current channel for the audit log
+	before := ""
+	if guild, err := store.Guild(ctx, guildID); err == nil && guild.AuditChannelID != 0 {
+		before = fmt.Sprintf("<#%d>", guild.AuditChannelID)
+	}
+
+	// Set channel and create embed reply
+	after := ""
+	switch err := store.SetAuditChannel(ctx, guildID, channelID); {
+	case err != nil:
+		reply("Failed to set the audit channel, please try again")
+		return
+	case channel == nil:
+		reply("Successfully unset the audit channel. Changes are still listed in /audit_log")
+	default:
+		after = channel.Mention()
+		reply("Configuration changes will also be logged to " + channel.Mention())
+	}
+
+	recordAudit(ctx, s, i, "set_audit_channel", before, after)
+}
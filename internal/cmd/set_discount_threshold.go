@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
 )
 
 // NewSetDiscountThreshold creates /set_discount_threshold <threshold>.
@@ -30,22 +32,24 @@ func NewSetDiscountThreshold() Cmd {
 				MaxLength:   150,
 			},
 		},
-		Handle: setDiscountThresholdHandler,
+		Handle:      setDiscountThresholdHandler,
+		ManagerOnly: true,
 	}
 }
 
-func setDiscountThresholdHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func setDiscountThresholdHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
 	DeferMsgReply(s, i)
 
 	// Parse discount threshold
 	threshold := i.ApplicationCommandData().Options[0].IntValue()
 
 	// Parse appids
-	appids := []int{}
+	appids := []steam.Item{}
 	invalidAppids := []string{}
 	if len(i.ApplicationCommandData().Options) > 1 {
 		strs := strings.Split(i.ApplicationCommandData().Options[1].StringValue(), ",")
-		appids, invalidAppids = strsToAppids(strs)
+		appids, invalidAppids = strsToItems(strs)
 	}
 
 	// Parse guildID
@@ -57,19 +61,44 @@ func setDiscountThresholdHandler(s *discordgo.Session, i *discordgo.InteractionC
 
 	// Set threshold and write reply embed
 	var description string
+	var before, after string
 	if len(appids) == 0 && len(invalidAppids) == 0 {
-		if err = db.SetThreshold(guildID, int(threshold)); err != nil {
+		if guild, err := store.Guild(ctx, guildID); err == nil {
+			before = fmt.Sprintf("%d%%", guild.SaleThreshold)
+		}
+
+		if err = store.SetThreshold(ctx, guildID, int(threshold)); err != nil {
 			description = "Failed to update discount threshold, please try again"
 		} else {
 			description = "Successfully updated discount threshold"
+			after = fmt.Sprintf("%d%%", threshold)
 		}
 	} else {
-		_, fail := db.SetThresholds(guildID, int(threshold), appids)
+		// Get the current thresholds of the apps for the audit log
+		current := map[steam.Item]int{}
+		if guildInfos, err := store.AppsOf(ctx, guildID); err == nil {
+			for _, info := range guildInfos {
+				current[info.Item()] = info.AppSaleThreshold
+			}
+		}
+
+		succ, fail := store.SetThresholds(ctx, guildID, int(threshold), appids)
 		if len(invalidAppids) > 0 || len(fail) > 0 {
 			description = "Failed to set the threshold for some apps, please try again"
 		} else {
 			description = "Successfully updated discount thresholds for apps"
 		}
+
+		befores, afters := []string{}, []string{}
+		for _, item := range succ {
+			old := "general"
+			if current[item] != 0 {
+				old = fmt.Sprintf("%d%%", current[item])
+			}
+			befores = append(befores, fmt.Sprintf("%s: %s", item, old))
+			afters = append(afters, fmt.Sprintf("%s: %d%%", item, threshold))
+		}
+		before, after = strings.Join(befores, ", "), strings.Join(afters, ", ")
 	}
 
 	EditReply(s, i, &discordgo.WebhookEdit{
@@ -80,4 +109,8 @@ func setDiscountThresholdHandler(s *discordgo.Session, i *discordgo.InteractionC
 			},
 		},
 	})
+
+	if after != "" {
+		recordAudit(ctx, s, i, "set_discount_threshold", before, after)
+	}
 }
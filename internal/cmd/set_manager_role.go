@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// NewSetManagerRole creates /set_manager_role <role>.
+func NewSetManagerRole() Cmd {
+	return Cmd{
+		Name:        "set_manager_role",
+		Description: "Set the role allowed to change the tracker's configuration",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionRole,
+				Name:        "role",
+				Description: "The manager role. Leave empty to only allow members with Manage Server",
+			},
+		},
+		Handle:      setManagerRoleHandler,
+		ManagerOnly: true,
+	}
+}
+
+func setManagerRoleHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	// Members with the manager role may not hand it to another role
+	if !hasManageServer(i) {
+		MsgReply(s, i, &discordgo.InteractionResponseData{
+			Content: "Only members with the Manage Server permission can do this",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		return
+	}
+
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	// Parse roleID
+	var roleID int64
+	var role *discordgo.Role
+	if len(i.ApplicationCommandData().Options) > 0 {
+		role = i.ApplicationCommandData().Options[0].RoleValue(nil, i.GuildID)
+		roleID, err = strconv.ParseInt(role.ID, 10, 64)
+		if err != nil {
+			EditReplyUnexpected(s, i)
+			return
+		}
+	}
+
+	// Get the current role for the audit log
+	before := ""
+	if guild, err := store.Guild(ctx, guildID); err == nil && guild.ManagerRoleID != 0 {
+		before = fmt.Sprintf("<@&%d>", guild.ManagerRoleID)
+	}
+
+	// Set role and create embed reply
+	var description, after string
+	err = store.SetManagerRole(ctx, guildID, roleID)
+	switch {
+	case err != nil:
+		description = "Failed to set the manager role, please try again"
+	case role == nil:
+		description = "Successfully unset the manager role. " +
+			"Only members with Manage Server can change the configuration"
+	default:
+		after = role.Mention()
+		description = "Successfully set the manager role to " + role.Mention() + ". " +
+			"Members with it may also need access to the commands in " +
+			"Server Settings > Integrations"
+	}
+
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title:       "Set Manager Role",
+				Description: description,
+			},
+		},
+	})
+
+	if err == nil {
+		recordAudit(ctx, s, i, "set_manager_role", before, after)
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// NewSetMinReviewScore creates /set_min_review_score <score>.
+func NewSetMinReviewScore() Cmd {
+	min := float64(0)
+	return Cmd{
+		Name:        "set_min_review_score",
+		Description: "Set the percent of positive reviews required to trigger a sale alert",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "score",
+				Description: "The percent of positive reviews required. Set to 0 to ignore reviews",
+				Required:    true,
+				MinValue:    &min,
+				MaxValue:    100,
+			},
+		},
+		Handle:      setMinReviewScoreHandler,
+		ManagerOnly: true,
+	}
+}
+
+func setMinReviewScoreHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate) {
+	DeferMsgReply(s, i)
+
+	// Parse score
+	score := int(i.ApplicationCommandData().Options[0].IntValue())
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	var before string
+	if guild, err := store.Guild(ctx, guildID); err == nil {
+		before = describeMinReviewScore(guild.MinReviewScore)
+	}
+
+	description := "Successfully updated minimum review score"
+	if err = store.SetMinReviewScore(ctx, guildID, score); err != nil {
+		description = "Failed to update minimum review score, please try again"
+	}
+
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title:       "Set Minimum Review Score",
+				Description: description,
+			},
+		},
+	})
+
+	if err == nil {
+		recordAudit(ctx, s, i, "set_min_review_score", before, describeMinReviewScore(score))
+	}
+}
+
+// describeMinReviewScore describes score, the percent of positive reviews
+// required for sale alerts.
+func describeMinReviewScore(score int) string {
+	if score == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d%% positive", score)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/schedule"
+)
+
+// NewStatus creates /status. sched is the schedule sale checks run on.
+func NewStatus(sched schedule.Schedule) Cmd {
+	return Cmd{
+		Name:        "status",
+		Description: "Show whether alerts can be delivered to the bound channel",
+		Handle: func(ctx context.Context, s Session, i *discordgo.InteractionCreate) {
+			statusHandler(ctx, s, i, sched)
+		},
+	}
+}
+
+func statusHandler(ctx context.Context, s Session,
+	i *discordgo.InteractionCreate, sched schedule.Schedule) {
+	DeferMsgReply(s, i)
+
+	// Parse guildID
+	guildID, err := strconv.ParseInt(i.GuildID, 10, 64)
+	if err != nil {
+		EditReplyUnexpected(s, i)
+		return
+	}
+
+	guild, err := store.Guild(ctx, guildID)
+	if err != nil {
+		EditReply(s, i, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{
+				{
+					Title:       "Status",
+					Description: "Failed to get status, please try again",
+				},
+			},
+		})
+		return
+	}
+
+	// Create embed reply
+	channel := "None, use /bind to set one"
+	delivery := "Unable to deliver alerts until a channel is bound"
+	if guild.ChannelID != 0 {
+		channelID := strconv.FormatInt(guild.ChannelID, 10)
+		channel = "<#" + channelID + ">"
+
+		if CanSendAlerts(s.State, channelID) {
+			delivery = "Alerts can be delivered"
+		} else {
+			delivery = "Unable to deliver alerts, missing view channel, " +
+				"send message, or embed links permissions for the bound channel"
+		}
+	}
+
+	EditReply(s, i, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title: "Status",
+				Fields: []*discordgo.MessageEmbedField{
+					{
+						Name:   "Bound Channel",
+						Value:  channel,
+						Inline: true,
+					},
+					{
+						Name:   "General Discount Threshold",
+						Value:  fmt.Sprintf("%d%%", guild.SaleThreshold),
+						Inline: true,
+					},
+					{
+						Name:   "Minimum Review Score",
+						Value:  describeMinReviewScore(guild.MinReviewScore),
+						Inline: true,
+					},
+					{
+						Name:  "Delivery",
+						Value: delivery,
+					},
+					{
+						Name:   "Check Schedule",
+						Value:  "Every day at " + sched.String(),
+						Inline: true,
+					},
+					{
+						Name:   "Next Check",
+						Value:  fmt.Sprintf("<t:%d:R>", sched.Next(time.Now()).Unix()),
+						Inline: true,
+					},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// configStore is what commands use of the database. See the db functions
+// of the same names.
+type configStore interface {
+	// Guild settings
+	Guild(ctx context.Context, guildID int64) (db.DiscordInfo, error)
+	SetChannelID(ctx context.Context, guildID, channelID int64) error
+	SetThreshold(ctx context.Context, guildID int64, threshold int) error
+	SetManagerRole(ctx context.Context, guildID, roleID int64) error
+	SetAuditChannel(ctx context.Context, guildID, channelID int64) error
+	SetMinReviewScore(ctx context.Context, guildID int64, score int) error
+	SetHiddenFields(ctx context.Context, guildID int64, fields []string) error
+	SetWishlistSync(ctx context.Context, guildID, steamID int64, seen []int) error
+
+	// Tracked apps
+	AppsOf(ctx context.Context, guildID int64) ([]db.GuildInfo, error)
+	AddApps(ctx context.Context, guildID int64, apps []*steam.App) (
+		succ []*steam.App, fail []*steam.App)
+	AddDLC(ctx context.Context, guildID int64, appid int, dlc []*steam.App) (
+		succ []*steam.App, fail []*steam.App)
+	SetKnownDLC(ctx context.Context, guildID int64, appid int, known []int) error
+	SetThresholds(ctx context.Context, guildID int64, threshold int, items []steam.Item) (
+		succ []steam.Item, fail []steam.Item)
+	RemoveApps(ctx context.Context, guildID int64, items []steam.Item) (
+		succ []steam.Item, fail []steam.Item, trashID primitive.ObjectID)
+	ClearApps(ctx context.Context, guildID int64) (trashID primitive.ObjectID, err error)
+	LatestTrash(ctx context.Context, guildID int64) (db.TrashInfo, error)
+	RestoreApps(ctx context.Context, guildID int64, trashID primitive.ObjectID) (
+		[]db.TrashedApp, error)
+
+	// Logs
+	RecordAudit(ctx context.Context, info db.AuditInfo) (db.AuditInfo, error)
+	AuditLog(ctx context.Context, guildID int64, skip, limit int) (
+		entries []db.AuditInfo, total int64, err error)
+	History(ctx context.Context, guildID int64, filter db.HistoryFilter, skip, limit int) (
+		alerts []db.HistoryInfo, total int64, err error)
+	DeadAlerts(ctx context.Context, guildID int64) ([]db.OutboxInfo, error)
+}
+
+// store is the configStore commands use. Tests replace it with a fake.
+var store configStore = dbStore{}
+
+// dbStore is the configStore backed by the database.
+type dbStore struct{}
+
+func (dbStore) Guild(ctx context.Context, guildID int64) (db.DiscordInfo, error) {
+	return db.Guild(ctx, guildID)
+}
+
+func (dbStore) SetChannelID(ctx context.Context, guildID, channelID int64) error {
+	return db.SetChannelID(ctx, guildID, channelID)
+}
+
+func (dbStore) SetThreshold(ctx context.Context, guildID int64, threshold int) error {
+	return db.SetThreshold(ctx, guildID, threshold)
+}
+
+func (dbStore) SetManagerRole(ctx context.Context, guildID, roleID int64) error {
+	return db.SetManagerRole(ctx, guildID, roleID)
+}
+
+func (dbStore) SetAuditChannel(ctx context.Context, guildID, channelID int64) error {
+	return db.SetAuditChannel(ctx, guildID, channelID)
+}
+
+func (dbStore) SetMinReviewScore(ctx context.Context, guildID int64, score int) error {
+	return db.SetMinReviewScore(ctx, guildID, score)
+}
+
+func (dbStore) SetHiddenFields(ctx context.Context, guildID int64, fields []string) error {
+	return db.SetHiddenFields(ctx, guildID, fields)
+}
+
+func (dbStore) SetWishlistSync(ctx context.Context, guildID, steamID int64, seen []int) error {
+	return db.SetWishlistSync(ctx, guildID, steamID, seen)
+}
+
+func (dbStore) AppsOf(ctx context.Context, guildID int64) ([]db.GuildInfo, error) {
+	return db.AppsOf(ctx, guildID)
+}
+
+func (dbStore) AddApps(ctx context.Context, guildID int64, apps []*steam.App) (
+	succ []*steam.App, fail []*steam.App) {
+	return db.AddApps(ctx, guildID, apps)
+}
+
+func (dbStore) AddDLC(ctx context.Context, guildID int64, appid int, dlc []*steam.App) (
+	succ []*steam.App, fail []*steam.App) {
+	return db.AddDLC(ctx, guildID, appid, dlc)
+}
+
+func (dbStore) SetKnownDLC(ctx context.Context, guildID int64, appid int, known []int) error {
+	return db.SetKnownDLC(ctx, guildID, appid, known)
+}
+
+func (dbStore) SetThresholds(ctx context.Context, guildID int64, threshold int,
+	items []steam.Item) (succ []steam.Item, fail []steam.Item) {
+	return db.SetThresholds(ctx, guildID, threshold, items)
+}
+
+func (dbStore) RemoveApps(ctx context.Context, guildID int64, items []steam.Item) (
+	succ []steam.Item, fail []steam.Item, trashID primitive.ObjectID) {
+	return db.RemoveApps(ctx, guildID, items)
+}
+
+func (dbStore) ClearApps(ctx context.Context, guildID int64) (primitive.ObjectID, error) {
+	return db.ClearApps(ctx, guildID)
+}
+
+func (dbStore) LatestTrash(ctx context.Context, guildID int64) (db.TrashInfo, error) {
+	return db.LatestTrash(ctx, guildID)
+}
+
+func (dbStore) RestoreApps(ctx context.Context, guildID int64, trashID primitive.ObjectID) (
+	[]db.TrashedApp, error) {
+	return db.RestoreApps(ctx, guildID, trashID)
+}
+
+func (dbStore) RecordAudit(ctx context.Context, info db.AuditInfo) (db.AuditInfo, error) {
+	return db.RecordAudit(ctx, info)
+}
+
+func (dbStore) AuditLog(ctx context.Context, guildID int64, skip, limit int) (
+	[]db.AuditInfo, int64, error) {
+	return db.AuditLog(ctx, guildID, skip, limit)
+}
+
+func (dbStore) History(ctx context.Context, guildID int64, filter db.HistoryFilter,
+	skip, limit int) ([]db.HistoryInfo, int64, error) {
+	return db.History(ctx, guildID, filter, skip, limit)
+}
+
+func (dbStore) DeadAlerts(ctx context.Context, guildID int64) ([]db.OutboxInfo, error) {
+	return db.DeadAlerts(ctx, guildID)
+}
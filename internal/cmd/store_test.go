@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeStore is an in-memory configStore. It starts out with the test guild.
+type fakeStore struct {
+	mu      sync.Mutex
+	guilds  map[int64]db.DiscordInfo
+	apps    []db.GuildInfo // The apps of every guild, without the guild's settings
+	trash   []db.TrashInfo
+	audits  []db.AuditInfo
+	history []db.HistoryInfo
+	dead    []db.OutboxInfo
+}
+
+// useFakeStore has commands use a new fakeStore until t ends.
+func useFakeStore(t *testing.T) *fakeStore {
+	guildID, _ := strconv.ParseInt(testGuildID, 10, 64)
+	f := &fakeStore{
+		guilds: map[int64]db.DiscordInfo{
+			guildID: {ServerID: guildID, SaleThreshold: 1},
+		},
+	}
+
+	prev := store
+	store = f
+	t.Cleanup(func() { store = prev })
+	return f
+}
+
+// app gets the app of a guild matching appid, or nil if there isn't one.
+func (f *fakeStore) app(guildID int64, appid int) *db.GuildInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.indexOf(guildID, steam.AppItem(appid))
+	if i == -1 {
+		return nil
+	}
+	app := f.apps[i]
+	return &app
+}
+
+// auditLog gets the audit entries recorded, in order.
+func (f *fakeStore) auditLog() []db.AuditInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]db.AuditInfo{}, f.audits...)
+}
+
+func (f *fakeStore) Guild(_ context.Context, guildID int64) (db.DiscordInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	guild, ok := f.guilds[guildID]
+	if !ok {
+		return db.DiscordInfo{}, errors.New("no such guild")
+	}
+	return guild, nil
+}
+
+// updateGuild applies update to the settings of the guild matching guildID.
+func (f *fakeStore) updateGuild(guildID int64, update func(*db.DiscordInfo)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	guild, ok := f.guilds[guildID]
+	if !ok {
+		return errors.New("no such guild")
+	}
+	update(&guild)
+	f.guilds[guildID] = guild
+	return nil
+}
+
+func (f *fakeStore) SetChannelID(_ context.Context, guildID, channelID int64) error {
+	return f.updateGuild(guildID, func(g *db.DiscordInfo) { g.ChannelID = channelID })
+}
+
+func (f *fakeStore) SetThreshold(_ context.Context, guildID int64, threshold int) error {
+	return f.updateGuild(guildID, func(g *db.DiscordInfo) { g.SaleThreshold = threshold })
+}
+
+func (f *fakeStore) SetManagerRole(_ context.Context, guildID, roleID int64) error {
+	return f.updateGuild(guildID, func(g *db.DiscordInfo) { g.ManagerRoleID = roleID })
+}
+
+func (f *fakeStore) SetAuditChannel(_ context.Context, guildID, channelID int64) error {
+	return f.updateGuild(guildID, func(g *db.DiscordInfo) { g.AuditChannelID = channelID })
+}
+
+func (f *fakeStore) SetMinReviewScore(_ context.Context, guildID int64, score int) error {
+	return f.updateGuild(guildID, func(g *db.DiscordInfo) { g.MinReviewScore = score })
+}
+
+func (f *fakeStore) SetHiddenFields(_ context.Context, guildID int64, fields []string) error {
+	return f.updateGuild(guildID, func(g *db.DiscordInfo) { g.HiddenFields = fields })
+}
+
+func (f *fakeStore) SetWishlistSync(_ context.Context, guildID, steamID int64,
+	seen []int) error {
+	return f.updateGuild(guildID, func(g *db.DiscordInfo) {
+		g.WishlistSteamID, g.WishlistSeen = steamID, seen
+	})
+}
+
+func (f *fakeStore) AppsOf(_ context.Context, guildID int64) (guildInfos []db.GuildInfo,
+	err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	guild, ok := f.guilds[guildID]
+	if !ok {
+		return nil, errors.New("no such guild")
+	}
+	for _, app := range f.apps {
+		if app.ServerID == guildID {
+			app.ChannelID, app.SaleThreshold = guild.ChannelID, guild.SaleThreshold
+			guildInfos = append(guildInfos, app)
+		}
+	}
+	return guildInfos, nil
+}
+
+func (f *fakeStore) AddApps(_ context.Context, guildID int64, apps []*steam.App) (
+	succ []*steam.App, fail []*steam.App) {
+	return f.addApps(guildID, apps, 0)
+}
+
+func (f *fakeStore) AddDLC(_ context.Context, guildID int64, appid int, dlc []*steam.App) (
+	succ []*steam.App, fail []*steam.App) {
+	return f.addApps(guildID, dlc, appid)
+}
+
+// addApps adds apps like db.AddApps(...) does, as DLC of the game matching
+// dlcOf unless it's 0.
+func (f *fakeStore) addApps(guildID int64, apps []*steam.App, dlcOf int) (
+	succ []*steam.App, fail []*steam.App) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, app := range apps {
+		info := db.GuildInfo{
+			ServerID:   guildID,
+			Appid:      app.Item().ID,
+			Kind:       app.Item().Kind,
+			AppName:    app.Name,
+			ComingSoon: app.ComingSoon,
+			DLCOf:      dlcOf,
+		}
+		if app.SaleThreshold != nil {
+			info.AppSaleThreshold = *app.SaleThreshold
+		}
+		f.apps = append(f.apps, info)
+		succ = append(succ, app)
+	}
+	return succ, nil
+}
+
+func (f *fakeStore) SetKnownDLC(_ context.Context, guildID int64, appid int,
+	known []int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.indexOf(guildID, steam.AppItem(appid))
+	if i == -1 {
+		return errors.New("no such app")
+	}
+	f.apps[i].TrackDLC, f.apps[i].KnownDLC = true, known
+	return nil
+}
+
+func (f *fakeStore) SetThresholds(_ context.Context, guildID int64, threshold int,
+	items []steam.Item) (succ []steam.Item, fail []steam.Item) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range items {
+		i := f.indexOf(guildID, item)
+		if i == -1 {
+			fail = append(fail, item)
+			continue
+		}
+		f.apps[i].AppSaleThreshold = threshold
+		succ = append(succ, item)
+	}
+	return succ, fail
+}
+
+func (f *fakeStore) RemoveApps(_ context.Context, guildID int64, items []steam.Item) (
+	succ []steam.Item, fail []steam.Item, trashID primitive.ObjectID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var removed []db.GuildInfo
+	for _, item := range items {
+		i := f.indexOf(guildID, item)
+		if i == -1 {
+			fail = append(fail, item)
+			continue
+		}
+		removed = append(removed, f.apps[i])
+		f.apps = slices.Delete(f.apps, i, i+1)
+		succ = append(succ, item)
+	}
+	return succ, fail, f.trashApps(guildID, removed)
+}
+
+func (f *fakeStore) ClearApps(_ context.Context, guildID int64) (primitive.ObjectID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var removed []db.GuildInfo
+	f.apps = slices.DeleteFunc(f.apps, func(app db.GuildInfo) bool {
+		if app.ServerID != guildID {
+			return false
+		}
+		removed = append(removed, app)
+		return true
+	})
+	return f.trashApps(guildID, removed), nil
+}
+
+func (f *fakeStore) LatestTrash(_ context.Context, guildID int64) (db.TrashInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.trash) - 1; i >= 0; i-- {
+		if f.trash[i].ServerID == guildID {
+			return f.trash[i], nil
+		}
+	}
+	return db.TrashInfo{}, db.ErrNothingToRestore
+}
+
+func (f *fakeStore) RestoreApps(_ context.Context, guildID int64,
+	trashID primitive.ObjectID) ([]db.TrashedApp, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.trash, func(t db.TrashInfo) bool {
+		return t.ID == trashID && t.ServerID == guildID
+	})
+	if i == -1 {
+		return nil, db.ErrNothingToRestore
+	}
+	trash := f.trash[i]
+	f.trash = slices.Delete(f.trash, i, i+1)
+	for _, app := range trash.Apps {
+		if f.indexOf(guildID, app.Item()) != -1 {
+			continue
+		}
+		f.apps = append(f.apps, db.GuildInfo{
+			ServerID:         guildID,
+			Appid:            app.Appid,
+			Kind:             app.Kind,
+			AppName:          app.AppName,
+			AppSaleThreshold: app.SaleThreshold,
+			TrailingSaleDay:  app.TrailingSaleDay,
+			ComingSoon:       app.ComingSoon,
+			DLCOf:            app.DLCOf,
+			TrackDLC:         app.TrackDLC,
+			KnownDLC:         app.KnownDLC,
+		})
+	}
+	return trash.Apps, nil
+}
+
+// indexOf finds the app of a guild matching item, or -1 if there isn't one.
+// f.mu must be held.
+func (f *fakeStore) indexOf(guildID int64, item steam.Item) int {
+	return slices.IndexFunc(f.apps, func(app db.GuildInfo) bool {
+		return app.ServerID == guildID && app.Item() == item
+	})
+}
+
+// trashApps snapshots removed apps of a guild like db.RemoveApps(...) does,
+// returning the snapshot's ID. The zero ID is returned if nothing was
+// removed. f.mu must be held.
+func (f *fakeStore) trashApps(guildID int64, removed []db.GuildInfo) primitive.ObjectID {
+	if len(removed) == 0 {
+		return primitive.NilObjectID
+	}
+	trash := db.TrashInfo{
+		ID:        primitive.NewObjectID(),
+		ServerID:  guildID,
+		DeletedAt: time.Now(),
+	}
+	for _, app := range removed {
+		trash.Apps = append(trash.Apps, db.TrashedApp{
+			Appid:           app.Appid,
+			Kind:            app.Kind,
+			AppName:         app.AppName,
+			SaleThreshold:   app.AppSaleThreshold,
+			TrailingSaleDay: app.TrailingSaleDay,
+			ComingSoon:      app.ComingSoon,
+			DLCOf:           app.DLCOf,
+			TrackDLC:        app.TrackDLC,
+			KnownDLC:        app.KnownDLC,
+		})
+	}
+	f.trash = append(f.trash, trash)
+	return trash.ID
+}
+
+func (f *fakeStore) RecordAudit(_ context.Context, info db.AuditInfo) (db.AuditInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.audits = append(f.audits, info)
+	return info, nil
+}
+
+func (f *fakeStore) AuditLog(_ context.Context, guildID int64, skip, limit int) (
+	entries []db.AuditInfo, total int64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Newest first, like db.AuditLog(...)
+	for _, entry := range slices.Backward(f.audits) {
+		if entry.ServerID == guildID {
+			entries = append(entries, entry)
+		}
+	}
+	return page(entries, skip, limit), int64(len(entries)), nil
+}
+
+func (f *fakeStore) History(_ context.Context, guildID int64, filter db.HistoryFilter,
+	skip, limit int) (alerts []db.HistoryInfo, total int64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, alert := range slices.Backward(f.history) {
+		if alert.ServerID == guildID &&
+			(filter.Appid == 0 || alert.Item() == steam.AppItem(filter.Appid)) &&
+			!alert.SentAt.Before(filter.Since) {
+			alerts = append(alerts, alert)
+		}
+	}
+	return page(alerts, skip, limit), int64(len(alerts)), nil
+}
+
+func (f *fakeStore) DeadAlerts(_ context.Context, guildID int64) (
+	alerts []db.OutboxInfo, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, alert := range f.dead {
+		if alert.ServerID == guildID {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, nil
+}
+
+// page gets the items of s after the first skip, up to limit of them.
+func page[T any](s []T, skip, limit int) []T {
+	s = s[min(skip, len(s)):]
+	return s[:min(limit, len(s))]
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditRecord is a change a member made to a guild's configuration.
+type AuditRecord struct {
+	ID        *primitive.ObjectID `bson:"_id,omitempty"`
+	ServerID  *int64              `bson:"server_id,omitempty"`
+	UserID    *int64              `bson:"user_id,omitempty"`
+	Command   *string             `bson:"command,omitempty"`
+	Args      *string             `bson:"args,omitempty"`
+	Before    *string             `bson:"before,omitempty"`
+	After     *string             `bson:"after,omitempty"`
+	CreatedAt *time.Time          `bson:"created_at,omitempty"`
+}
+
+type AuditInfo struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	ServerID  int64              `bson:"server_id"`
+	UserID    int64              `bson:"user_id"`
+	Command   string             `bson:"command"`
+	Args      string             `bson:"args"`
+	Before    string             `bson:"before"`
+	After     string             `bson:"after"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// RecordAudit adds a change to the audit log. The ID and creation time
+// are filled in if unset.
+func RecordAudit(ctx context.Context, info AuditInfo) (AuditInfo, error) {
+	if info.ID.IsZero() {
+		info.ID = primitive.NewObjectID()
+	}
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = time.Now()
+	}
+
+	_, err := auditColl.InsertOne(ctx, AuditRecord{
+		ID:        &info.ID,
+		ServerID:  &info.ServerID,
+		UserID:    &info.UserID,
+		Command:   &info.Command,
+		Args:      &info.Args,
+		Before:    &info.Before,
+		After:     &info.After,
+		CreatedAt: &info.CreatedAt,
+	})
+	if err != nil {
+		return AuditInfo{}, err
+	}
+
+	return info, nil
+}
+
+// AuditLog finds a page of a guild's audit log, newest first. total is
+// the number of changes across every page.
+func AuditLog(ctx context.Context, guildID int64, skip, limit int) (
+	entries []AuditInfo, total int64, err error) {
+	filter := AuditRecord{ServerID: &guildID}
+
+	total, err = auditColl.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cur, err := auditColl.Find(ctx, filter,
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetSkip(int64(skip)).
+			SetLimit(int64(limit)))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var info AuditInfo
+		if err := cur.Decode(&info); err != nil {
+			continue
+		}
+		entries = append(entries, info)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
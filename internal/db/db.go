@@ -8,6 +8,7 @@ import (
 
 	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -17,69 +18,118 @@ var client *mongo.Client
 var (
 	appsColl,
 	discordColl,
-	junctionColl *mongo.Collection
+	junctionColl,
+	outboxColl,
+	historyColl,
+	auditColl,
+	migrationsColl,
+	trashColl *mongo.Collection
 )
 
+// AppRecord is an item of the store, not just an app. Its Appid is the id
+// of whichever Kind it is.
 type AppRecord struct {
-	Appid   *int    `bson:"app_id,omitempty"`
-	AppName *string `bson:"app_name,omitempty"`
+	Appid   *int            `bson:"app_id,omitempty"`
+	Kind    *steam.ItemKind `bson:"kind,omitempty"`
+	AppName *string         `bson:"app_name,omitempty"`
 }
 
 type AppInfo struct {
-	Appid   int    `bson:"app_id"`
-	AppName string `bson:"app_name"`
+	Appid   int            `bson:"app_id"`
+	Kind    steam.ItemKind `bson:"kind"`
+	AppName string         `bson:"app_name"`
 }
 
 type DiscordRecord struct {
-	ServerID      *int64 `bson:"server_id,omitempty"`
-	ChannelID     *int64 `bson:"channel_id,omitempty"`
-	SaleThreshold *int   `bson:"sale_threshold,omitempty"`
+	ServerID        *int64     `bson:"server_id,omitempty"`
+	ChannelID       *int64     `bson:"channel_id,omitempty"`
+	SaleThreshold   *int       `bson:"sale_threshold,omitempty"`
+	MissingSince    *time.Time `bson:"missing_since,omitempty"`
+	ManagerRoleID   *int64     `bson:"manager_role_id,omitempty"`
+	WishlistSteamID *int64     `bson:"wishlist_steam_id,omitempty"`
+	WishlistSeen    []int      `bson:"wishlist_seen,omitempty"`
+	AuditChannelID  *int64     `bson:"audit_channel_id,omitempty"`
+	HiddenFields    []string   `bson:"hidden_fields,omitempty"`
+	MinReviewScore  *int       `bson:"min_review_score,omitempty"`
 }
 
 type DiscordInfo struct {
-	ServerID      int64 `bson:"server_id"`
-	ChannelID     int64 `bson:"channel_id"`
-	SaleThreshold int   `bson:"sale_threshold"`
+	ServerID        int64     `bson:"server_id"`
+	ChannelID       int64     `bson:"channel_id"`
+	SaleThreshold   int       `bson:"sale_threshold"`
+	MissingSince    time.Time `bson:"missing_since"`
+	ManagerRoleID   int64     `bson:"manager_role_id"`
+	WishlistSteamID int64     `bson:"wishlist_steam_id"`
+	WishlistSeen    []int     `bson:"wishlist_seen"`
+	AuditChannelID  int64     `bson:"audit_channel_id"`
+	HiddenFields    []string  `bson:"hidden_fields"`
+	MinReviewScore  int       `bson:"min_review_score"`
 }
 
 type JunctionRecord struct {
-	Appid           *int   `bson:"app_id,omitempty"`
-	ServerID        *int64 `bson:"server_id,omitempty"`
-	TrailingSaleDay *bool  `bson:"is_trailing_sale_day,omitempty"`
-	ComingSoon      *bool  `bson:"coming_soon,omitempty"`
-	SaleThreshold   *int   `bson:"sale_threshold,omitempty"`
+	Appid           *int            `bson:"app_id,omitempty"`
+	Kind            *steam.ItemKind `bson:"kind,omitempty"`
+	ServerID        *int64          `bson:"server_id,omitempty"`
+	TrailingSaleDay *bool           `bson:"is_trailing_sale_day,omitempty"`
+	ComingSoon      *bool           `bson:"coming_soon,omitempty"`
+	SaleThreshold   *int            `bson:"sale_threshold,omitempty"`
+	DLCOf           *int            `bson:"dlc_of,omitempty"`
+	TrackDLC        *bool           `bson:"track_dlc,omitempty"`
+	KnownDLC        []int           `bson:"known_dlc,omitempty"`
 }
 
 type JunctionInfo struct {
-	Appid           int   `bson:"app_id"`
-	ServerID        int64 `bson:"server_id"`
-	TrailingSaleDay bool  `bson:"is_trailing_sale_day"`
-	ComingSoon      bool  `bson:"coming_soon"`
-	SaleThreshold   int   `bson:"sale_threshold"`
+	Appid           int            `bson:"app_id"`
+	Kind            steam.ItemKind `bson:"kind"`
+	ServerID        int64          `bson:"server_id"`
+	TrailingSaleDay bool           `bson:"is_trailing_sale_day"`
+	ComingSoon      bool           `bson:"coming_soon"`
+	SaleThreshold   int            `bson:"sale_threshold"`
+	DLCOf           int            `bson:"dlc_of"`
+	TrackDLC        bool           `bson:"track_dlc"`
+	KnownDLC        []int          `bson:"known_dlc"`
 }
 
 type GuildInfo struct {
 	ServerID         int64
 	ChannelID        int64
 	Appid            int
+	Kind             steam.ItemKind
 	AppName          string
 	AppSaleThreshold int
 	SaleThreshold    int
 	TrailingSaleDay  bool
 	ComingSoon       bool
+	HiddenFields     []string  // Fields left out of the guild's sale alerts
+	MinReviewScore   int       // Percent of positive reviews required for sale alerts
+	DLCOf            int       // Appid of the game the app was added as DLC of, or 0
+	TrackDLC         bool      // Whether new DLC of the app is added as it's released
+	KnownDLC         []int     // Appids of the app's DLC as of the last check
+	MissingSince     time.Time // When the bot was found removed from the guild, or zero
 }
 
-func ctx() context.Context {
-	return context.Background()
+// Item references the app of g.
+func (g GuildInfo) Item() steam.Item {
+	return itemOf(g.Kind, g.Appid)
 }
 
-// Init intializes the database.
-// Close() should be called to close the database.
-func Init(uri, dbName string) {
+// itemOf references the item of kind matching id. Items without a kind
+// are apps, since they were saved before packages and bundles could be.
+func itemOf(kind steam.ItemKind, id int) steam.Item {
+	if kind == "" {
+		kind = steam.KindApp
+	}
+	return steam.Item{Kind: kind, ID: id}
+}
+
+// Init intializes the database. Migrate() should be called afterward to
+// bring the database up to date. Close() should be called to close the
+// database.
+func Init(ctx context.Context, uri, dbName string) {
 	var err error
 
 	client, err = mongo.Connect(
-		ctx(),
+		ctx,
 		options.Client().
 			ApplyURI(uri).
 			SetSocketTimeout(15*time.Second),
@@ -87,25 +137,55 @@ func Init(uri, dbName string) {
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
-	if err = client.Ping(context.Background(), nil); err != nil {
+	if err = client.Ping(ctx, nil); err != nil {
 		log.Fatal("Failed to ping database:", err)
 	}
 
 	appsColl = client.Database(dbName).Collection("apps")
 	discordColl = client.Database(dbName).Collection("discord")
 	junctionColl = client.Database(dbName).Collection("junction")
+	outboxColl = client.Database(dbName).Collection("outbox")
+	historyColl = client.Database(dbName).Collection("history")
+	auditColl = client.Database(dbName).Collection("audit")
+	migrationsColl = client.Database(dbName).Collection("migrations")
+	trashColl = client.Database(dbName).Collection("trash")
+
+	// Snapshots of removed apps expire on their own
+	_, err = trashColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(TrashTTL.Seconds())),
+	})
+	if err != nil {
+		log.Println("Failed to create trash TTL index:", err)
+	}
+
+	// History is always looked up by guild, newest first
+	_, err = historyColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "sent_at", Value: -1}},
+	})
+	if err != nil {
+		log.Println("Failed to create history index:", err)
+	}
+
+	// So is the audit log
+	_, err = auditColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		log.Println("Failed to create audit index:", err)
+	}
 }
 
 // Close closes the database
-func Close() {
-	if err := client.Disconnect(context.Background()); err != nil {
+func Close(ctx context.Context) {
+	if err := client.Disconnect(ctx); err != nil {
 		log.Fatal("Error disconnecting:", err)
 	}
 }
 
 // validateColFilDoc verifies that the combination of parameters
 // are meant to be used with each other. They should all be the
-// same kind App, Discord, or Junction.
+// same kind App, Discord, Junction, or Outbox.
 //
 // Panics on invalid combinations or types.
 func validateColFilDoc(coll *mongo.Collection, filter any, doc any) {
@@ -121,6 +201,9 @@ func validateColFilDoc(coll *mongo.Collection, filter any, doc any) {
 	case JunctionRecord:
 		_, ok = doc.(JunctionRecord)
 		ok = ok && (coll == junctionColl)
+	case OutboxRecord:
+		_, ok = doc.(OutboxRecord)
+		ok = ok && (coll == outboxColl)
 	}
 
 	if !ok {
@@ -131,10 +214,10 @@ func validateColFilDoc(coll *mongo.Collection, filter any, doc any) {
 // insert adds a doc to coll if there is nothing in coll matching filter.
 // If there is a doc matching filter, no insertion occurs and it isn't
 // considered an error.
-func insert(coll *mongo.Collection, filter any, doc any) error {
+func insert(ctx context.Context, coll *mongo.Collection, filter any, doc any) error {
 	validateColFilDoc(coll, filter, doc)
 	_, err := coll.UpdateOne(
-		ctx(),
+		ctx,
 		filter,
 		bson.M{
 			"$setOnInsert": doc,
@@ -147,10 +230,10 @@ func insert(coll *mongo.Collection, filter any, doc any) error {
 // update finds a doc in coll matching filter and updates it with doc.
 // If there is no doc matching filter, no update occurs and it isn't
 // considered an error.
-func update(coll *mongo.Collection, filter any, doc any) error {
+func update(ctx context.Context, coll *mongo.Collection, filter any, doc any) error {
 	validateColFilDoc(coll, filter, doc)
 	_, err := coll.UpdateOne(
-		ctx(),
+		ctx,
 		filter,
 		bson.M{
 			"$set": doc,
@@ -161,10 +244,10 @@ func update(coll *mongo.Collection, filter any, doc any) error {
 
 // upsert finds a doc in coll matching filter and updates it with doc.
 // If there is no doc matching filter, doc is added.
-func upsert(coll *mongo.Collection, filter any, doc any) error {
+func upsert(ctx context.Context, coll *mongo.Collection, filter any, doc any) error {
 	validateColFilDoc(coll, filter, doc)
 	_, err := coll.UpdateOne(
-		ctx(),
+		ctx,
 		filter,
 		bson.M{
 			"$set": doc,
@@ -178,9 +261,9 @@ func upsert(coll *mongo.Collection, filter any, doc any) error {
 // already a record in the database with the same guildID, nothing happens
 // and it isn't considered an error. If a channelID cannot be added right now,
 // pass 0 for channelID.
-func AddGuild(guildID, channelID int64) error {
+func AddGuild(ctx context.Context, guildID, channelID int64) error {
 	saleThreshold := 1
-	return insert(discordColl,
+	return insert(ctx, discordColl,
 		DiscordRecord{ServerID: &guildID},
 		DiscordRecord{
 			ServerID:      &guildID,
@@ -192,19 +275,80 @@ func AddGuild(guildID, channelID int64) error {
 
 // RemoveGuild removes a guild and its app from the database.
 // If there's no record in the database with the guildID, nothing happens
-// and it isn't considered an error.
-func RemoveGuild(guildID int64) error {
-	ClearApps(guildID)
-	_, err := discordColl.DeleteOne(ctx(),
+// and it isn't considered an error. Unlike ClearApps(...), no snapshot
+// of the apps is kept.
+func RemoveGuild(ctx context.Context, guildID int64) error {
+	if items, err := itemsOf(ctx, guildID); err == nil {
+		removeApps(ctx, guildID, items)
+	}
+	_, err := discordColl.DeleteOne(ctx,
 		DiscordRecord{ServerID: &guildID})
 	return err
 }
 
+// Guild finds the settings of the guild matching guildID. If guildID
+// hasn't been added through AddGuild(...), mongo.ErrNoDocuments is returned.
+func Guild(ctx context.Context, guildID int64) (DiscordInfo, error) {
+	var dInfo DiscordInfo
+	err := discordColl.FindOne(ctx, DiscordRecord{ServerID: &guildID}).Decode(&dInfo)
+	return dInfo, err
+}
+
+// Guilds finds the settings of every guild in the database.
+func Guilds(ctx context.Context) (dInfos []DiscordInfo, err error) {
+	cur, err := discordColl.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var dInfo DiscordInfo
+		if err := cur.Decode(&dInfo); err != nil {
+			continue
+		}
+		dInfos = append(dInfos, dInfo)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return dInfos, nil
+}
+
+// MarkGuildMissing records that the bot is no longer a member of a guild
+// as of since. If the guild is already marked missing, the original time
+// is kept.
+func MarkGuildMissing(ctx context.Context, guildID int64, since time.Time) error {
+	_, err := discordColl.UpdateOne(ctx,
+		bson.M{
+			"server_id":     guildID,
+			"missing_since": bson.M{"$exists": false},
+		},
+		bson.M{
+			"$set": DiscordRecord{MissingSince: &since},
+		},
+	)
+	return err
+}
+
+// ClearGuildMissing removes the mark set by MarkGuildMissing(...), e.g.,
+// when the bot is found to be a member of the guild again.
+func ClearGuildMissing(ctx context.Context, guildID int64) error {
+	_, err := discordColl.UpdateOne(ctx,
+		DiscordRecord{ServerID: &guildID},
+		bson.M{
+			"$unset": bson.M{"missing_since": ""},
+		},
+	)
+	return err
+}
+
 // AppsOf finds all GuildInfos tracked by the guild matching guildID.
 // If guildID hasn't been added through AddGuild(...), an empty
 // list will be returned.
-func AppsOf(guildID int64) (guildInfos []GuildInfo, err error) {
-	findGuildRes := discordColl.FindOne(ctx(), DiscordRecord{ServerID: &guildID})
+func AppsOf(ctx context.Context, guildID int64) (guildInfos []GuildInfo, err error) {
+	findGuildRes := discordColl.FindOne(ctx, DiscordRecord{ServerID: &guildID})
 	if err := findGuildRes.Err(); err != nil {
 		return nil, err
 	}
@@ -213,26 +357,35 @@ func AppsOf(guildID int64) (guildInfos []GuildInfo, err error) {
 		return nil, err
 	}
 
-	cur, err := junctionColl.Find(ctx(), JunctionRecord{ServerID: &guildID})
+	// Join each of the guild's junctions with its app in a single query
+	cur, err := junctionColl.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"server_id": guildID}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": appsColl.Name(),
+			"let":  bson.M{"app_id": "$app_id", "kind": "$kind"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$app_id", "$$app_id"}},
+					bson.M{"$eq": bson.A{"$kind", "$$kind"}},
+				}}}}},
+				// A duplicate app would otherwise duplicate the junction
+				{{Key: "$limit", Value: 1}},
+			},
+			"as": "app",
+		}}},
+		{{Key: "$unwind", Value: "$app"}},
+	})
 	if err != nil {
 		return nil, err
 	}
+	defer cur.Close(ctx)
 
-	// Extract appid from each JunctionRecord to filter
-	// for that App's AppRecord
-	for cur.Next(ctx()) {
-		var jInfo JunctionInfo
-		if err := cur.Decode(&jInfo); err != nil {
-			continue
-		}
-
-		findAppRes := appsColl.FindOne(ctx(), AppRecord{Appid: &jInfo.Appid})
-		if err := findAppRes.Err(); err != nil {
-			continue
+	for cur.Next(ctx) {
+		var joined struct {
+			JunctionInfo `bson:",inline"`
+			App          AppInfo `bson:"app"`
 		}
-
-		var aInfo AppInfo
-		if err := findAppRes.Decode(&aInfo); err != nil {
+		if err := cur.Decode(&joined); err != nil {
 			continue
 		}
 
@@ -240,12 +393,19 @@ func AppsOf(guildID int64) (guildInfos []GuildInfo, err error) {
 			GuildInfo{
 				ServerID:         dInfo.ServerID,
 				ChannelID:        dInfo.ChannelID,
-				Appid:            aInfo.Appid,
-				AppName:          aInfo.AppName,
-				AppSaleThreshold: jInfo.SaleThreshold,
+				Appid:            joined.App.Appid,
+				Kind:             joined.App.Kind,
+				AppName:          joined.App.AppName,
+				AppSaleThreshold: joined.SaleThreshold,
 				SaleThreshold:    dInfo.SaleThreshold,
-				TrailingSaleDay:  jInfo.TrailingSaleDay,
-				ComingSoon:       jInfo.ComingSoon,
+				TrailingSaleDay:  joined.TrailingSaleDay,
+				ComingSoon:       joined.ComingSoon,
+				HiddenFields:     dInfo.HiddenFields,
+				MinReviewScore:   dInfo.MinReviewScore,
+				MissingSince:     dInfo.MissingSince,
+				DLCOf:            joined.DLCOf,
+				TrackDLC:         joined.TrackDLC,
+				KnownDLC:         joined.KnownDLC,
 			})
 	}
 	if err := cur.Err(); err != nil {
@@ -255,68 +415,84 @@ func AppsOf(guildID int64) (guildInfos []GuildInfo, err error) {
 	return guildInfos, nil
 }
 
-func Apps() (nextApp func() *int, close func()) {
-	cur, err := appsColl.Find(ctx(), bson.M{})
+// Apps iterates over every tracked item, of every kind. nextApp returns
+// nil once there are no more.
+func Apps(ctx context.Context) (nextApp func() *steam.Item, close func()) {
+	cur, err := appsColl.Find(ctx, bson.M{})
 	if err != nil {
-		return func() *int { return nil }, func() {}
+		return func() *steam.Item { return nil }, func() {}
 	}
 
-	nextApp = func() *int {
-		for cur.Next(ctx()) {
+	nextApp = func() *steam.Item {
+		for cur.Next(ctx) {
 			var rec AppInfo
 			err := cur.Decode(&rec)
 			if err != nil {
 				continue
 			}
-			return &rec.Appid
+			item := itemOf(rec.Kind, rec.Appid)
+			return &item
 		}
 		return nil
 	}
 
 	close = func() {
-		cur.Close(ctx())
+		cur.Close(ctx)
 	}
 
 	return nextApp, close
 }
 
-// GuildsOf finds all guilds tracking the app specified by appid.
-// If appid wasn't added through AddApps(...), guildInfos will be empty.
-func GuildsOf(appid int) (guildInfos []GuildInfo, err error) {
-	cur, err := junctionColl.Find(ctx(), JunctionRecord{Appid: &appid})
+// GuildsOf finds all guilds tracking item.
+// If item wasn't added through AddApps(...), guildInfos will be empty.
+func GuildsOf(ctx context.Context, item steam.Item) (guildInfos []GuildInfo, err error) {
+	// Join each of the app's junctions with its guild in a single query
+	cur, err := junctionColl.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"app_id": item.ID, "kind": item.Kind}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": discordColl.Name(),
+			"let":  bson.M{"server_id": "$server_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{
+					"$eq": bson.A{"$server_id", "$$server_id"},
+				}}}},
+				// A duplicate guild would otherwise duplicate the junction
+				{{Key: "$limit", Value: 1}},
+			},
+			"as": "guild",
+		}}},
+		{{Key: "$unwind", Value: "$guild"}},
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer cur.Close(ctx())
+	defer cur.Close(ctx)
 
-	for cur.Next(ctx()) {
-		var jInfo JunctionInfo
-		if err := cur.Decode(&jInfo); err != nil {
-			continue
+	for cur.Next(ctx) {
+		var joined struct {
+			JunctionInfo `bson:",inline"`
+			Guild        DiscordInfo `bson:"guild"`
 		}
-
-		// Given the guildID from a junction, find more info on the guild in
-		// the Discord collection to be able to initialize every field in the
-		// GuildInfo object
-		findDiscRes := discordColl.FindOne(ctx(), DiscordRecord{ServerID: &jInfo.ServerID})
-		if err := findDiscRes.Err(); err != nil {
-			continue
-		}
-
-		var dInfo DiscordInfo
-		if err := findDiscRes.Decode(&dInfo); err != nil {
+		if err := cur.Decode(&joined); err != nil {
 			continue
 		}
 
 		guildInfos = append(guildInfos,
 			GuildInfo{
-				ServerID:         dInfo.ServerID,
-				ChannelID:        dInfo.ChannelID,
-				Appid:            jInfo.Appid,
-				AppSaleThreshold: jInfo.SaleThreshold,
-				SaleThreshold:    dInfo.SaleThreshold,
-				TrailingSaleDay:  jInfo.TrailingSaleDay,
-				ComingSoon:       jInfo.ComingSoon,
+				ServerID:         joined.Guild.ServerID,
+				ChannelID:        joined.Guild.ChannelID,
+				Appid:            joined.Appid,
+				Kind:             joined.Kind,
+				AppSaleThreshold: joined.SaleThreshold,
+				SaleThreshold:    joined.Guild.SaleThreshold,
+				TrailingSaleDay:  joined.TrailingSaleDay,
+				ComingSoon:       joined.ComingSoon,
+				HiddenFields:     joined.Guild.HiddenFields,
+				MinReviewScore:   joined.Guild.MinReviewScore,
+				MissingSince:     joined.Guild.MissingSince,
+				DLCOf:            joined.DLCOf,
+				TrackDLC:         joined.TrackDLC,
+				KnownDLC:         joined.KnownDLC,
 			},
 		)
 	}
@@ -329,21 +505,38 @@ func GuildsOf(appid int) (guildInfos []GuildInfo, err error) {
 
 // AddApps adds apps under a guild. If guildID hasn't been added through AddGuild(...),
 // adding the apps will still work but they won't be retrievable through AppsOf(...).
-func AddApps(guildID int64, apps []*steam.App) (succ []*steam.App, fail []*steam.App) {
+func AddApps(ctx context.Context, guildID int64, apps []*steam.App) (
+	succ []*steam.App, fail []*steam.App) {
+	return addApps(ctx, guildID, apps, nil)
+}
+
+// AddDLC adds dlc under a guild like AddApps(...), grouped under the game
+// matching appid. DLC that was already under the guild is left as is.
+func AddDLC(ctx context.Context, guildID int64, appid int, dlc []*steam.App) (
+	succ []*steam.App, fail []*steam.App) {
+	return addApps(ctx, guildID, dlc, &appid)
+}
+
+// addApps adds apps under a guild, as DLC of the game matching dlcOf unless
+// it's nil. See AddApps(...).
+func addApps(ctx context.Context, guildID int64, apps []*steam.App, dlcOf *int) (
+	succ []*steam.App, fail []*steam.App) {
 	sess, err := client.StartSession()
 	if err != nil {
 		return nil, apps
 	}
-	defer sess.EndSession(ctx())
+	defer sess.EndSession(ctx)
 
 	// For each app, attempt the transaction
 	// of upserting an App and inserting a Junction
 	for _, app := range apps {
+		item := app.Item()
 		transactionFn := func(ctx mongo.SessionContext) (any, error) {
-			err := upsert(appsColl,
-				AppRecord{Appid: &app.Appid},
+			err := upsert(ctx, appsColl,
+				AppRecord{Appid: &item.ID, Kind: &item.Kind},
 				AppRecord{
-					Appid:   &app.Appid,
+					Appid:   &item.ID,
+					Kind:    &item.Kind,
 					AppName: &app.Name, // Upsertion is done because name may have changed
 				},
 			)
@@ -352,14 +545,16 @@ func AddApps(guildID int64, apps []*steam.App) (succ []*steam.App, fail []*steam
 			}
 
 			trailingSaleDay := false
-			err = insert(junctionColl,
-				JunctionRecord{Appid: &app.Appid, ServerID: &guildID},
+			err = insert(ctx, junctionColl,
+				JunctionRecord{Appid: &item.ID, Kind: &item.Kind, ServerID: &guildID},
 				JunctionRecord{
-					Appid:           &app.Appid,
+					Appid:           &item.ID,
+					Kind:            &item.Kind,
 					ServerID:        &guildID,
 					TrailingSaleDay: &trailingSaleDay,
 					ComingSoon:      &app.ComingSoon,
 					SaleThreshold:   app.SaleThreshold,
+					DLCOf:           dlcOf,
 				},
 			)
 			if err != nil {
@@ -369,7 +564,7 @@ func AddApps(guildID int64, apps []*steam.App) (succ []*steam.App, fail []*steam
 			return nil, nil
 		}
 
-		if _, err := sess.WithTransaction(ctx(), transactionFn); err != nil {
+		if _, err := sess.WithTransaction(ctx, transactionFn); err != nil {
 			fail = append(fail, app)
 		} else {
 			succ = append(succ, app)
@@ -379,35 +574,52 @@ func AddApps(guildID int64, apps []*steam.App) (succ []*steam.App, fail []*steam
 	return succ, fail
 }
 
-// RemoveApps removes apps from a guild. If an appid from appids isn't
+// RemoveApps removes items from a guild. If an item from items isn't
 // actually under this guild, the removal is still considered successful
-// and placed in the succ list.
-func RemoveApps(guildID int64, appids []int) (succ []int, fail []int) {
+// and placed in the succ list. The removed items are snapshotted to the
+// trash first so they can be brought back with RestoreApps(...) using
+// trashID. trashID is primitive.NilObjectID if none of items were
+// under the guild.
+func RemoveApps(ctx context.Context, guildID int64, items []steam.Item) (
+	succ []steam.Item, fail []steam.Item, trashID primitive.ObjectID) {
+	trashID, err := trashApps(ctx, guildID, items)
+	if err != nil {
+		return nil, items, primitive.NilObjectID
+	}
+
+	succ, fail = removeApps(ctx, guildID, items)
+	return succ, fail, trashID
+}
+
+// removeApps is RemoveApps(...) without snapshotting.
+func removeApps(ctx context.Context, guildID int64, items []steam.Item) (
+	succ []steam.Item, fail []steam.Item) {
 	sess, err := client.StartSession()
 	if err != nil {
-		return nil, appids
+		return nil, items
 	}
-	defer sess.EndSession(context.Background())
+	defer sess.EndSession(ctx)
 
-	// For each app, attempt the transaction of
+	// For each item, attempt the transaction of
 	// removing the JunctionRecord and removing the AppRecord if
 	// the JunctionRecord was the last junction referencing it
-	for _, appid := range appids {
+	for _, item := range items {
 		transactionFn := func(ctx mongo.SessionContext) (any, error) {
 			_, err := junctionColl.DeleteOne(ctx,
-				JunctionRecord{Appid: &appid, ServerID: &guildID})
+				JunctionRecord{Appid: &item.ID, Kind: &item.Kind, ServerID: &guildID})
 			if err != nil {
 				return nil, err
 			}
 
-			count, err := junctionColl.CountDocuments(ctx, JunctionRecord{Appid: &appid})
+			count, err := junctionColl.CountDocuments(ctx,
+				JunctionRecord{Appid: &item.ID, Kind: &item.Kind})
 			if err != nil {
 				return nil, err
 			} else if count > 0 { // If not an orphan, no need to remove
 				return nil, nil
 			}
 
-			_, err = appsColl.DeleteOne(ctx, AppRecord{Appid: &appid})
+			_, err = appsColl.DeleteOne(ctx, AppRecord{Appid: &item.ID, Kind: &item.Kind})
 			if err != nil {
 				return nil, err
 			}
@@ -415,10 +627,10 @@ func RemoveApps(guildID int64, appids []int) (succ []int, fail []int) {
 			return nil, nil
 		}
 
-		if _, err := sess.WithTransaction(ctx(), transactionFn); err != nil {
-			fail = append(fail, appid)
+		if _, err := sess.WithTransaction(ctx, transactionFn); err != nil {
+			fail = append(fail, item)
 		} else {
-			succ = append(succ, appid)
+			succ = append(succ, item)
 		}
 	}
 
@@ -426,64 +638,174 @@ func RemoveApps(guildID int64, appids []int) (succ []int, fail []int) {
 }
 
 // ClearApps clears the apps under guildID. Does nothing if there
-// are no apps under the guild.
-func ClearApps(guildID int64) error {
-	cur, err := junctionColl.Find(ctx(), JunctionRecord{ServerID: &guildID})
+// are no apps under the guild. Like RemoveApps(...), the apps can be
+// brought back with RestoreApps(...) using trashID.
+func ClearApps(ctx context.Context, guildID int64) (trashID primitive.ObjectID, err error) {
+	items, err := itemsOf(ctx, guildID)
 	if err != nil {
-		return err
+		return primitive.NilObjectID, err
 	}
-	defer cur.Close(ctx())
 
-	// Extract appids
-	appids := []int{}
-	for cur.Next(ctx()) {
+	_, fail, trashID := RemoveApps(ctx, guildID, items)
+	if len(fail) > 0 {
+		return trashID, errors.New("failed to clear some apps")
+	}
+
+	return trashID, nil
+}
+
+// itemsOf finds the items under guildID.
+func itemsOf(ctx context.Context, guildID int64) ([]steam.Item, error) {
+	cur, err := junctionColl.Find(ctx, JunctionRecord{ServerID: &guildID})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	// Extract items
+	items := []steam.Item{}
+	for cur.Next(ctx) {
 		var rec JunctionInfo
 		if err := cur.Decode(&rec); err != nil {
 			continue
 		}
 
-		appids = append(appids, rec.Appid)
+		items = append(items, itemOf(rec.Kind, rec.Appid))
 	}
 	if err := cur.Err(); err != nil {
-		return err
-	}
-
-	if _, fail := RemoveApps(guildID, appids); len(fail) > 0 {
-		return errors.New("failed to clear some apps")
+		return nil, err
 	}
 
-	return nil
+	return items, nil
 }
 
 // SetChannelID sets the channelID alerts are sent for a guild
-func SetChannelID(guildID, channelID int64) error {
-	return update(discordColl,
+func SetChannelID(ctx context.Context, guildID, channelID int64) error {
+	return update(ctx, discordColl,
 		DiscordRecord{ServerID: &guildID},
 		DiscordRecord{ChannelID: &channelID},
 	)
 }
 
 // SetThreshold sets the sale threshold for alerts sent to a guild
-func SetThreshold(guildID int64, threshold int) error {
-	return update(discordColl,
+func SetThreshold(ctx context.Context, guildID int64, threshold int) error {
+	return update(ctx, discordColl,
 		DiscordRecord{ServerID: &guildID},
 		DiscordRecord{SaleThreshold: &threshold},
 	)
 }
 
+// SetManagerRole sets the role whose members may change a guild's
+// configuration. Pass 0 for roleID to unset it.
+func SetManagerRole(ctx context.Context, guildID, roleID int64) error {
+	return update(ctx, discordColl,
+		DiscordRecord{ServerID: &guildID},
+		DiscordRecord{ManagerRoleID: &roleID},
+	)
+}
+
+// SetAuditChannel sets the channel a guild's audit log is mirrored to.
+// Pass 0 for channelID to stop mirroring.
+func SetAuditChannel(ctx context.Context, guildID, channelID int64) error {
+	return update(ctx, discordColl,
+		DiscordRecord{ServerID: &guildID},
+		DiscordRecord{AuditChannelID: &channelID},
+	)
+}
+
+// SetHiddenFields sets the fields left out of the sale alerts sent to
+// a guild. Pass no fields to show every field.
+func SetHiddenFields(ctx context.Context, guildID int64, fields []string) error {
+	if len(fields) == 0 {
+		_, err := discordColl.UpdateOne(ctx,
+			DiscordRecord{ServerID: &guildID},
+			bson.M{
+				"$unset": bson.M{"hidden_fields": ""},
+			},
+		)
+		return err
+	}
+
+	return update(ctx, discordColl,
+		DiscordRecord{ServerID: &guildID},
+		DiscordRecord{HiddenFields: fields},
+	)
+}
+
+// SetMinReviewScore sets the percent of positive reviews apps need for sale
+// alerts to be sent to a guild. Pass 0 to alert regardless of reviews.
+func SetMinReviewScore(ctx context.Context, guildID int64, score int) error {
+	return update(ctx, discordColl,
+		DiscordRecord{ServerID: &guildID},
+		DiscordRecord{MinReviewScore: &score},
+	)
+}
+
+// SetWishlistSync sets the profile whose wishlist is synced to a guild.
+// seen are the appids already on the wishlist, which won't be added by
+// later syncs. Pass 0 for steamID to stop syncing.
+func SetWishlistSync(ctx context.Context, guildID, steamID int64, seen []int) error {
+	if steamID == 0 {
+		_, err := discordColl.UpdateOne(ctx,
+			DiscordRecord{ServerID: &guildID},
+			bson.M{
+				"$unset": bson.M{"wishlist_steam_id": "", "wishlist_seen": ""},
+			},
+		)
+		return err
+	}
+
+	return update(ctx, discordColl,
+		DiscordRecord{ServerID: &guildID},
+		DiscordRecord{WishlistSteamID: &steamID, WishlistSeen: seen},
+	)
+}
+
+// SetWishlistSeen sets the appids of a guild's synced wishlist as of
+// the latest sync.
+func SetWishlistSeen(ctx context.Context, guildID int64, seen []int) error {
+	return update(ctx, discordColl,
+		DiscordRecord{ServerID: &guildID},
+		DiscordRecord{WishlistSeen: seen},
+	)
+}
+
+// WishlistSyncs finds every guild with a synced wishlist.
+func WishlistSyncs(ctx context.Context) (dInfos []DiscordInfo, err error) {
+	cur, err := discordColl.Find(ctx, bson.M{"wishlist_steam_id": bson.M{"$gt": 0}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var dInfo DiscordInfo
+		if err := cur.Decode(&dInfo); err != nil {
+			continue
+		}
+		dInfos = append(dInfos, dInfo)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return dInfos, nil
+}
+
 // SetThresholds sets the sale threshold for alerts sent to a guild
-// for the specific appids
-func SetThresholds(guildID int64, threshold int, appids []int) (succ []int, fail []int) {
+// for the specific items
+func SetThresholds(ctx context.Context, guildID int64, threshold int, items []steam.Item) (
+	succ []steam.Item, fail []steam.Item) {
 	sess, err := client.StartSession()
 	if err != nil {
-		return nil, appids
+		return nil, items
 	}
-	defer sess.EndSession(ctx())
+	defer sess.EndSession(ctx)
 
-	for _, appid := range appids {
+	for _, item := range items {
 		transactionFn := func(ctx mongo.SessionContext) (any, error) {
-			err := update(junctionColl,
-				JunctionRecord{ServerID: &guildID, Appid: &appid},
+			err := update(ctx, junctionColl,
+				JunctionRecord{ServerID: &guildID, Appid: &item.ID, Kind: &item.Kind},
 				JunctionRecord{SaleThreshold: &threshold})
 			if err != nil {
 				return nil, err
@@ -492,28 +814,44 @@ func SetThresholds(guildID int64, threshold int, appids []int) (succ []int, fail
 			return nil, nil
 		}
 
-		if _, err := sess.WithTransaction(ctx(), transactionFn); err != nil {
-			fail = append(fail, appid)
+		if _, err := sess.WithTransaction(ctx, transactionFn); err != nil {
+			fail = append(fail, item)
 		} else {
-			succ = append(succ, appid)
+			succ = append(succ, item)
 		}
 	}
 
 	return succ, fail
 }
 
-// SetTrailingSaleDay sets the trailing sale day field for an app for a guild
-func SetTrailingSaleDay(guildID int64, appid int, sale bool) error {
-	return update(junctionColl,
-		JunctionRecord{ServerID: &guildID, Appid: &appid},
+// SetTrailingSaleDay sets the trailing sale day field for an item for a guild
+func SetTrailingSaleDay(ctx context.Context, guildID int64, item steam.Item, sale bool) error {
+	return update(ctx, junctionColl,
+		JunctionRecord{ServerID: &guildID, Appid: &item.ID, Kind: &item.Kind},
 		JunctionRecord{TrailingSaleDay: &sale},
 	)
 }
 
-// SetComingSoon sets the coming soon field for an app for a guild
-func SetComingSoon(guildID int64, appid int, comingSoon bool) error {
-	return update(junctionColl,
-		JunctionRecord{ServerID: &guildID, Appid: &appid},
+// SetComingSoon sets the coming soon field for an item for a guild
+func SetComingSoon(ctx context.Context, guildID int64, item steam.Item,
+	comingSoon bool) error {
+	return update(ctx, junctionColl,
+		JunctionRecord{ServerID: &guildID, Appid: &item.ID, Kind: &item.Kind},
 		JunctionRecord{ComingSoon: &comingSoon},
 	)
 }
+
+// SetKnownDLC has the game matching appid add its new DLC under a guild as
+// it's released, where known are the appids of the DLC it has already.
+func SetKnownDLC(ctx context.Context, guildID int64, appid int, known []int) error {
+	// known is set as is, since an empty JunctionRecord.KnownDLC is omitted
+	if known == nil {
+		known = []int{}
+	}
+	kind := steam.KindApp
+	_, err := junctionColl.UpdateOne(ctx,
+		JunctionRecord{ServerID: &guildID, Appid: &appid, Kind: &kind},
+		bson.M{"$set": bson.M{"track_dlc": true, "known_dlc": known}},
+	)
+	return err
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The benchmarks run against a local database given by MONGODB_TEST_URI,
+// e.g., mongodb://localhost:27017/?replicaSet=rs0. A scratch database is
+// seeded and dropped afterward. Without it, the benchmarks are skipped.
+//
+//	MONGODB_TEST_URI=... go test ./internal/db -run ^$ -bench .
+const benchDBName = "steam_sale_bench"
+
+const (
+	benchGuilds       = 200
+	benchApps         = 500
+	benchAppsPerGuild = 100
+	// benchPopularAppid is tracked by every guild
+	benchPopularAppid = 1
+)
+
+var (
+	benchOnce sync.Once
+	benchErr  error
+	benchInit bool
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	if benchInit {
+		client.Database(benchDBName).Drop(context.Background())
+		Close(context.Background())
+	}
+
+	os.Exit(code)
+}
+
+// seedBench connects to and seeds the benchmark database once.
+func seedBench(b *testing.B) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		b.Skip("MONGODB_TEST_URI is not set")
+	}
+
+	benchOnce.Do(func() {
+		ctx := context.Background()
+		Init(ctx, uri, benchDBName)
+		benchInit = true
+		if _, benchErr = Migrate(ctx); benchErr != nil {
+			return
+		}
+
+		// Start from scratch in case a previous run wasn't cleaned up
+		for _, coll := range []*mongo.Collection{appsColl, discordColl, junctionColl} {
+			if _, benchErr = coll.DeleteMany(ctx, bson.M{}); benchErr != nil {
+				return
+			}
+		}
+
+		kind := steam.KindApp
+		apps := make([]any, 0, benchApps)
+		for appid := 1; appid <= benchApps; appid++ {
+			name := fmt.Sprintf("App %d", appid)
+			apps = append(apps, AppRecord{Appid: &appid, Kind: &kind, AppName: &name})
+		}
+		if _, benchErr = appsColl.InsertMany(ctx, apps); benchErr != nil {
+			return
+		}
+
+		guilds := make([]any, 0, benchGuilds)
+		junctions := make([]any, 0, benchGuilds*benchAppsPerGuild)
+		for guildID := int64(1); guildID <= benchGuilds; guildID++ {
+			channelID, threshold := guildID*10, 1
+			guilds = append(guilds, DiscordRecord{
+				ServerID:      &guildID,
+				ChannelID:     &channelID,
+				SaleThreshold: &threshold,
+			})
+
+			for n := 0; n < benchAppsPerGuild; n++ {
+				// The first app is the popular one. The rest are spread
+				// over the other apps without repeats.
+				appid := benchPopularAppid
+				if n > 0 {
+					appid = 2 + (int(guildID)+n*7)%(benchApps-1)
+				}
+				junctions = append(junctions,
+					JunctionRecord{ServerID: &guildID, Appid: &appid, Kind: &kind})
+			}
+		}
+		if _, benchErr = discordColl.InsertMany(ctx, guilds); benchErr != nil {
+			return
+		}
+		_, benchErr = junctionColl.InsertMany(ctx, junctions)
+	})
+
+	if benchErr != nil {
+		b.Fatal("Failed to seed benchmark database:", benchErr)
+	}
+}
+
+func BenchmarkAppsOf(b *testing.B) {
+	seedBench(b)
+	ctx := context.Background()
+	guildID := int64(benchGuilds / 2)
+
+	b.Run("lookup", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := AppsOf(ctx, guildID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("per-junction", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := appsOfPerJunction(ctx, guildID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGuildsOf(b *testing.B) {
+	seedBench(b)
+	ctx := context.Background()
+
+	b.Run("lookup", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := GuildsOf(ctx, steam.AppItem(benchPopularAppid)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("per-junction", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := guildsOfPerJunction(ctx, benchPopularAppid); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// appsOfPerJunction is AppsOf as it was before using $lookup, with a
+// query per junction. It's kept as the baseline of BenchmarkAppsOf.
+func appsOfPerJunction(ctx context.Context, guildID int64) (guildInfos []GuildInfo, err error) {
+	var dInfo DiscordInfo
+	err = discordColl.FindOne(ctx, DiscordRecord{ServerID: &guildID}).Decode(&dInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := junctionColl.Find(ctx, JunctionRecord{ServerID: &guildID})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var jInfo JunctionInfo
+		if err := cur.Decode(&jInfo); err != nil {
+			continue
+		}
+
+		var aInfo AppInfo
+		err := appsColl.FindOne(ctx, AppRecord{Appid: &jInfo.Appid}).Decode(&aInfo)
+		if err != nil {
+			continue
+		}
+
+		guildInfos = append(guildInfos, GuildInfo{
+			ServerID: dInfo.ServerID,
+			Appid:    aInfo.Appid,
+			AppName:  aInfo.AppName,
+		})
+	}
+
+	return guildInfos, cur.Err()
+}
+
+// guildsOfPerJunction is GuildsOf as it was before using $lookup, with a
+// query per junction. It's kept as the baseline of BenchmarkGuildsOf.
+func guildsOfPerJunction(ctx context.Context, appid int) (guildInfos []GuildInfo, err error) {
+	cur, err := junctionColl.Find(ctx, JunctionRecord{Appid: &appid})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var jInfo JunctionInfo
+		if err := cur.Decode(&jInfo); err != nil {
+			continue
+		}
+
+		var dInfo DiscordInfo
+		err := discordColl.FindOne(ctx, DiscordRecord{ServerID: &jInfo.ServerID}).Decode(&dInfo)
+		if err != nil {
+			continue
+		}
+
+		guildInfos = append(guildInfos, GuildInfo{
+			ServerID:  dInfo.ServerID,
+			ChannelID: dInfo.ChannelID,
+			Appid:     jInfo.Appid,
+		})
+	}
+
+	return guildInfos, cur.Err()
+}
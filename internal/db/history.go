@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HistoryRecord is an alert that was sent, or tried to be sent, to a guild.
+// Alerts that went through the outbox share their ID with it.
+type HistoryRecord struct {
+	ID        *primitive.ObjectID `bson:"_id,omitempty"`
+	ServerID  *int64              `bson:"server_id,omitempty"`
+	ChannelID *int64              `bson:"channel_id,omitempty"`
+	Appid     *int                `bson:"app_id,omitempty"`
+	ItemKind  *steam.ItemKind     `bson:"item_kind,omitempty"` // Unset for apps
+	AppName   *string             `bson:"app_name,omitempty"`
+	Kind      *AlertKind          `bson:"kind,omitempty"`
+	Discount  *int                `bson:"discount,omitempty"`
+	Price     *string             `bson:"price,omitempty"`
+	State     *AlertState         `bson:"state,omitempty"`
+	SentAt    *time.Time          `bson:"sent_at,omitempty"`
+}
+
+type HistoryInfo struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	ServerID  int64              `bson:"server_id"`
+	ChannelID int64              `bson:"channel_id"`
+	Appid     int                `bson:"app_id"`
+	ItemKind  steam.ItemKind     `bson:"item_kind,omitempty"` // Empty for apps
+	AppName   string             `bson:"app_name"`
+	Kind      AlertKind          `bson:"kind"`
+	Discount  int                `bson:"discount"`
+	Price     string             `bson:"price"`
+	State     AlertState         `bson:"state"`
+	SentAt    time.Time          `bson:"sent_at"`
+}
+
+// Item references the app the alert was about.
+func (h HistoryInfo) Item() steam.Item {
+	return itemOf(h.ItemKind, h.Appid)
+}
+
+// HistoryFilter narrows down the alerts History finds.
+type HistoryFilter struct {
+	Appid int       // 0 for every app, packages and bundles included
+	Since time.Time // Zero for every alert
+}
+
+// RecordHistory adds an alert to the history.
+func RecordHistory(ctx context.Context, info HistoryInfo) error {
+	var itemKind *steam.ItemKind
+	if info.ItemKind != "" && info.ItemKind != steam.KindApp {
+		itemKind = &info.ItemKind
+	}
+
+	_, err := historyColl.InsertOne(ctx, HistoryRecord{
+		ID:        &info.ID,
+		ServerID:  &info.ServerID,
+		ChannelID: &info.ChannelID,
+		Appid:     &info.Appid,
+		ItemKind:  itemKind,
+		AppName:   &info.AppName,
+		Kind:      &info.Kind,
+		Discount:  &info.Discount,
+		Price:     &info.Price,
+		State:     &info.State,
+		SentAt:    &info.SentAt,
+	})
+	return err
+}
+
+// setHistoryState moves the alert in the history matching id to state.
+func setHistoryState(ctx context.Context, id primitive.ObjectID, state AlertState) error {
+	_, err := historyColl.UpdateOne(ctx,
+		HistoryRecord{ID: &id},
+		bson.M{"$set": HistoryRecord{State: &state}},
+	)
+	return err
+}
+
+// History finds a page of a guild's alert history matching filter, newest
+// first. total is the number of alerts matching filter across every page.
+func History(ctx context.Context, guildID int64, filter HistoryFilter, skip, limit int) (
+	alerts []HistoryInfo, total int64, err error) {
+	query := bson.M{"server_id": guildID}
+	if filter.Appid != 0 {
+		query["app_id"] = filter.Appid
+		query["item_kind"] = bson.M{"$exists": false}
+	}
+	if !filter.Since.IsZero() {
+		query["sent_at"] = bson.M{"$gte": filter.Since}
+	}
+
+	total, err = historyColl.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cur, err := historyColl.Find(ctx, query,
+		options.Find().
+			SetSort(bson.D{{Key: "sent_at", Value: -1}}).
+			SetSkip(int64(skip)).
+			SetLimit(int64(limit)))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var info HistoryInfo
+		if err := cur.Decode(&info); err != nil {
+			continue
+		}
+		alerts = append(alerts, info)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return alerts, total, nil
+}
+
+// LowestAlerted finds the sent sale alert with the deepest discount on the
+// app matching appid, across every guild. The latest is found if there's a
+// tie. ok is false if no sale alert was ever sent for the app. Packages and
+// bundles sharing the appid aren't considered.
+func LowestAlerted(ctx context.Context, appid int) (low HistoryInfo, ok bool, err error) {
+	err = historyColl.FindOne(ctx,
+		bson.M{
+			"app_id":    appid,
+			"item_kind": bson.M{"$exists": false},
+			"kind":      AlertSale,
+			"state":     AlertSent,
+		},
+		options.FindOne().SetSort(bson.D{
+			{Key: "discount", Value: -1},
+			{Key: "sent_at", Value: -1},
+		}),
+	).Decode(&low)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return HistoryInfo{}, false, nil
+	}
+	if err != nil {
+		return HistoryInfo{}, false, err
+	}
+
+	return low, true, nil
+}
@@ -0,0 +1,371 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migration is a versioned change to the schema or data of the database.
+// up must be idempotent, since a migration that fails partway is retried
+// from the start.
+type migration struct {
+	version     int
+	description string
+	up          func(ctx context.Context) error
+}
+
+// migrations are every migration in the order they are applied. New
+// migrations are appended with the next version, and existing ones are
+// never changed once released.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "Remove duplicate apps, guilds, and junctions",
+		up:          removeDuplicates,
+	},
+	{
+		version:     2,
+		description: "Create unique indexes on apps, guilds, and junctions",
+		up:          createUniqueIndexes,
+	},
+	{
+		version:     3,
+		description: "Backfill missing guild and junction fields",
+		up:          backfillFields,
+	},
+	{
+		version:     4,
+		description: "Index history by app for the lowest alerted discounts",
+		up:          createLowestAlertedIndex,
+	},
+	{
+		version:     5,
+		description: "Key apps and junctions by item kind",
+		up:          keyByItemKind,
+	},
+}
+
+type MigrationRecord struct {
+	Version     *int       `bson:"version,omitempty"`
+	Description *string    `bson:"description,omitempty"`
+	AppliedAt   *time.Time `bson:"applied_at,omitempty"`
+}
+
+type MigrationInfo struct {
+	Version     int       `bson:"version"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Migrate applies the migrations that haven't been applied to the database
+// yet, in order. Each one is recorded in the migrations collection once it
+// succeeds. Applying stops at the first failure. applied are the migrations
+// that succeeded.
+func Migrate(ctx context.Context) (applied []MigrationInfo, err error) {
+	done, err := appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range migrations {
+		if done[m.version] {
+			continue
+		}
+
+		if err := m.up(ctx); err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.version, m.description, err)
+		}
+
+		info := MigrationInfo{
+			Version:     m.version,
+			Description: m.description,
+			AppliedAt:   time.Now(),
+		}
+		_, err := migrationsColl.UpdateOne(ctx,
+			MigrationRecord{Version: &info.Version},
+			bson.M{"$setOnInsert": MigrationRecord{
+				Version:     &info.Version,
+				Description: &info.Description,
+				AppliedAt:   &info.AppliedAt,
+			}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return applied, fmt.Errorf("recording migration %d: %w", m.version, err)
+		}
+
+		log.Printf("Applied migration %d: %s", m.version, m.description)
+		applied = append(applied, info)
+	}
+
+	return applied, nil
+}
+
+// appliedMigrations finds the versions of the migrations that have been
+// applied to the database.
+func appliedMigrations(ctx context.Context) (map[int]bool, error) {
+	cur, err := migrationsColl.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	done := map[int]bool{}
+	for cur.Next(ctx) {
+		var info MigrationInfo
+		if err := cur.Decode(&info); err != nil {
+			return nil, err
+		}
+		done[info.Version] = true
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return done, nil
+}
+
+// removeDuplicates keeps only the oldest document of each app, guild, and
+// junction that has duplicates, which concurrent upserts could create
+// before unique indexes existed.
+func removeDuplicates(ctx context.Context) error {
+	for _, dup := range []struct {
+		coll *mongo.Collection
+		keys []string
+	}{
+		{appsColl, []string{"app_id"}},
+		{discordColl, []string{"server_id"}},
+		{junctionColl, []string{"app_id", "server_id"}},
+	} {
+		group := bson.M{}
+		for _, key := range dup.keys {
+			group[key] = "$" + key
+		}
+
+		cur, err := dup.coll.Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$sort", Value: bson.M{"_id": 1}}},
+			{{Key: "$group", Value: bson.M{
+				"_id":   group,
+				"ids":   bson.M{"$push": "$_id"},
+				"count": bson.M{"$sum": 1},
+			}}},
+			{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		})
+		if err != nil {
+			return err
+		}
+
+		var groups []struct {
+			IDs []any `bson:"ids"`
+		}
+		if err := cur.All(ctx, &groups); err != nil {
+			return err
+		}
+
+		for _, g := range groups {
+			_, err := dup.coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": g.IDs[1:]}})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// createUniqueIndexes creates the unique indexes that stop concurrent
+// upserts from creating duplicates. Non-unique indexes on the same keys
+// are replaced.
+func createUniqueIndexes(ctx context.Context) error {
+	for _, idx := range []struct {
+		coll *mongo.Collection
+		keys bson.D
+	}{
+		{appsColl, bson.D{{Key: "app_id", Value: 1}}},
+		{discordColl, bson.D{{Key: "server_id", Value: 1}}},
+		{junctionColl, bson.D{{Key: "app_id", Value: 1}, {Key: "server_id", Value: 1}}},
+	} {
+		if err := dropNonUniqueIndex(ctx, idx.coll, idx.keys); err != nil {
+			return err
+		}
+
+		_, err := idx.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    idx.keys,
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The unique junction index covers looking up junctions by app, so
+	// a separate index for it is redundant. Looking up by guild still
+	// needs its own.
+	if err := dropNonUniqueIndex(ctx, junctionColl, bson.D{{Key: "app_id", Value: 1}}); err != nil {
+		return err
+	}
+	_, err := junctionColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "server_id", Value: 1}},
+	})
+	return err
+}
+
+// dropNonUniqueIndex drops the index of coll on keys if it exists and
+// isn't unique. Only the key names are compared, not their directions.
+func dropNonUniqueIndex(ctx context.Context, coll *mongo.Collection, keys bson.D) error {
+	spec, err := indexOn(ctx, coll, keys)
+	if err != nil || spec == nil {
+		return err
+	}
+	if spec.Unique != nil && *spec.Unique {
+		return nil
+	}
+
+	return dropOne(ctx, coll, spec.Name)
+}
+
+// indexOn finds the index of coll on keys, or nil if there is none. Only
+// the key names are compared, not their directions.
+func indexOn(ctx context.Context, coll *mongo.Collection, keys bson.D) (
+	*mongo.IndexSpecification, error) {
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	want := make([]string, 0, len(keys))
+	for _, key := range keys {
+		want = append(want, key.Key)
+	}
+
+	for _, spec := range specs {
+		elems, err := spec.KeysDocument.Elements()
+		if err != nil {
+			return nil, err
+		}
+		have := make([]string, 0, len(elems))
+		for _, elem := range elems {
+			have = append(have, elem.Key())
+		}
+		if slices.Equal(want, have) {
+			return spec, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// dropOne drops the index of coll matching name. It's not an error if
+// the index was already dropped.
+func dropOne(ctx context.Context, coll *mongo.Collection, name string) error {
+	_, err := coll.Indexes().DropOne(ctx, name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
+
+// backfillFields sets fields that older documents are missing to the
+// values new documents start with.
+func backfillFields(ctx context.Context) error {
+	for _, fill := range []struct {
+		coll  *mongo.Collection
+		field string
+		value any
+	}{
+		{discordColl, "channel_id", int64(0)},
+		{discordColl, "sale_threshold", 1},
+		{junctionColl, "is_trailing_sale_day", false},
+		{junctionColl, "coming_soon", false},
+	} {
+		_, err := fill.coll.UpdateMany(ctx,
+			bson.M{fill.field: bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{fill.field: fill.value}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createLowestAlertedIndex creates the index LowestAlerted() looks up
+// alerts by.
+func createLowestAlertedIndex(ctx context.Context) error {
+	_, err := historyColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "app_id", Value: 1},
+			{Key: "kind", Value: 1},
+			{Key: "discount", Value: -1},
+			{Key: "sent_at", Value: -1},
+		},
+	})
+	return err
+}
+
+// keyByItemKind marks every app and junction as an app, since only apps
+// could be tracked before packages and bundles, and makes the unique
+// indexes on them unique per item kind instead.
+func keyByItemKind(ctx context.Context) error {
+	for _, coll := range []*mongo.Collection{appsColl, junctionColl} {
+		_, err := coll.UpdateMany(ctx,
+			bson.M{"kind": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"kind": steam.KindApp}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, idx := range []struct {
+		coll      *mongo.Collection
+		old, keys bson.D
+	}{
+		{
+			appsColl,
+			bson.D{{Key: "app_id", Value: 1}},
+			bson.D{{Key: "app_id", Value: 1}, {Key: "kind", Value: 1}},
+		},
+		{
+			junctionColl,
+			bson.D{{Key: "app_id", Value: 1}, {Key: "server_id", Value: 1}},
+			bson.D{{Key: "app_id", Value: 1}, {Key: "kind", Value: 1}, {Key: "server_id", Value: 1}},
+		},
+	} {
+		if err := dropIndex(ctx, idx.coll, idx.old); err != nil {
+			return err
+		}
+
+		_, err := idx.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    idx.keys,
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropIndex drops the index of coll on keys if it exists, unique or not.
+// Only the key names are compared, not their directions.
+func dropIndex(ctx context.Context, coll *mongo.Collection, keys bson.D) error {
+	spec, err := indexOn(ctx, coll, keys)
+	if err != nil || spec == nil {
+		return err
+	}
+
+	return dropOne(ctx, coll, spec.Name)
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AlertState is the delivery state of an alert in the outbox.
+type AlertState string
+
+const (
+	// AlertPending alerts have been queued but not delivered yet.
+	AlertPending AlertState = "pending"
+	// AlertSent alerts have been delivered to their channel.
+	AlertSent AlertState = "sent"
+	// AlertDead alerts failed permanently or ran out of attempts.
+	// They make up the dead-letter list.
+	AlertDead AlertState = "dead"
+)
+
+// AlertKind is the kind of alert in the outbox.
+type AlertKind string
+
+const (
+	AlertSale    AlertKind = "sale"
+	AlertRelease AlertKind = "release"
+	AlertDLC     AlertKind = "dlc"
+)
+
+type OutboxRecord struct {
+	ID        *primitive.ObjectID     `bson:"_id,omitempty"`
+	ServerID  *int64                  `bson:"server_id,omitempty"`
+	ChannelID *int64                  `bson:"channel_id,omitempty"`
+	Appid     *int                    `bson:"app_id,omitempty"`
+	Kind      *AlertKind              `bson:"kind,omitempty"`
+	Embed     *discordgo.MessageEmbed `bson:"embed,omitempty"`
+	State     *AlertState             `bson:"state,omitempty"`
+	CreatedAt *time.Time              `bson:"created_at,omitempty"`
+}
+
+type OutboxInfo struct {
+	ID        primitive.ObjectID      `bson:"_id"`
+	ServerID  int64                   `bson:"server_id"`
+	ChannelID int64                   `bson:"channel_id"`
+	Appid     int                     `bson:"app_id"`
+	Kind      AlertKind               `bson:"kind"`
+	Embed     *discordgo.MessageEmbed `bson:"embed"`
+	State     AlertState              `bson:"state"`
+	CreatedAt time.Time               `bson:"created_at"`
+	Attempts  []DeliveryAttempt       `bson:"attempts"`
+}
+
+// DeliveryAttempt is a single try at sending an alert. Error is empty
+// if the attempt succeeded.
+type DeliveryAttempt struct {
+	Time  time.Time `bson:"time"`
+	Error string    `bson:"error,omitempty"`
+}
+
+// QueueAlert writes a pending alert to the outbox before it is sent.
+// The returned OutboxInfo should be used with RecordAttempt(...) as
+// delivery is attempted.
+func QueueAlert(ctx context.Context, guildID, channelID int64, appid int, kind AlertKind,
+	embed *discordgo.MessageEmbed) (OutboxInfo, error) {
+	info := OutboxInfo{
+		ID:        primitive.NewObjectID(),
+		ServerID:  guildID,
+		ChannelID: channelID,
+		Appid:     appid,
+		Kind:      kind,
+		Embed:     embed,
+		State:     AlertPending,
+		CreatedAt: time.Now(),
+	}
+
+	_, err := outboxColl.InsertOne(ctx, OutboxRecord{
+		ID:        &info.ID,
+		ServerID:  &info.ServerID,
+		ChannelID: &info.ChannelID,
+		Appid:     &info.Appid,
+		Kind:      &info.Kind,
+		Embed:     info.Embed,
+		State:     &info.State,
+		CreatedAt: &info.CreatedAt,
+	})
+	if err != nil {
+		return OutboxInfo{}, err
+	}
+
+	return info, nil
+}
+
+// RecordAttempt appends a delivery attempt to an alert in the outbox
+// and moves the alert to state. The alert's history is moved to state too.
+func RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt DeliveryAttempt,
+	state AlertState) error {
+	_, err := outboxColl.UpdateOne(ctx,
+		OutboxRecord{ID: &id},
+		bson.M{
+			"$set":  OutboxRecord{State: &state},
+			"$push": bson.M{"attempts": attempt},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return setHistoryState(ctx, id, state)
+}
+
+// PendingAlerts finds all alerts in the outbox that haven't been
+// delivered or dead-lettered yet, oldest first.
+func PendingAlerts(ctx context.Context) ([]OutboxInfo, error) {
+	state := AlertPending
+	return findAlerts(ctx, OutboxRecord{State: &state})
+}
+
+// DeadAlerts finds the dead-lettered alerts of a guild, oldest first.
+func DeadAlerts(ctx context.Context, guildID int64) ([]OutboxInfo, error) {
+	state := AlertDead
+	return findAlerts(ctx, OutboxRecord{ServerID: &guildID, State: &state})
+}
+
+func findAlerts(ctx context.Context, filter OutboxRecord) (alerts []OutboxInfo, err error) {
+	cur, err := outboxColl.Find(ctx, filter,
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var info OutboxInfo
+		if err := cur.Decode(&info); err != nil {
+			continue
+		}
+		alerts = append(alerts, info)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TrashTTL is how long removed apps can be restored for.
+const TrashTTL = 7 * 24 * time.Hour
+
+// ErrNothingToRestore is returned when there is no snapshot to restore.
+var ErrNothingToRestore = errors.New("nothing to restore")
+
+type TrashRecord struct {
+	ID        *primitive.ObjectID `bson:"_id,omitempty"`
+	ServerID  *int64              `bson:"server_id,omitempty"`
+	DeletedAt *time.Time          `bson:"deleted_at,omitempty"`
+	Apps      []TrashedApp        `bson:"apps,omitempty"`
+}
+
+type TrashInfo struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	ServerID  int64              `bson:"server_id"`
+	DeletedAt time.Time          `bson:"deleted_at"`
+	Apps      []TrashedApp       `bson:"apps"`
+}
+
+// TrashedApp is a snapshot of an item's junction with a guild.
+type TrashedApp struct {
+	Appid           int            `bson:"app_id"`
+	Kind            steam.ItemKind `bson:"kind"`
+	AppName         string         `bson:"app_name"`
+	SaleThreshold   int            `bson:"sale_threshold"`
+	TrailingSaleDay bool           `bson:"is_trailing_sale_day"`
+	ComingSoon      bool           `bson:"coming_soon"`
+	DLCOf           int            `bson:"dlc_of,omitempty"`
+	TrackDLC        bool           `bson:"track_dlc,omitempty"`
+	KnownDLC        []int          `bson:"known_dlc,omitempty"`
+}
+
+// Item references the item of a.
+func (a TrashedApp) Item() steam.Item {
+	return itemOf(a.Kind, a.Appid)
+}
+
+// trashApps snapshots items under guildID into the trash.
+// Returns primitive.NilObjectID if none of items are under the guild.
+func trashApps(ctx context.Context, guildID int64, items []steam.Item) (
+	primitive.ObjectID, error) {
+	guildInfos, err := AppsOf(ctx, guildID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	apps := []TrashedApp{}
+	for _, info := range guildInfos {
+		if !slices.Contains(items, info.Item()) {
+			continue
+		}
+
+		apps = append(apps, TrashedApp{
+			Appid:           info.Appid,
+			Kind:            info.Item().Kind,
+			AppName:         info.AppName,
+			SaleThreshold:   info.AppSaleThreshold,
+			TrailingSaleDay: info.TrailingSaleDay,
+			ComingSoon:      info.ComingSoon,
+			DLCOf:           info.DLCOf,
+			TrackDLC:        info.TrackDLC,
+			KnownDLC:        info.KnownDLC,
+		})
+	}
+	if len(apps) == 0 {
+		return primitive.NilObjectID, nil
+	}
+
+	id := primitive.NewObjectID()
+	deletedAt := time.Now()
+	_, err = trashColl.InsertOne(ctx, TrashRecord{
+		ID:        &id,
+		ServerID:  &guildID,
+		DeletedAt: &deletedAt,
+		Apps:      apps,
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return id, nil
+}
+
+// LatestTrash finds the most recent snapshot of removed apps of a guild
+// that can still be restored. If there is none, ErrNothingToRestore
+// is returned.
+func LatestTrash(ctx context.Context, guildID int64) (TrashInfo, error) {
+	var info TrashInfo
+	err := trashColl.FindOne(ctx,
+		bson.M{
+			"server_id":  guildID,
+			"deleted_at": bson.M{"$gt": time.Now().Add(-TrashTTL)},
+		},
+		options.FindOne().SetSort(bson.M{"deleted_at": -1}),
+	).Decode(&info)
+	if err == mongo.ErrNoDocuments {
+		return TrashInfo{}, ErrNothingToRestore
+	}
+
+	return info, err
+}
+
+// RestoreApps brings back the apps of the snapshot matching trashID under
+// guildID, along with their thresholds. Apps that have since been re-added
+// are left as is. The snapshot is removed once restored. If the snapshot
+// doesn't exist or has expired, ErrNothingToRestore is returned.
+func RestoreApps(ctx context.Context, guildID int64, trashID primitive.ObjectID) (
+	[]TrashedApp, error) {
+	var trash TrashInfo
+	err := trashColl.FindOne(ctx,
+		bson.M{
+			"_id":        trashID,
+			"server_id":  guildID,
+			"deleted_at": bson.M{"$gt": time.Now().Add(-TrashTTL)},
+		},
+	).Decode(&trash)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNothingToRestore
+	} else if err != nil {
+		return nil, err
+	}
+
+	sess, err := client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer sess.EndSession(ctx)
+
+	// Attempt the transaction of upserting every App, inserting every
+	// Junction, and removing the snapshot
+	transactionFn := func(ctx mongo.SessionContext) (any, error) {
+		for _, app := range trash.Apps {
+			item := app.Item()
+			err := upsert(ctx, appsColl,
+				AppRecord{Appid: &item.ID, Kind: &item.Kind},
+				AppRecord{
+					Appid:   &item.ID,
+					Kind:    &item.Kind,
+					AppName: &app.AppName,
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			var saleThreshold, dlcOf *int
+			if app.SaleThreshold != 0 {
+				saleThreshold = &app.SaleThreshold
+			}
+			if app.DLCOf != 0 {
+				dlcOf = &app.DLCOf
+			}
+			var trackDLC *bool
+			if app.TrackDLC {
+				trackDLC = &app.TrackDLC
+			}
+			err = insert(ctx, junctionColl,
+				JunctionRecord{Appid: &item.ID, Kind: &item.Kind, ServerID: &guildID},
+				JunctionRecord{
+					Appid:           &item.ID,
+					Kind:            &item.Kind,
+					ServerID:        &guildID,
+					TrailingSaleDay: &app.TrailingSaleDay,
+					ComingSoon:      &app.ComingSoon,
+					SaleThreshold:   saleThreshold,
+					DLCOf:           dlcOf,
+					TrackDLC:        trackDLC,
+					KnownDLC:        app.KnownDLC,
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err := trashColl.DeleteOne(ctx, TrashRecord{ID: &trashID})
+		return nil, err
+	}
+
+	if _, err := sess.WithTransaction(ctx, transactionFn); err != nil {
+		return nil, err
+	}
+
+	return trash.Apps, nil
+}
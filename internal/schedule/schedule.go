@@ -0,0 +1,107 @@
+// schedule provides the daily times sale checks run at.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	// Embedded so time zones load even when the host has no tzdata.
+	_ "time/tzdata"
+)
+
+// Schedule is a set of times of day in a time zone.
+type Schedule struct {
+	times []timeOfDay
+	loc   *time.Location
+}
+
+// timeOfDay is an hour and minute of a day.
+type timeOfDay struct {
+	hour, min int
+}
+
+// Default is the schedule used when none is configured,
+// daily at 10:05 AM America/Los_Angeles.
+func Default() Schedule {
+	s, err := Parse("10:05", "America/Los_Angeles")
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Parse creates a Schedule from comma separated 24-hour times, e.g.,
+// "10:05,22:05", and an IANA time zone name, e.g., "America/Los_Angeles".
+func Parse(times, tz string) (Schedule, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid time zone %q: %w", tz, err)
+	}
+
+	s := Schedule{loc: loc}
+	for _, str := range strings.Split(times, ",") {
+		str = strings.TrimSpace(str)
+
+		t, err := time.Parse("15:04", str)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid time %q, expected HH:MM", str)
+		}
+
+		tod := timeOfDay{hour: t.Hour(), min: t.Minute()}
+		if !slices.Contains(s.times, tod) {
+			s.times = append(s.times, tod)
+		}
+	}
+	if len(s.times) == 0 {
+		return Schedule{}, errors.New("no times given")
+	}
+
+	slices.SortFunc(s.times, func(a, b timeOfDay) int {
+		return (a.hour*60 + a.min) - (b.hour*60 + b.min)
+	})
+
+	return s, nil
+}
+
+// Next finds the first time in the schedule strictly after t.
+func (s Schedule) Next(t time.Time) time.Time {
+	local := t.In(s.loc)
+
+	// Days are stepped by date rather than by 24 hours
+	// so that DST transitions don't shift the time of day.
+	for day := 0; ; day++ {
+		y, m, d := local.AddDate(0, 0, day).Date()
+		for _, tod := range s.times {
+			next := time.Date(y, m, d, tod.hour, tod.min, 0, 0, s.loc)
+			if next.After(t) {
+				return next
+			}
+		}
+	}
+}
+
+// String describes the schedule, e.g., "10:05 AM and 10:05 PM (PDT)".
+// The zone abbreviation is the one currently in effect.
+func (s Schedule) String() string {
+	strs := make([]string, 0, len(s.times))
+	for _, tod := range s.times {
+		t := time.Date(2000, 1, 1, tod.hour, tod.min, 0, 0, time.UTC)
+		strs = append(strs, t.Format("3:04 PM"))
+	}
+
+	var times string
+	switch len(strs) {
+	case 1:
+		times = strs[0]
+	case 2:
+		times = strs[0] + " and " + strs[1]
+	default:
+		times = strings.Join(strs[:len(strs)-1], ", ") + ", and " + strs[len(strs)-1]
+	}
+
+	zone, _ := time.Now().In(s.loc).Zone()
+	return fmt.Sprintf("%s (%s)", times, zone)
+}
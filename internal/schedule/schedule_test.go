@@ -0,0 +1,79 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type scheduleShould struct {
+	suite.Suite
+	loc *time.Location
+}
+
+func (s *scheduleShould) SetupTest() {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	s.Require().NoError(err)
+	s.loc = loc
+}
+
+func TestScheduleShould(t *testing.T) {
+	suite.Run(t, new(scheduleShould))
+}
+
+func (s *scheduleShould) TestErrOnInvalidTime() {
+	_, err := Parse("25:00", "UTC")
+
+	s.Error(err)
+}
+
+func (s *scheduleShould) TestErrOnInvalidTimeZone() {
+	_, err := Parse("10:05", "Not/AZone")
+
+	s.Error(err)
+}
+
+func (s *scheduleShould) TestNextIsLaterToday() {
+	sched, err := Parse("10:05", s.loc.String())
+	s.Require().NoError(err)
+
+	next := sched.Next(time.Date(2024, 6, 1, 9, 0, 0, 0, s.loc))
+
+	s.Equal(time.Date(2024, 6, 1, 10, 5, 0, 0, s.loc), next)
+}
+
+func (s *scheduleShould) TestNextIsTomorrowWhenPassed() {
+	sched, err := Parse("10:05", s.loc.String())
+	s.Require().NoError(err)
+
+	next := sched.Next(time.Date(2024, 6, 1, 10, 5, 0, 0, s.loc))
+
+	s.Equal(time.Date(2024, 6, 2, 10, 5, 0, 0, s.loc), next)
+}
+
+func (s *scheduleShould) TestNextPicksEarliestOfMultipleTimes() {
+	sched, err := Parse("22:05, 10:05", s.loc.String())
+	s.Require().NoError(err)
+
+	next := sched.Next(time.Date(2024, 6, 1, 12, 0, 0, 0, s.loc))
+
+	s.Equal(time.Date(2024, 6, 1, 22, 5, 0, 0, s.loc), next)
+}
+
+func (s *scheduleShould) TestNextKeepsTimeOfDayAcrossDST() {
+	sched, err := Parse("10:05", s.loc.String())
+	s.Require().NoError(err)
+
+	// DST starts 2024-03-10 in America/Los_Angeles
+	next := sched.Next(time.Date(2024, 3, 9, 11, 0, 0, 0, s.loc))
+
+	s.Equal(time.Date(2024, 3, 10, 10, 5, 0, 0, s.loc), next)
+}
+
+func (s *scheduleShould) TestStringListsTimesInOrder() {
+	sched, err := Parse("22:05,10:05,13:30", "UTC")
+	s.Require().NoError(err)
+
+	s.Equal("10:05 AM, 1:30 PM, and 10:05 PM (UTC)", sched.String())
+}
@@ -0,0 +1,136 @@
+package steam
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FixtureMode is how requests to the Steam API are served.
+type FixtureMode int
+
+const (
+	// Live sends requests to Steam.
+	Live FixtureMode = iota
+	// Record sends requests to Steam and saves the bodies of successful
+	// responses as fixtures.
+	Record
+	// Replay serves responses from saved fixtures without sending requests.
+	Replay
+)
+
+// ErrNoFixture is the error of a replayed request that has no fixture.
+var ErrNoFixture = errors.New("no fixture saved for request")
+
+// UseFixtures makes requests to the Steam API be served according to
+// mode, with fixtures kept in dir. Live restores the default client.
+//
+// Fixtures are keyed by endpoint and params, not by base URL, so they can
+// be recorded from any server the base URLs point at.
+func UseFixtures(mode FixtureMode, dir string) {
+	switch mode {
+	case Record:
+		client = &recorder{client: newClient(), dir: dir}
+	case Replay:
+		client = &replayer{dir: dir}
+	default:
+		client = newClient()
+	}
+}
+
+// fixtureUnsafe matches characters left out of fixture names.
+var fixtureUnsafe = regexp.MustCompile(`[^\w.,=-]+`)
+
+// FixtureName names the fixture of a request to u by its endpoint and
+// sorted params, e.g., api_appdetails-appids=400-cc=US.json
+func FixtureName(u *url.URL) string {
+	name := strings.Trim(u.Path, "/")
+
+	params := u.Query()
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		name += "-" + k + "=" + strings.Join(params[k], ",")
+	}
+
+	return fixtureUnsafe.ReplaceAllString(name, "_") + ".json"
+}
+
+// recorder is an httpClient that saves the bodies of successful responses
+// to dir.
+type recorder struct {
+	client httpClient
+	dir    string
+}
+
+func (r *recorder) Do(req *http.Request) (*http.Response, error) {
+	resp, err := r.client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Indented JSON keeps changes to fixtures reviewable
+	saved := body
+	var indented bytes.Buffer
+	if json.Indent(&indented, body, "", "  ") == nil {
+		saved = append(indented.Bytes(), '\n')
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(r.dir, FixtureName(req.URL))
+	if err := os.WriteFile(path, saved, 0o644); err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// replayer is an httpClient that serves the fixtures in dir.
+type replayer struct {
+	dir string
+}
+
+func (r *replayer) Do(req *http.Request) (*http.Response, error) {
+	name := FixtureName(req.URL)
+	body, err := os.ReadFile(filepath.Join(r.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoFixture, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// newClient creates the client used for live requests to the Steam API.
+func newClient() httpClient {
+	return &http.Client{Timeout: 10 * time.Second}
+}
@@ -0,0 +1,238 @@
+package steam
+
+import (
+	"context"
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
+	"github.com/stretchr/testify/suite"
+)
+
+// record re-records the fixtures from the live Steam API:
+//
+//	go test ./internal/steam -run TestFixturesShould -record
+var record = flag.Bool("record", false, "record fixtures from the live Steam API")
+
+const fixturesDir = "testdata/fixtures"
+
+// fixturesShould checks payloads of odd apps captured from Steam.
+type fixturesShould struct {
+	suite.Suite
+}
+
+func (s *fixturesShould) SetupTest() {
+	StoreURL, CommunityURL = "https://store.steampowered.com", "https://steamcommunity.com"
+	if *record {
+		UseFixtures(Record, fixturesDir)
+	} else {
+		UseFixtures(Replay, fixturesDir)
+	}
+	retryAt.Time = time.Time{}
+}
+
+func (s *fixturesShould) TearDownTest() {
+	UseFixtures(Live, "")
+}
+
+func TestFixturesShould(t *testing.T) {
+	suite.Run(t, new(fixturesShould))
+}
+
+func (s *fixturesShould) TestCreateFreeToPlayApp() {
+	app, err := NewApp(context.Background(), 440)
+
+	s.Nil(err)
+	s.Equal("Team Fortress 2", app.Name)
+	s.True(app.Free)
+	s.Equal(Price{}, app.Price)
+	s.Positive(app.Reviews)
+}
+
+func (s *fixturesShould) TestCreateComingSoonApp() {
+	app, err := NewApp(context.Background(), 1030300)
+
+	s.Nil(err)
+	s.True(app.ComingSoon)
+	s.False(app.Free)
+	s.Equal(Price{}, app.Price)
+}
+
+func (s *fixturesShould) TestCreateAppNotForSale() {
+	app, err := NewApp(context.Background(), 12210)
+
+	s.Nil(err)
+	s.False(app.ComingSoon)
+	s.False(app.Free)
+	s.Equal(Price{}, app.Price)
+}
+
+func (s *fixturesShould) TestCreateDLC() {
+	app, err := NewApp(context.Background(), 2138330)
+
+	s.Nil(err)
+	s.Equal("Cyberpunk 2077: Phantom Liberty", app.Name)
+	s.Equal("dlc", app.Type)
+	s.NotEmpty(app.Price.Initial)
+	s.NotEmpty(app.Price.Final)
+}
+
+func (s *fixturesShould) TestCreateAppWithAllFilters() {
+	app, err := NewApp(context.Background(), 1091500, AllFilters...)
+
+	s.Nil(err)
+	s.Equal("game", app.Type)
+	s.Equal("Dec 9, 2020", app.ReleaseDate)
+	s.Equal([]string{"CD PROJEKT RED"}, app.Developers)
+	s.Equal([]string{"CD PROJEKT RED"}, app.Publishers)
+	s.Equal([]string{"RPG"}, app.Genres)
+	s.Contains(app.Categories, "Single-player")
+	s.Equal(Platforms{Windows: true, Mac: true}, app.Platforms)
+	s.Equal(86, app.Metacritic.Score)
+	s.Contains(app.Languages, "Spanish - Latin America")
+	s.NotContains(app.Languages, "languages with full audio support")
+	s.Contains(app.DLC, 2138330)
+	s.Require().Len(app.PackageGroups, 1)
+	s.Len(app.PackageGroups[0].Subs, 2)
+	s.Equal(2999, app.PackageGroups[0].Subs[0].Price)
+	s.Len(app.Screenshots, 2)
+	s.Equal([]int{1, 5}, app.ContentDescriptors.IDs)
+}
+
+func (s *fixturesShould) TestSumUpReviews() {
+	reviews, err := Reviews(context.Background(), 440)
+
+	s.Nil(err)
+	s.Equal("Very Positive", reviews.ScoreDesc)
+	s.Positive(reviews.Positive)
+	s.Positive(reviews.Negative)
+}
+
+func (s *fixturesShould) TestErrOnPackageAsApp() {
+	// 469 is The Orange Box, a package and not an app
+	_, err := NewApp(context.Background(), 469)
+
+	s.Error(err)
+}
+
+func (s *fixturesShould) TestCreatePackage() {
+	app, err := NewPackage(context.Background(), 469)
+
+	s.Nil(err)
+	s.Equal("The Orange Box", app.Name)
+	s.Contains(app.Apps, 400)
+	s.Contains(app.Description, "Portal")
+	s.Equal(Price{Initial: "$29.99", Final: "$29.99"}, app.Price)
+	s.NotEmpty(app.Image)
+}
+
+func (s *fixturesShould) TestCreateBundle() {
+	app, err := NewBundle(context.Background(), 232)
+
+	s.Nil(err)
+	s.Equal("Valve Complete Pack", app.Name)
+	s.Contains(app.Apps, 620)
+	s.Equal(38, app.Discount)
+	s.NotEmpty(app.Initial)
+	s.NotEmpty(app.Image)
+}
+
+func (s *fixturesShould) TestCreateBundleAtBaseDiscountWithoutDiscount() {
+	app, err := NewBundle(context.Background(), 234)
+
+	s.Nil(err)
+	s.Equal("Portal Bundle", app.Name)
+	s.Equal([]int{400, 620}, app.Apps)
+	s.Zero(app.Discount)
+	s.Equal(Price{Initial: "$19.98", Final: "$17.98"}, app.Price)
+}
+
+func (s *fixturesShould) TestErrOnUnknownBundle() {
+	_, err := NewBundle(context.Background(), 1)
+
+	s.EqualError(err, "Invalid bundleid 1")
+}
+
+func (s *fixturesShould) TestSearchApps() {
+	res, err := Search(context.Background(), "portal")
+
+	s.Nil(err)
+	s.Contains(res, SearchResult{Appid: 400, Name: "Portal"})
+	s.Contains(res, SearchResult{Appid: 620, Name: "Portal 2"})
+}
+
+func (s *fixturesShould) TestErrOnMissingFixture() {
+	if *record {
+		s.T().Skip("fixtures aren't replayed while recording")
+	}
+
+	_, err := NewApp(context.Background(), 1)
+
+	s.ErrorIs(err, ErrNoFixture)
+}
+
+type recordShould struct {
+	suite.Suite
+	server *steamtest.Server
+	dir    string
+}
+
+func (s *recordShould) SetupTest() {
+	s.server = steamtest.NewServer(steamtest.CannedApps...)
+	StoreURL, CommunityURL = s.server.URL, s.server.URL
+	s.dir = s.T().TempDir()
+	retryAt.Time = time.Time{}
+}
+
+func (s *recordShould) TearDownTest() {
+	s.server.Close()
+	UseFixtures(Live, "")
+}
+
+func TestRecordShould(t *testing.T) {
+	suite.Run(t, new(recordShould))
+}
+
+func (s *recordShould) TestReplayRecordedResponses() {
+	UseFixtures(Record, s.dir)
+	recorded, err := NewApp(context.Background(), 400)
+	s.Require().Nil(err)
+	s.server.Close()
+
+	UseFixtures(Replay, s.dir)
+	replayed, err := NewApp(context.Background(), 400)
+
+	s.Nil(err)
+	s.Equal(recorded, replayed)
+}
+
+func (s *recordShould) TestNotRecordFailedResponses() {
+	UseFixtures(Record, s.dir)
+	s.server.Queue(steamtest.AppDetailsPath, steamtest.TooManyRequests)
+
+	_, err := NewApp(context.Background(), 400)
+
+	s.ErrorIs(err, ErrNetTryAgainLater)
+	entries, _ := os.ReadDir(s.dir)
+	s.Empty(entries)
+}
+
+func (s *recordShould) TestKeyFixturesByEndpointAndParams() {
+	UseFixtures(Record, s.dir)
+
+	Search(context.Background(), "portal 2")
+
+	s.FileExists(filepath.Join(s.dir, "actions_SearchApps_portal_2.json"))
+}
+
+func (s *recordShould) TestNameFixturesIgnoringBaseURLAndParamOrder() {
+	a, _ := url.Parse("https://store.steampowered.com/api/appdetails?cc=US&appids=400")
+	b, _ := url.Parse("http://127.0.0.1:8080/api/appdetails?appids=400&cc=US")
+
+	s.Equal("api_appdetails-appids=400-cc=US.json", FixtureName(a))
+	s.Equal(FixtureName(a), FixtureName(b))
+}
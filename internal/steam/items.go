@@ -0,0 +1,258 @@
+package steam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ItemKind is the kind of an item of the store.
+type ItemKind string
+
+const (
+	KindApp    ItemKind = "app"
+	KindSub    ItemKind = "sub"    // A package, e.g., a game's Complete Edition
+	KindBundle ItemKind = "bundle" // A bundle of apps and packages
+)
+
+// Item references an item of the store by its kind and id. Ids are only
+// unique among items of the same kind.
+type Item struct {
+	Kind ItemKind
+	ID   int
+}
+
+// AppItem references the app matching appid.
+func AppItem(appid int) Item {
+	return Item{Kind: KindApp, ID: appid}
+}
+
+// String gets the id of i, prefixed by its kind unless it's an app, e.g.,
+// "400" or "sub/469". See ParseItem().
+func (i Item) String() string {
+	if i.Kind == KindApp || i.Kind == "" {
+		return strconv.Itoa(i.ID)
+	}
+	return fmt.Sprintf("%s/%d", i.Kind, i.ID)
+}
+
+// itemRegex matches an item given as an optionally kind-prefixed id, e.g.,
+// "400" or "sub/469", or as a store URL, e.g.,
+// "https://store.steampowered.com/bundle/232/Valve_Complete_Pack/".
+var itemRegex = regexp.MustCompile(
+	`^(?:(?:https?://)?(?:store\.steampowered\.com|steamcommunity\.com)/)?` +
+		`(?:(app|sub|bundle)/)?(\d+)(?:/.*)?$`)
+
+var ErrInvalidItem = errors.New("invalid item, expected an id, sub/<id>, bundle/<id>, " +
+	"or store URL")
+
+// ParseItem parses s as an appid, a kind-prefixed id like "sub/469", or a
+// store URL of an app, package, or bundle.
+func ParseItem(s string) (Item, error) {
+	m := itemRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Item{}, ErrInvalidItem
+	}
+
+	id, err := strconv.Atoi(m[2])
+	if err != nil || id <= 0 {
+		return Item{}, ErrInvalidItem
+	}
+
+	kind := ItemKind(m[1])
+	if kind == "" {
+		kind = KindApp
+	}
+	return Item{Kind: kind, ID: id}, nil
+}
+
+// NewItem creates the App of item, like NewApp(), NewPackage(), or
+// NewBundle(). filters only apply to apps.
+func NewItem(ctx context.Context, item Item, filters ...Filter) (App, error) {
+	switch item.Kind {
+	case KindApp, "":
+		return NewApp(ctx, item.ID, filters...)
+	case KindSub:
+		return NewPackage(ctx, item.ID)
+	case KindBundle:
+		return NewBundle(ctx, item.ID)
+	default:
+		return App{}, fmt.Errorf("unknown item kind %q", item.Kind)
+	}
+}
+
+// packageDetails is the form Steam API naturally returns
+type packageDetails struct {
+	Success bool               `json:"success"`
+	Data    packageDetailsData `json:"data"`
+}
+
+type packageDetailsData struct {
+	Name        string        `json:"name"`
+	HeaderImage string        `json:"header_image"`
+	PageImage   string        `json:"page_image"`
+	Apps        []packageApp  `json:"apps"`
+	Price       *packagePrice `json:"price"`
+	Platforms   Platforms     `json:"platforms"`
+	ReleaseDate releaseDate   `json:"release_date"`
+}
+
+type packageApp struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// packagePrice is a price in cents
+type packagePrice struct {
+	Initial  int `json:"initial"`
+	Final    int `json:"final"`
+	Discount int `json:"discount_percent"`
+}
+
+// rawBundle is the form Steam API naturally returns
+type rawBundle struct {
+	BundleID           int    `json:"bundleid"`
+	Name               string `json:"name"`
+	HeaderImageURL     string `json:"header_image_url"`
+	Appids             []int  `json:"appids"`
+	DiscountPercent    int    `json:"discount_percent"`
+	BundleBaseDiscount int    `json:"bundle_base_discount"`
+	FormattedOrigPrice string `json:"formatted_orig_price"`
+	FormattedFinal     string `json:"formatted_final_price"`
+}
+
+func newAppFromPackage(subid int, d packageDetails) App {
+	app := App{
+		Name:        d.Data.Name,
+		Appid:       subid,
+		Kind:        KindSub,
+		Description: includedApps(d.Data.Apps),
+		Image:       d.Data.HeaderImage,
+		ComingSoon:  d.Data.ReleaseDate.ComingSoon,
+		ReleaseDate: d.Data.ReleaseDate.Date,
+		Platforms:   d.Data.Platforms,
+	}
+	if app.Image == "" {
+		app.Image = d.Data.PageImage
+	}
+	for _, a := range d.Data.Apps {
+		app.Apps = append(app.Apps, a.ID)
+	}
+	if p := d.Data.Price; p != nil {
+		app.Price = Price{
+			Discount: p.Discount,
+			Initial:  formatCents(p.Initial),
+			Final:    formatCents(p.Final),
+		}
+	}
+	return app
+}
+
+// newAppFromBundle creates the App of b. Every bundle is discounted by its
+// base discount, so a bundle only has a Discount while it's discounted
+// beyond that, i.e., while it's on sale.
+func newAppFromBundle(b rawBundle) App {
+	app := App{
+		Name:  b.Name,
+		Appid: b.BundleID,
+		Kind:  KindBundle,
+		Image: b.HeaderImageURL,
+		Apps:  b.Appids,
+		Price: Price{
+			Initial: b.FormattedOrigPrice,
+			Final:   b.FormattedFinal,
+		},
+	}
+	if b.DiscountPercent > b.BundleBaseDiscount {
+		app.Discount = b.DiscountPercent
+	}
+	return app
+}
+
+// formatCents formats cents as US dollars, e.g., "$9.99", since prices are
+// requested for the US.
+func formatCents(cents int) string {
+	return fmt.Sprintf("$%d.%02d", cents/100, cents%100)
+}
+
+// maxIncludedLen is the longest includedApps() describes apps, so they fit
+// in an embed field.
+const maxIncludedLen = 1000
+
+// includedApps describes the apps a package includes, e.g.,
+// "Includes Portal, Half-Life 2". It's empty if there are none.
+func includedApps(apps []packageApp) string {
+	if len(apps) == 0 {
+		return ""
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("Includes ")
+	for i, a := range apps {
+		more := fmt.Sprintf(" and %d more", len(apps)-i)
+		if sb.Len()+len(a.Name)+len(more)+2 > maxIncludedLen {
+			sb.WriteString(more)
+			break
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(a.Name)
+	}
+	return sb.String()
+}
+
+// NewPackage calls the Steam API with subid to retrieve information on that
+// package. Its Appid is subid and its Apps are the apps it includes.
+func NewPackage(ctx context.Context, subid int) (App, error) {
+	sid := fmt.Sprint(subid)
+	endpoint :=
+		StoreURL + "/api/packagedetails?" +
+			url.Values{
+				"packageids": {sid},
+				"cc":         {"US"},
+			}.Encode()
+
+	details := make(map[string]packageDetails, 1)
+	err := apiGet(ctx, endpoint, &details)
+	if err != nil {
+		return App{}, err
+	}
+
+	if !details[sid].Success {
+		return App{}, errors.New("Invalid subid " + sid)
+	}
+
+	return newAppFromPackage(subid, details[sid]), nil
+}
+
+// NewBundle calls the Steam API with bundleid to retrieve information on
+// that bundle. Its Appid is bundleid and its Apps are the apps it includes.
+// Unknown bundleids are left out of the reply rather than failing.
+func NewBundle(ctx context.Context, bundleid int) (App, error) {
+	bid := fmt.Sprint(bundleid)
+	endpoint :=
+		StoreURL + "/actions/ajaxresolvebundles?" +
+			url.Values{
+				"bundleids": {bid},
+				"cc":        {"US"},
+				"l":         {"english"},
+			}.Encode()
+
+	bundles := []rawBundle{}
+	err := apiGet(ctx, endpoint, &bundles)
+	if err != nil {
+		return App{}, err
+	}
+
+	for _, b := range bundles {
+		if b.BundleID == bundleid {
+			return newAppFromBundle(b), nil
+		}
+	}
+	return App{}, errors.New("Invalid bundleid " + bid)
+}
@@ -0,0 +1,81 @@
+package steam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ReviewSummary sums up the user reviews of an app across every language
+// and purchase type, like its store page does.
+type ReviewSummary struct {
+	Positive  int
+	Negative  int
+	ScoreDesc string // e.g., "Very Positive", or "No user reviews"
+}
+
+// rawReviews is the form Steam API naturally returns
+type rawReviews struct {
+	Success      int          `json:"success"`
+	QuerySummary querySummary `json:"query_summary"`
+}
+
+type querySummary struct {
+	ReviewScoreDesc string `json:"review_score_desc"`
+	TotalPositive   int    `json:"total_positive"`
+	TotalNegative   int    `json:"total_negative"`
+}
+
+// Total gets the number of reviews.
+func (r ReviewSummary) Total() int {
+	return r.Positive + r.Negative
+}
+
+// Percent gets the percentage of reviews that are positive, rounded down
+// like Steam does. It's 0 if there are no reviews.
+func (r ReviewSummary) Percent() int {
+	if r.Total() == 0 {
+		return 0
+	}
+	return r.Positive * 100 / r.Total()
+}
+
+// String describes r like the store page does, e.g., "Very Positive, 92%".
+func (r ReviewSummary) String() string {
+	if r.Total() == 0 {
+		return r.ScoreDesc
+	}
+	return fmt.Sprintf("%s, %d%%", r.ScoreDesc, r.Percent())
+}
+
+// Reviews calls the Steam API with appid to sum up the app's user reviews.
+// No reviews are fetched along with the summary.
+//
+// More information: https://partner.steamgames.com/doc/store/getreviews
+func Reviews(ctx context.Context, appid int) (ReviewSummary, error) {
+	endpoint :=
+		StoreURL + "/appreviews/" + fmt.Sprint(appid) + "?" +
+			url.Values{
+				"json":          {"1"},
+				"language":      {"all"},
+				"purchase_type": {"all"},
+				"num_per_page":  {"0"},
+			}.Encode()
+
+	var raw rawReviews
+	err := apiGet(ctx, endpoint, &raw)
+	if err != nil {
+		return ReviewSummary{}, err
+	}
+
+	if raw.Success != 1 {
+		return ReviewSummary{}, errors.New("Failed to get reviews of appid " + fmt.Sprint(appid))
+	}
+
+	return ReviewSummary{
+		Positive:  raw.QuerySummary.TotalPositive,
+		Negative:  raw.QuerySummary.TotalNegative,
+		ScoreDesc: raw.QuerySummary.ReviewScoreDesc,
+	}, nil
+}
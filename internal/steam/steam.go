@@ -2,25 +2,54 @@
 package steam
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/clock"
 )
 
-// App is appDetails flattened and with a settable SaleThreshold
+// App is appDetails flattened and with a settable SaleThreshold.
+// Fields outside of the always requested filters are only set when
+// their Filter is requested. See NewApp().
+//
+// Packages and bundles are Apps too, of another Kind, whose Appid is their
+// subid or bundleid. See NewPackage() and NewBundle().
 type App struct {
 	Name        string
 	Appid       int
+	Kind        ItemKind // KindApp if empty
+	Type        string   // e.g., game, dlc, demo, or music
 	Free        bool
 	Description string
 	Image       string
 	Reviews     int
 	ComingSoon  bool
+	ReleaseDate string // As Steam shows it, e.g., "Oct 10, 2007" or "Coming soon"
+	Languages   []string
+	DLC         []int // Appids
+	Apps        []int // Appids a package or bundle includes
 	Price
+
+	Developers         []string
+	Publishers         []string
+	Genres             []string
+	Categories         []string
+	Platforms          Platforms
+	Metacritic         Metacritic
+	PackageGroups      []PackageGroup
+	Screenshots        []Screenshot
+	ContentDescriptors ContentDescriptors
+
 	SaleThreshold *int
 }
 
@@ -30,6 +59,81 @@ type Price struct {
 	Final    string `json:"final_formatted"`
 }
 
+// Platforms are the operating systems an app supports.
+type Platforms struct {
+	Windows bool `json:"windows"`
+	Mac     bool `json:"mac"`
+	Linux   bool `json:"linux"`
+}
+
+// Metacritic is an app's Metacritic score. Score is 0 if it has none.
+type Metacritic struct {
+	Score int    `json:"score"`
+	URL   string `json:"url"`
+}
+
+// PackageGroup is a group of packages an app is sold in, e.g., the
+// editions of a game.
+type PackageGroup struct {
+	Name  string `json:"name"` // "default", or "subscriptions" for subscriptions
+	Title string `json:"title"`
+	Subs  []Sub  `json:"subs"`
+}
+
+// Sub is a package of a PackageGroup.
+type Sub struct {
+	PackageID      int    `json:"packageid"`
+	OptionText     string `json:"option_text"` // e.g., "Portal - $9.99"
+	PercentSavings int    `json:"percent_savings"`
+	Price          int    `json:"price_in_cents_with_discount"`
+	IsFreeLicense  bool   `json:"is_free_license"`
+}
+
+// Screenshot is a screenshot on an app's store page.
+type Screenshot struct {
+	ID        int    `json:"id"`
+	Thumbnail string `json:"path_thumbnail"`
+	Full      string `json:"path_full"`
+}
+
+// ContentDescriptors are an app's mature content warnings.
+type ContentDescriptors struct {
+	IDs   []int  `json:"ids"`
+	Notes string `json:"notes"`
+}
+
+// Filter selects a part of appdetails for NewApp() to request. The basic
+// info, price, recommendations, and release date are always requested.
+type Filter string
+
+const (
+	FilterDevelopers         Filter = "developers"
+	FilterPublishers         Filter = "publishers"
+	FilterGenres             Filter = "genres"
+	FilterCategories         Filter = "categories"
+	FilterPlatforms          Filter = "platforms"
+	FilterMetacritic         Filter = "metacritic"
+	FilterPackageGroups      Filter = "package_groups"
+	FilterScreenshots        Filter = "screenshots"
+	FilterContentDescriptors Filter = "content_descriptors"
+)
+
+// AllFilters are every Filter.
+var AllFilters = []Filter{
+	FilterDevelopers,
+	FilterPublishers,
+	FilterGenres,
+	FilterCategories,
+	FilterPlatforms,
+	FilterMetacritic,
+	FilterPackageGroups,
+	FilterScreenshots,
+	FilterContentDescriptors,
+}
+
+// baseFilters are the filters NewApp() always requests.
+const baseFilters = "basic,price_overview,recommendations,release_date"
+
 // appDetails is the form Steam API naturally returns
 type appDetails struct {
 	Success bool           `json:"success"`
@@ -37,15 +141,28 @@ type appDetails struct {
 }
 
 type appDetailsData struct {
-	Name             string `json:"name"`
-	SteamAppid       int    `json:"steam_appid"`
-	IsFree           bool   `json:"is_free"`
-	ShortDescription string `json:"short_description"`
-	HeaderImage      string `json:"header_image"`
+	Type               string `json:"type"`
+	Name               string `json:"name"`
+	SteamAppid         int    `json:"steam_appid"`
+	IsFree             bool   `json:"is_free"`
+	ShortDescription   string `json:"short_description"`
+	HeaderImage        string `json:"header_image"`
+	SupportedLanguages string `json:"supported_languages"`
+	DLC                []int  `json:"dlc"`
 
 	Recommendations recommendations `json:"recommendations"`
 	ReleaseDate     releaseDate     `json:"release_date"`
 	PriceOverview   Price           `json:"price_overview"`
+
+	Developers         []string           `json:"developers"`
+	Publishers         []string           `json:"publishers"`
+	Genres             []description      `json:"genres"`
+	Categories         []description      `json:"categories"`
+	Platforms          Platforms          `json:"platforms"`
+	Metacritic         Metacritic         `json:"metacritic"`
+	PackageGroups      []PackageGroup     `json:"package_groups"`
+	Screenshots        []Screenshot       `json:"screenshots"`
+	ContentDescriptors ContentDescriptors `json:"content_descriptors"`
 }
 
 type recommendations struct {
@@ -53,7 +170,15 @@ type recommendations struct {
 }
 
 type releaseDate struct {
-	ComingSoon bool `json:"coming_soon"`
+	ComingSoon bool   `json:"coming_soon"`
+	Date       string `json:"date"`
+}
+
+// description is a described item of a list, like a genre. Its id isn't
+// kept since Steam sends it as a string for some lists and a number
+// for others.
+type description struct {
+	Description string `json:"description"`
 }
 
 // SearchResult is rawSearchResult with its Appid converted to int
@@ -68,32 +193,120 @@ type rawSearchResult struct {
 	Name  string `json:"name"`
 }
 
+// StoreURL and CommunityURL are the base URLs requests to the Steam API
+// are sent to. They can be pointed elsewhere, e.g., at a steamtest.Server.
+var (
+	StoreURL     = "https://store.steampowered.com"
+	CommunityURL = "https://steamcommunity.com"
+)
+
 type httpClient interface {
-	Get(string) (*http.Response, error)
+	Do(*http.Request) (*http.Response, error)
 }
 
 // client is the client used for requests to the Steam API.
 var client httpClient
 
+// clk tells time for rate limiting. It's guarded by retryAt. See SetClock().
+var clk = clock.Real()
+
 func init() {
-	client = &http.Client{Timeout: 10 * time.Second}
+	client = newClient()
 }
 
 func (a *App) Url() string {
-	return "https://store.steampowered.com/app/" + fmt.Sprint(a.Appid)
+	return fmt.Sprintf("https://store.steampowered.com/%s/%d", a.Item().Kind, a.Appid)
+}
+
+// Item references a.
+func (a *App) Item() Item {
+	if a.Kind == "" {
+		return AppItem(a.Appid)
+	}
+	return Item{Kind: a.Kind, ID: a.Appid}
+}
+
+// Trackable reports whether a can go on sale, i.e., it's priced or hasn't
+// released yet.
+func (a *App) Trackable() bool {
+	return a.Initial != "" || a.Final != "" || a.ComingSoon
 }
 
 func newAppFrom(d appDetails) App {
 	return App{
 		Name:        d.Data.Name,
 		Appid:       d.Data.SteamAppid,
+		Kind:        KindApp,
+		Type:        d.Data.Type,
 		Free:        d.Data.IsFree,
 		Description: d.Data.ShortDescription,
 		Image:       d.Data.HeaderImage,
 		Reviews:     d.Data.Recommendations.Total,
 		ComingSoon:  d.Data.ReleaseDate.ComingSoon,
+		ReleaseDate: d.Data.ReleaseDate.Date,
+		Languages:   parseLanguages(d.Data.SupportedLanguages),
+		DLC:         d.Data.DLC,
 		Price:       d.Data.PriceOverview,
+
+		Developers:         d.Data.Developers,
+		Publishers:         d.Data.Publishers,
+		Genres:             descriptions(d.Data.Genres),
+		Categories:         descriptions(d.Data.Categories),
+		Platforms:          d.Data.Platforms,
+		Metacritic:         d.Data.Metacritic,
+		PackageGroups:      d.Data.PackageGroups,
+		Screenshots:        d.Data.Screenshots,
+		ContentDescriptors: d.Data.ContentDescriptors,
+	}
+}
+
+// htmlTag matches an HTML tag.
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// parseLanguages parses the HTML list of supported languages Steam sends,
+// e.g., "English<strong>*</strong>, French<br><strong>*</strong>languages
+// with full audio support", into ["English", "French"].
+func parseLanguages(html string) []string {
+	// Drop the footnote
+	if i := strings.Index(html, "<br>"); i != -1 {
+		html = html[:i]
 	}
+	html = htmlTag.ReplaceAllString(html, "")
+
+	var langs []string
+	for _, lang := range strings.Split(html, ",") {
+		lang = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(lang), "*"))
+		if lang != "" {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
+// descriptions gets the descriptions of ds, or nil if there are none.
+func descriptions(ds []description) []string {
+	if len(ds) == 0 {
+		return nil
+	}
+	strs := make([]string, 0, len(ds))
+	for _, d := range ds {
+		strs = append(strs, d.Description)
+	}
+	return strs
+}
+
+// filterParam joins baseFilters and filters, in a stable order so the
+// same request is always keyed the same. See FixtureName().
+func filterParam(filters []Filter) string {
+	extra := make([]string, 0, len(filters))
+	for _, f := range filters {
+		if !slices.Contains(extra, string(f)) {
+			extra = append(extra, string(f))
+		}
+	}
+	slices.Sort(extra)
+
+	return strings.Join(append([]string{baseFilters}, extra...), ",")
 }
 
 func newSearchResultFrom(s rawSearchResult) (SearchResult, error) {
@@ -106,18 +319,102 @@ func newSearchResultFrom(s rawSearchResult) (SearchResult, error) {
 
 var ErrNetTryAgainLater = errors.New("too many requests. Try again later")
 
-// apiGet sends a GET request to endpoint and tries to decode it into value
-func apiGet[T any](endpoint string, value *T) error {
-	resp, err := client.Get(endpoint)
+// RateLimitCooldown is how long requests are held off after
+// the Steam API rate limits a request.
+const RateLimitCooldown = 5 * time.Minute
+
+// retryAt is when requests may be made again after being rate limited.
+// It's shared by every caller so they all draw from the same rate limit.
+var retryAt struct {
+	sync.Mutex
+	time.Time
+}
+
+// RetryAt gets when requests may be made again after the Steam API
+// rate limited a request. Until then, requests fail with ErrNetTryAgainLater
+// without being sent. If there is no rate limit in effect, the time
+// returned is in the past.
+func RetryAt() time.Time {
+	retryAt.Lock()
+	defer retryAt.Unlock()
+	return retryAt.Time
+}
+
+// holdOffRequests makes requests fail until RateLimitCooldown has passed.
+func holdOffRequests() {
+	retryAt.Lock()
+	defer retryAt.Unlock()
+	retryAt.Time = clk.Now().Add(RateLimitCooldown)
+}
+
+// SetClock makes rate limiting tell time with c. Any rate limit in effect
+// is lifted, since it was measured by the previous clock.
+func SetClock(c clock.Clock) {
+	retryAt.Lock()
+	defer retryAt.Unlock()
+	clk = c
+	retryAt.Time = time.Time{}
+}
+
+// currentClock gets the clock set by SetClock().
+func currentClock() clock.Clock {
+	retryAt.Lock()
+	defer retryAt.Unlock()
+	return clk
+}
+
+// WaitForLimit calls fn and returns its results. For as long as fn fails
+// with ErrNetTryAgainLater, it waits until RetryAt() and calls fn again.
+// If ctx is done while waiting, ctx.Err() is returned.
+func WaitForLimit[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	v, err := fn()
+	for err == ErrNetTryAgainLater {
+		clk := currentClock()
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-clk.After(RetryAt().Sub(clk.Now())):
+		}
+		v, err = fn()
+	}
+	return v, err
+}
+
+// get sends a GET request to endpoint, unless requests are being held
+// off. A rate limited response holds off requests.
+func get(ctx context.Context, endpoint string) (*http.Response, error) {
+	if currentClock().Now().Before(RetryAt()) {
+		return nil, ErrNetTryAgainLater
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusTooManyRequests ||
 		resp.StatusCode == http.StatusForbidden {
-		return ErrNetTryAgainLater
+		resp.Body.Close()
+		holdOffRequests()
+		return nil, ErrNetTryAgainLater
+	}
+
+	return resp, nil
+}
+
+// apiGet sends a GET request to endpoint and tries to decode it into value
+func apiGet[T any](ctx context.Context, endpoint string, value *T) error {
+	resp, err := get(ctx, endpoint)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
 	err = json.NewDecoder(resp.Body).Decode(&value)
 	if err != nil {
@@ -128,21 +425,23 @@ func apiGet[T any](endpoint string, value *T) error {
 }
 
 // NewApp calls the Steam API with appid to retrieve information on that app.
-// Fields may be unset, and Steam rate limits requests.
+// Only the parts of appdetails in filters are requested on top of the basic
+// info, so callers only pay for what they need. Fields may be unset, and
+// Steam rate limits requests.
 //
 // More information: https://github.com/Revadike/InternalSteamWebAPI/wiki/Get-App-Details
-func NewApp(appid int) (App, error) {
+func NewApp(ctx context.Context, appid int, filters ...Filter) (App, error) {
 	aid := fmt.Sprint(appid)
 	endpoint :=
-		"https://store.steampowered.com/api/appdetails" +
-			"?filters=basic,price_overview,recommendations,release_date&" +
+		StoreURL + "/api/appdetails?" +
 			url.Values{
-				"appids": {aid},
-				"cc":     {"US"},
+				"appids":  {aid},
+				"cc":      {"US"},
+				"filters": {filterParam(filters)},
 			}.Encode()
 
 	details := make(map[string]appDetails, 1)
-	err := apiGet(endpoint, &details)
+	err := apiGet(ctx, endpoint, &details)
 	if err != nil {
 		return App{}, err
 	}
@@ -157,12 +456,12 @@ func NewApp(appid int) (App, error) {
 // Search calls the Steam API with query to find apps.
 //
 // More information: https://github.com/Revadike/InternalSteamWebAPI/wiki/Search-Apps
-func Search(query string) ([]SearchResult, error) {
+func Search(ctx context.Context, query string) ([]SearchResult, error) {
 	endpoint :=
-		"https://steamcommunity.com/actions/SearchApps/" + url.QueryEscape(query)
+		CommunityURL + "/actions/SearchApps/" + url.QueryEscape(query)
 
 	rawResults := []rawSearchResult{}
-	err := apiGet(endpoint, &rawResults)
+	err := apiGet(ctx, endpoint, &rawResults)
 	if err != nil {
 		return nil, err
 	}
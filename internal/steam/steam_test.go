@@ -2,13 +2,16 @@ package steam
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -17,7 +20,7 @@ type mockClient struct {
 	err  error
 }
 
-func (c *mockClient) Get(string) (*http.Response, error) {
+func (c *mockClient) Do(*http.Request) (*http.Response, error) {
 	return c.resp, c.err
 }
 
@@ -32,6 +35,13 @@ func (c *mockClient) setBody(v any) {
 	}
 }
 
+func (c *mockClient) setStatus(code int) {
+	c.resp = &http.Response{
+		StatusCode: code,
+		Body:       io.NopCloser(bytes.NewBuffer(nil)),
+	}
+}
+
 type newAppShould struct {
 	suite.Suite
 	arbitraryAppid      int
@@ -80,6 +90,7 @@ func (s *newAppShould) SetupTest() {
 		err:  nil,
 	}
 	client = &s.client
+	retryAt.Time = time.Time{}
 }
 
 func TestNewAppShould(t *testing.T) {
@@ -94,7 +105,7 @@ func (s *newAppShould) TestErrOnFalseSuccess() {
 		},
 	})
 
-	app, err := NewApp(s.arbitraryAppid)
+	app, err := NewApp(context.Background(), s.arbitraryAppid)
 
 	s.Error(err)
 	s.Equal(app, App{})
@@ -109,7 +120,7 @@ func (s *newAppShould) TestErrOnDifferentAppid() {
 		},
 	})
 
-	app, err := NewApp(s.arbitraryAppid)
+	app, err := NewApp(context.Background(), s.arbitraryAppid)
 
 	s.Error(err)
 	s.Equal(app, App{})
@@ -123,7 +134,7 @@ func (s *newAppShould) TestNoErrOnTrueSuccessAndSameAppid() {
 		},
 	})
 
-	app, err := NewApp(s.arbitraryAppid)
+	app, err := NewApp(context.Background(), s.arbitraryAppid)
 
 	s.Nil(err)
 	s.NotEqual(app, App{})
@@ -132,12 +143,43 @@ func (s *newAppShould) TestNoErrOnTrueSuccessAndSameAppid() {
 func (s *newAppShould) TestAppEqualToApp() {
 	s.setReturnedDetails(s.arbitraryAppDetails)
 
-	app, err := NewApp(s.arbitraryAppDetails.Data.SteamAppid)
+	app, err := NewApp(context.Background(), s.arbitraryAppDetails.Data.SteamAppid)
 
 	s.Nil(err)
 	s.Equal(app, newAppFrom(s.arbitraryAppDetails))
 }
 
+func (s *newAppShould) TestErrOnRateLimit() {
+	s.client.setStatus(http.StatusTooManyRequests)
+
+	_, err := NewApp(context.Background(), s.arbitraryAppid)
+
+	s.ErrorIs(err, ErrNetTryAgainLater)
+	s.True(RetryAt().After(time.Now()))
+}
+
+func (s *newAppShould) TestErrWhileCoolingDownFromRateLimit() {
+	s.client.setStatus(http.StatusTooManyRequests)
+	NewApp(context.Background(), s.arbitraryAppid)
+	s.setReturnedDetails(s.arbitraryAppDetails)
+
+	_, err := NewApp(context.Background(), s.arbitraryAppid)
+
+	s.ErrorIs(err, ErrNetTryAgainLater)
+}
+
+func (s *newAppShould) TestWaitForLimitStopsWhenCancelled() {
+	s.client.setStatus(http.StatusTooManyRequests)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := WaitForLimit(ctx, func() (App, error) {
+		return NewApp(ctx, s.arbitraryAppid)
+	})
+
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
 type searchShould struct {
 	suite.Suite
 	client mockClient
@@ -149,6 +191,7 @@ func (s *searchShould) SetupTest() {
 		err:  nil,
 	}
 	client = &s.client
+	retryAt.Time = time.Time{}
 }
 
 func TestSearchShould(t *testing.T) {
@@ -166,7 +209,7 @@ func (s *searchShould) TestResultsEmptyWhenResultNotConvToInt() {
 		},
 	})
 
-	res, err := Search("")
+	res, err := Search(context.Background(), "")
 
 	s.Nil(err)
 	s.Empty(res)
@@ -181,7 +224,7 @@ func (s *searchShould) TestResultsEqualToResults() {
 	}
 	s.setReturnedResults(rawRes)
 
-	actualRes, err := Search("")
+	actualRes, err := Search(context.Background(), "")
 
 	s.Nil(err)
 	expectedRes := []SearchResult{}
@@ -205,7 +248,7 @@ func (s *searchShould) TestFiltersResultsThatArentConvToInt() {
 	rawRes := []rawSearchResult{badRaw, okRaw, badRaw, okRaw}
 	s.setReturnedResults(rawRes)
 
-	actualRes, err := Search("")
+	actualRes, err := Search(context.Background(), "")
 
 	s.Nil(err)
 	expectedRes := []SearchResult{}
@@ -218,3 +261,344 @@ func (s *searchShould) TestFiltersResultsThatArentConvToInt() {
 	}
 	s.Equal(actualRes, expectedRes)
 }
+
+type steamAPIShould struct {
+	suite.Suite
+	server *steamtest.Server
+}
+
+func (s *steamAPIShould) SetupTest() {
+	s.server = steamtest.NewServer(steamtest.CannedApps...)
+	s.server.SetPackages(steamtest.CannedPackages...)
+	s.server.SetBundles(steamtest.CannedBundles...)
+	StoreURL, CommunityURL = s.server.URL, s.server.URL
+	client = s.server.Client()
+	retryAt.Time = time.Time{}
+}
+
+func (s *steamAPIShould) TearDownTest() {
+	s.server.Close()
+}
+
+func TestSteamAPIShould(t *testing.T) {
+	suite.Run(t, new(steamAPIShould))
+}
+
+func (s *steamAPIShould) TestCreateAppFromDetails() {
+	app, err := NewApp(context.Background(), 400)
+
+	s.Nil(err)
+	s.Equal(App{
+		Name:        "Portal",
+		Appid:       400,
+		Kind:        KindApp,
+		Type:        "game",
+		Description: "Portal is a new single player game from Valve.",
+		Image:       "https://example.com/400/header.jpg",
+		Reviews:     100000,
+		ReleaseDate: "Oct 10, 2007",
+		Languages:   []string{"English", "French"},
+		Price: Price{
+			Discount: 90,
+			Initial:  "$9.99",
+			Final:    "$0.99",
+		},
+	}, app)
+}
+
+func (s *steamAPIShould) TestCreateUnpricedApp() {
+	app, err := NewApp(context.Background(), 440)
+
+	s.Nil(err)
+	s.True(app.Free)
+	s.Equal(Price{}, app.Price)
+	s.False(app.Trackable())
+}
+
+func (s *steamAPIShould) TestTrackPricedAndUnreleasedApps() {
+	portal, err := NewApp(context.Background(), 400)
+	s.Require().Nil(err)
+	dave, err := NewApp(context.Background(), 1868140)
+	s.Require().Nil(err)
+
+	s.True(portal.Trackable())
+	s.True(dave.Trackable())
+}
+
+func (s *steamAPIShould) TestErrOnUnknownAppid() {
+	_, err := NewApp(context.Background(), 1)
+
+	s.Error(err)
+	s.NotErrorIs(err, ErrNetTryAgainLater)
+}
+
+func (s *steamAPIShould) TestHoldOffOnRateLimitResponses() {
+	for _, resp := range []steamtest.Response{steamtest.TooManyRequests, steamtest.Forbidden} {
+		retryAt.Time = time.Time{}
+		s.server.Queue(steamtest.AppDetailsPath, resp)
+
+		_, err := NewApp(context.Background(), 400)
+		s.ErrorIs(err, ErrNetTryAgainLater)
+
+		// Held off requests aren't sent
+		requests := s.server.Requests(steamtest.AppDetailsPath)
+		_, err = NewApp(context.Background(), 400)
+		s.ErrorIs(err, ErrNetTryAgainLater)
+		s.Equal(requests, s.server.Requests(steamtest.AppDetailsPath))
+	}
+}
+
+func (s *steamAPIShould) TestErrOnMalformedJSON() {
+	s.server.Queue(steamtest.AppDetailsPath, steamtest.MalformedJSON)
+	s.server.Queue(steamtest.SearchAppsPath, steamtest.MalformedJSON)
+
+	_, err := NewApp(context.Background(), 400)
+	s.Error(err)
+	_, err = Search(context.Background(), "portal")
+	s.Error(err)
+
+	s.True(RetryAt().Before(time.Now()))
+}
+
+func (s *steamAPIShould) TestPickRequestedAppFromMultiAppidReply() {
+	s.server.Queue(steamtest.AppDetailsPath, steamtest.Response{
+		Body: `{"620":{"success":true,"data":{"name":"Portal 2","steam_appid":620}},` +
+			`"400":{"success":true,"data":{"name":"Portal","steam_appid":400}}}`,
+	})
+
+	app, err := NewApp(context.Background(), 400)
+
+	s.Nil(err)
+	s.Equal("Portal", app.Name)
+}
+
+func (s *steamAPIShould) TestErrOnMultiAppidReplyMissingAppid() {
+	s.server.Queue(steamtest.AppDetailsPath, steamtest.Response{
+		Body: `{"620":{"success":true,"data":{"name":"Portal 2","steam_appid":620}}}`,
+	})
+
+	_, err := NewApp(context.Background(), 400)
+
+	s.Error(err)
+}
+
+func (s *steamAPIShould) TestSearchServedApps() {
+	res, err := Search(context.Background(), "portal")
+
+	s.Nil(err)
+	s.Equal([]SearchResult{{Appid: 400, Name: "Portal"}, {Appid: 620, Name: "Portal 2"}}, res)
+}
+
+func (s *steamAPIShould) TestSearchProgrammedResults() {
+	s.server.SetSearch("query", steamtest.App{Appid: 1, Name: "One"})
+
+	res, err := Search(context.Background(), "query")
+
+	s.Nil(err)
+	s.Equal([]SearchResult{{Appid: 1, Name: "One"}}, res)
+}
+
+func (s *steamAPIShould) TestRequestOnlyBaseFiltersByDefault() {
+	app, err := NewApp(context.Background(), 400)
+
+	s.Nil(err)
+	s.Nil(app.Developers)
+	s.Nil(app.Genres)
+	s.Equal(Platforms{}, app.Platforms)
+	s.Equal(Metacritic{}, app.Metacritic)
+}
+
+func (s *steamAPIShould) TestRequestSelectedFilters() {
+	app, err := NewApp(context.Background(), 400, FilterGenres, FilterPlatforms, FilterGenres)
+
+	s.Nil(err)
+	s.Equal([]string{"Action"}, app.Genres)
+	s.Equal(Platforms{Windows: true, Mac: true, Linux: true}, app.Platforms)
+	s.Nil(app.Developers)
+	s.Nil(app.Categories)
+}
+
+func (s *steamAPIShould) TestRequestAllFilters() {
+	app, err := NewApp(context.Background(), 440, AllFilters...)
+
+	s.Nil(err)
+	s.Equal([]string{"Valve"}, app.Developers)
+	s.Equal([]string{"Valve"}, app.Publishers)
+	s.Equal([]string{"Action", "Free to Play"}, app.Genres)
+	s.Equal([]string{"Multi-player"}, app.Categories)
+	s.Equal(Platforms{Windows: true}, app.Platforms)
+	s.Equal(92, app.Metacritic.Score)
+}
+
+func (s *steamAPIShould) TestKeepDLCList() {
+	app, err := NewApp(context.Background(), 620)
+
+	s.Nil(err)
+	s.Equal([]int{323180}, app.DLC)
+}
+
+func (s *steamAPIShould) TestSumUpReviews() {
+	reviews, err := Reviews(context.Background(), 440)
+
+	s.Nil(err)
+	s.Equal(ReviewSummary{Positive: 920000, Negative: 80000, ScoreDesc: "Very Positive"},
+		reviews)
+	s.Equal(1000000, reviews.Total())
+	s.Equal("Very Positive, 92%", reviews.String())
+}
+
+func (s *steamAPIShould) TestSumUpAppWithoutReviews() {
+	reviews, err := Reviews(context.Background(), 1868140)
+
+	s.Nil(err)
+	s.Zero(reviews.Total())
+	s.Zero(reviews.Percent())
+	s.Equal("No user reviews", reviews.String())
+}
+
+func (s *steamAPIShould) TestErrOnUnsuccessfulReviews() {
+	s.server.Queue(steamtest.AppReviewsPath, steamtest.Response{Body: `{"success":2}`})
+
+	_, err := Reviews(context.Background(), 440)
+
+	s.Error(err)
+}
+
+func (s *steamAPIShould) TestRoundReviewPercentDown() {
+	s.Equal(99, ReviewSummary{Positive: 999, Negative: 1}.Percent())
+	s.Equal(66, ReviewSummary{Positive: 2, Negative: 1}.Percent())
+}
+
+func (s *steamAPIShould) TestCreatePackage() {
+	app, err := NewPackage(context.Background(), 469)
+
+	s.Nil(err)
+	s.Equal(App{
+		Name:        "The Orange Box",
+		Appid:       469,
+		Kind:        KindSub,
+		Description: "Includes Portal, Team Fortress 2",
+		Image:       "https://example.com/sub/469/header.jpg",
+		Apps:        []int{400, 440},
+		Price:       Price{Discount: 75, Initial: "$29.99", Final: "$7.49"},
+	}, app)
+	s.Equal("https://store.steampowered.com/sub/469", app.Url())
+}
+
+func (s *steamAPIShould) TestErrOnUnknownSubid() {
+	_, err := NewPackage(context.Background(), 1)
+
+	s.Error(err)
+}
+
+func (s *steamAPIShould) TestCreateBundleOnSale() {
+	app, err := NewBundle(context.Background(), 232)
+
+	s.Nil(err)
+	s.Equal("Valve Complete Pack", app.Name)
+	s.Equal(Item{Kind: KindBundle, ID: 232}, app.Item())
+	s.Equal([]int{400, 440, 620}, app.Apps)
+	s.Equal(Price{Discount: 60, Initial: "$159.89", Final: "$63.95"}, app.Price)
+	s.Equal("https://store.steampowered.com/bundle/232", app.Url())
+}
+
+func (s *steamAPIShould) TestNotDiscountBundleAtBaseDiscount() {
+	app, err := NewBundle(context.Background(), 234)
+
+	s.Nil(err)
+	s.Equal(Price{Initial: "$19.98", Final: "$17.98"}, app.Price)
+}
+
+func (s *steamAPIShould) TestErrOnUnknownBundleid() {
+	_, err := NewBundle(context.Background(), 1)
+
+	s.Error(err)
+}
+
+func (s *steamAPIShould) TestCreateItemOfEachKind() {
+	for _, item := range []Item{AppItem(400), {Kind: KindSub, ID: 469},
+		{Kind: KindBundle, ID: 232}} {
+		app, err := NewItem(context.Background(), item)
+
+		s.Nil(err)
+		s.Equal(item, app.Item())
+	}
+}
+
+type parseItemShould struct {
+	suite.Suite
+}
+
+func TestParseItemShould(t *testing.T) {
+	suite.Run(t, new(parseItemShould))
+}
+
+func (s *parseItemShould) TestParseIdsAndStoreURLs() {
+	for str, item := range map[string]Item{
+		"400":        AppItem(400),
+		" app/400 ":  AppItem(400),
+		"sub/469":    {Kind: KindSub, ID: 469},
+		"bundle/232": {Kind: KindBundle, ID: 232},
+		"https://store.steampowered.com/app/400/Portal/":        {Kind: KindApp, ID: 400},
+		"https://store.steampowered.com/sub/469/":               {Kind: KindSub, ID: 469},
+		"store.steampowered.com/bundle/232/Valve_Complete_Pack": {Kind: KindBundle, ID: 232},
+		"https://steamcommunity.com/app/400":                    {Kind: KindApp, ID: 400},
+	} {
+		parsed, err := ParseItem(str)
+
+		s.Nil(err, str)
+		s.Equal(item, parsed, str)
+	}
+}
+
+func (s *parseItemShould) TestErrOnInvalidItems() {
+	for _, str := range []string{"", "0", "-1", "portal", "dlc/400", "sub/", "sub/abc",
+		"https://example.com/app/400"} {
+		_, err := ParseItem(str)
+
+		s.ErrorIs(err, ErrInvalidItem, str)
+	}
+}
+
+func (s *parseItemShould) TestRoundTripThroughString() {
+	for _, item := range []Item{AppItem(400), {Kind: KindSub, ID: 469},
+		{Kind: KindBundle, ID: 232}} {
+		parsed, err := ParseItem(item.String())
+
+		s.Nil(err)
+		s.Equal(item, parsed)
+	}
+	s.Equal("400", AppItem(400).String())
+}
+
+func (s *parseItemShould) TestCutLongListsOfIncludedApps() {
+	apps := make([]packageApp, 200)
+	for i := range apps {
+		apps[i] = packageApp{ID: i, Name: "Some Long App Name"}
+	}
+
+	desc := includedApps(apps)
+
+	s.LessOrEqual(len(desc), maxIncludedLen)
+	s.Regexp(`^Includes Some Long App Name, .* and \d+ more$`, desc)
+	s.Empty(includedApps(nil))
+}
+
+type parseLanguagesShould struct {
+	suite.Suite
+}
+
+func TestParseLanguagesShould(t *testing.T) {
+	suite.Run(t, new(parseLanguagesShould))
+}
+
+func (s *parseLanguagesShould) TestDropMarkupAndFootnote() {
+	langs := parseLanguages("English<strong>*</strong>, Spanish - Spain, " +
+		"Japanese<strong>*</strong><br><strong>*</strong>languages with full audio support")
+
+	s.Equal([]string{"English", "Spanish - Spain", "Japanese"}, langs)
+}
+
+func (s *parseLanguagesShould) TestBeNilWhenEmpty() {
+	s.Nil(parseLanguages(""))
+}
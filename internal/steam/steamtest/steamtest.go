@@ -0,0 +1,625 @@
+// steamtest provides a fake Steam API for tests.
+//
+// A Server serves the appdetails, appreviews, packagedetails,
+// ajaxresolvebundles, and SearchApps endpoints from the items it's given,
+// speaking the same JSON as Steam. Point steam.StoreURL and
+// steam.CommunityURL at its URL to use it:
+//
+//	srv := steamtest.NewServer(steamtest.CannedApps...)
+//	defer srv.Close()
+//	steam.StoreURL, steam.CommunityURL = srv.URL, srv.URL
+package steamtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The paths of the endpoints a Server serves.
+const (
+	AppDetailsPath = "/api/appdetails"
+	AppReviewsPath = "/appreviews/"
+	PackagesPath   = "/api/packagedetails"
+	BundlesPath    = "/actions/ajaxresolvebundles"
+	SearchAppsPath = "/actions/SearchApps/"
+)
+
+// App is an app served by appdetails. Apps with an empty Initial and
+// Final aren't priced. Fields outside of the basic info are only served
+// when their filter is requested.
+type App struct {
+	Appid       int
+	Name        string
+	Type        string
+	Free        bool
+	Description string
+	Image       string
+	Reviews     int
+	ComingSoon  bool
+	ReleaseDate string
+	Languages   string // As HTML, like Steam sends
+	DLC         []int
+	Discount    int
+	Initial     string
+	Final       string
+
+	Developers []string
+	Publishers []string
+	Genres     []string
+	Categories []string
+	Platforms  []string // e.g., "windows", "mac", and "linux"
+	Metacritic int
+
+	// Served by appreviews. An empty ReviewDesc is served as
+	// "No user reviews".
+	Positive   int
+	Negative   int
+	ReviewDesc string
+}
+
+// CannedApps are apps like the ones on Steam, in the states the bot
+// cares about.
+var CannedApps = []App{
+	{
+		Appid:       400,
+		Name:        "Portal",
+		Type:        "game",
+		Description: "Portal is a new single player game from Valve.",
+		Image:       "https://example.com/400/header.jpg",
+		Reviews:     100000,
+		ReleaseDate: "Oct 10, 2007",
+		Languages:   "English<strong>*</strong>, French<br>*languages with full audio support",
+		Discount:    90,
+		Initial:     "$9.99",
+		Final:       "$0.99",
+		Developers:  []string{"Valve"},
+		Publishers:  []string{"Valve"},
+		Genres:      []string{"Action"},
+		Categories:  []string{"Single-player", "Steam Achievements"},
+		Platforms:   []string{"windows", "mac", "linux"},
+		Metacritic:  90,
+		Positive:    98000,
+		Negative:    2000,
+		ReviewDesc:  "Overwhelmingly Positive",
+	},
+	{
+		Appid:       440,
+		Name:        "Team Fortress 2",
+		Type:        "game",
+		Free:        true,
+		Description: "Nine distinct classes provide a broad range of tactical abilities.",
+		Image:       "https://example.com/440/header.jpg",
+		Reviews:     1000000,
+		ReleaseDate: "Oct 10, 2007",
+		Developers:  []string{"Valve"},
+		Publishers:  []string{"Valve"},
+		Genres:      []string{"Action", "Free to Play"},
+		Categories:  []string{"Multi-player"},
+		Platforms:   []string{"windows"},
+		Metacritic:  92,
+		Positive:    920000,
+		Negative:    80000,
+		ReviewDesc:  "Very Positive",
+	},
+	{
+		Appid:       620,
+		Name:        "Portal 2",
+		Type:        "game",
+		Description: "The sequel to Portal.",
+		Image:       "https://example.com/620/header.jpg",
+		Reviews:     300000,
+		ReleaseDate: "Apr 18, 2011",
+		DLC:         []int{323180},
+		Initial:     "$9.99",
+		Final:       "$9.99",
+		Developers:  []string{"Valve"},
+		Publishers:  []string{"Valve"},
+		Genres:      []string{"Action", "Adventure"},
+		Platforms:   []string{"windows", "mac", "linux"},
+		Metacritic:  95,
+		Positive:    295000,
+		Negative:    5000,
+		ReviewDesc:  "Overwhelmingly Positive",
+	},
+	{
+		Appid:       1868140,
+		Name:        "DAVE THE DIVER",
+		Type:        "game",
+		Description: "Explore the Blue Hole by day and run a sushi restaurant by night.",
+		Image:       "https://example.com/1868140/header.jpg",
+		ComingSoon:  true,
+		ReleaseDate: "Coming soon",
+		Developers:  []string{"MINTROCKET"},
+		Publishers:  []string{"MINTROCKET"},
+	},
+}
+
+// Package is a package served by packagedetails. Prices are in cents,
+// like Steam sends them. Packages with an Initial of 0 aren't priced.
+type Package struct {
+	Subid      int
+	Name       string
+	Image      string
+	Apps       []int // Named after the served apps
+	ComingSoon bool
+	Discount   int
+	Initial    int
+	Final      int
+}
+
+// Bundle is a bundle served by ajaxresolvebundles. Discount includes
+// BaseDiscount, the discount the bundle always has.
+type Bundle struct {
+	Bundleid     int
+	Name         string
+	Image        string
+	Apps         []int
+	BaseDiscount int
+	Discount     int
+	Initial      string
+	Final        string
+}
+
+// CannedPackages are packages like the ones on Steam.
+var CannedPackages = []Package{
+	{
+		Subid:    469,
+		Name:     "The Orange Box",
+		Image:    "https://example.com/sub/469/header.jpg",
+		Apps:     []int{400, 440},
+		Discount: 75,
+		Initial:  2999,
+		Final:    749,
+	},
+}
+
+// CannedBundles are bundles like the ones on Steam, one on sale and one
+// only at its base discount.
+var CannedBundles = []Bundle{
+	{
+		Bundleid:     232,
+		Name:         "Valve Complete Pack",
+		Image:        "https://example.com/bundle/232/header.jpg",
+		Apps:         []int{400, 440, 620},
+		BaseDiscount: 10,
+		Discount:     60,
+		Initial:      "$159.89",
+		Final:        "$63.95",
+	},
+	{
+		Bundleid:     234,
+		Name:         "Portal Bundle",
+		Image:        "https://example.com/bundle/234/header.jpg",
+		Apps:         []int{400, 620},
+		BaseDiscount: 10,
+		Discount:     10,
+		Initial:      "$19.98",
+		Final:        "$17.98",
+	},
+}
+
+// Response is a response a Server is programmed to send instead of
+// serving from its apps.
+type Response struct {
+	Status int // http.StatusOK if 0
+	Body   string
+}
+
+var (
+	// TooManyRequests is how Steam usually responds when rate limiting.
+	TooManyRequests = Response{Status: http.StatusTooManyRequests}
+	// Forbidden is how Steam sometimes responds when rate limiting.
+	Forbidden = Response{Status: http.StatusForbidden}
+	// MalformedJSON is a response that can't be decoded.
+	MalformedJSON = Response{Body: `{"400":{"success":tr`}
+)
+
+// Server is a fake Steam API. It's safe to program while serving.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	apps     map[int]App
+	packages map[int]Package
+	bundles  map[int]Bundle
+	searches map[string][]App
+	queued   map[string][]Response
+	requests map[string]int
+}
+
+// NewServer starts a Server serving apps. Close should be called to shut
+// it down.
+func NewServer(apps ...App) *Server {
+	s := &Server{
+		apps:     map[int]App{},
+		packages: map[int]Package{},
+		bundles:  map[int]Bundle{},
+		searches: map[string][]App{},
+		queued:   map[string][]Response{},
+		requests: map[string]int{},
+	}
+	s.SetApps(apps...)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(AppDetailsPath, s.serve(AppDetailsPath, s.appDetails))
+	mux.HandleFunc(AppReviewsPath+"{appid}", s.serve(AppReviewsPath, s.appReviews))
+	mux.HandleFunc(PackagesPath, s.serve(PackagesPath, s.packageDetails))
+	mux.HandleFunc(BundlesPath, s.serve(BundlesPath, s.resolveBundles))
+	mux.HandleFunc(SearchAppsPath+"{query}", s.serve(SearchAppsPath, s.searchApps))
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// SetApps serves apps, replacing any served apps with the same appids.
+func (s *Server) SetApps(apps ...App) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, app := range apps {
+		s.apps[app.Appid] = app
+	}
+}
+
+// SetPackages serves packages, replacing any served packages with the
+// same subids.
+func (s *Server) SetPackages(packages ...Package) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range packages {
+		s.packages[p.Subid] = p
+	}
+}
+
+// SetBundles serves bundles, replacing any served bundles with the same
+// bundleids.
+func (s *Server) SetBundles(bundles ...Bundle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range bundles {
+		s.bundles[b.Bundleid] = b
+	}
+}
+
+// RemoveApp stops serving the app matching appid. appdetails reports it
+// as unsuccessful afterward, like Steam does for unknown appids.
+func (s *Server) RemoveApp(appid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.apps, appid)
+}
+
+// SetSearch makes SearchApps respond to query with results. Otherwise,
+// it responds with the served apps whose name contains query.
+func (s *Server) SetSearch(query string, results ...App) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.searches[query] = results
+}
+
+// Queue makes the next requests to the endpoint at path respond with
+// resps, in order. Once they're used up, the endpoint serves from the
+// apps again.
+func (s *Server) Queue(path string, resps ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued[path] = append(s.queued[path], resps...)
+}
+
+// Requests gets the number of requests made to the endpoint at path.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// serve creates the handler of the endpoint at path. Queued responses are
+// sent before falling back to fn.
+func (s *Server) serve(path string, fn func(r *http.Request) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[path]++
+		var resp *Response
+		if queued := s.queued[path]; len(queued) > 0 {
+			resp = &queued[0]
+			s.queued[path] = queued[1:]
+		}
+		s.mu.Unlock()
+
+		if resp != nil {
+			status := resp.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(resp.Body))
+			return
+		}
+
+		s.mu.Lock()
+		body := fn(r)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// appDetails gets the details of each comma separated appid of a request,
+// keyed by appid.
+func (s *Server) appDetails(r *http.Request) any {
+	type price struct {
+		Discount int    `json:"discount_percent"`
+		Initial  string `json:"initial_formatted"`
+		Final    string `json:"final_formatted"`
+	}
+	type description struct {
+		ID          string `json:"id"`
+		Description string `json:"description"`
+	}
+	type metacritic struct {
+		Score int    `json:"score"`
+		URL   string `json:"url"`
+	}
+	type data struct {
+		Type               string `json:"type,omitempty"`
+		Name               string `json:"name"`
+		SteamAppid         int    `json:"steam_appid"`
+		IsFree             bool   `json:"is_free"`
+		ShortDescription   string `json:"short_description"`
+		HeaderImage        string `json:"header_image"`
+		SupportedLanguages string `json:"supported_languages,omitempty"`
+		DLC                []int  `json:"dlc,omitempty"`
+		Recommendations    struct {
+			Total int `json:"total"`
+		} `json:"recommendations"`
+		ReleaseDate struct {
+			ComingSoon bool   `json:"coming_soon"`
+			Date       string `json:"date"`
+		} `json:"release_date"`
+		PriceOverview *price `json:"price_overview,omitempty"`
+
+		Developers []string        `json:"developers,omitempty"`
+		Publishers []string        `json:"publishers,omitempty"`
+		Genres     []description   `json:"genres,omitempty"`
+		Categories []description   `json:"categories,omitempty"`
+		Platforms  map[string]bool `json:"platforms,omitempty"`
+		Metacritic *metacritic     `json:"metacritic,omitempty"`
+	}
+	describe := func(strs []string) []description {
+		ds := []description{}
+		for i, str := range strs {
+			ds = append(ds, description{ID: strconv.Itoa(i + 1), Description: str})
+		}
+		return ds
+	}
+	filters := strings.Split(r.URL.Query().Get("filters"), ",")
+	requested := func(filter string) bool {
+		return slices.Contains(filters, filter)
+	}
+	type details struct {
+		Success bool  `json:"success"`
+		Data    *data `json:"data,omitempty"`
+	}
+
+	res := map[string]details{}
+	for _, aid := range strings.Split(r.URL.Query().Get("appids"), ",") {
+		appid, _ := strconv.Atoi(aid)
+		app, ok := s.apps[appid]
+		if !ok {
+			res[aid] = details{Success: false}
+			continue
+		}
+
+		d := &data{
+			Type:               app.Type,
+			Name:               app.Name,
+			SteamAppid:         app.Appid,
+			IsFree:             app.Free,
+			ShortDescription:   app.Description,
+			HeaderImage:        app.Image,
+			SupportedLanguages: app.Languages,
+			DLC:                app.DLC,
+		}
+		d.Recommendations.Total = app.Reviews
+		d.ReleaseDate.ComingSoon = app.ComingSoon
+		d.ReleaseDate.Date = app.ReleaseDate
+		if requested("developers") {
+			d.Developers = app.Developers
+		}
+		if requested("publishers") {
+			d.Publishers = app.Publishers
+		}
+		if requested("genres") {
+			d.Genres = describe(app.Genres)
+		}
+		if requested("categories") {
+			d.Categories = describe(app.Categories)
+		}
+		if requested("platforms") {
+			d.Platforms = map[string]bool{
+				"windows": slices.Contains(app.Platforms, "windows"),
+				"mac":     slices.Contains(app.Platforms, "mac"),
+				"linux":   slices.Contains(app.Platforms, "linux"),
+			}
+		}
+		if requested("metacritic") && app.Metacritic != 0 {
+			d.Metacritic = &metacritic{
+				Score: app.Metacritic,
+				URL:   "https://www.metacritic.com/game/" + strconv.Itoa(app.Appid),
+			}
+		}
+		if app.Initial != "" || app.Final != "" {
+			d.PriceOverview = &price{
+				Discount: app.Discount,
+				Initial:  app.Initial,
+				Final:    app.Final,
+			}
+		}
+		res[aid] = details{Success: true, Data: d}
+	}
+
+	return res
+}
+
+// appReviews gets the review summary of the appid of a request. Unknown
+// appids have no reviews, like on Steam.
+func (s *Server) appReviews(r *http.Request) any {
+	type querySummary struct {
+		NumReviews      int    `json:"num_reviews"`
+		ReviewScoreDesc string `json:"review_score_desc"`
+		TotalPositive   int    `json:"total_positive"`
+		TotalNegative   int    `json:"total_negative"`
+		TotalReviews    int    `json:"total_reviews"`
+	}
+	type reviews struct {
+		Success      int          `json:"success"`
+		QuerySummary querySummary `json:"query_summary"`
+		Reviews      []struct{}   `json:"reviews"`
+	}
+
+	appid, _ := strconv.Atoi(r.PathValue("appid"))
+	app := s.apps[appid]
+	desc := app.ReviewDesc
+	if desc == "" {
+		desc = "No user reviews"
+	}
+
+	return reviews{
+		Success: 1,
+		QuerySummary: querySummary{
+			ReviewScoreDesc: desc,
+			TotalPositive:   app.Positive,
+			TotalNegative:   app.Negative,
+			TotalReviews:    app.Positive + app.Negative,
+		},
+		Reviews: []struct{}{},
+	}
+}
+
+// packageDetails gets the details of each comma separated subid of a
+// request, keyed by subid.
+func (s *Server) packageDetails(r *http.Request) any {
+	type app struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type price struct {
+		Currency string `json:"currency"`
+		Initial  int    `json:"initial"`
+		Final    int    `json:"final"`
+		Discount int    `json:"discount_percent"`
+	}
+	type data struct {
+		Name        string `json:"name"`
+		PageImage   string `json:"page_image"`
+		HeaderImage string `json:"header_image"`
+		Apps        []app  `json:"apps"`
+		Price       *price `json:"price,omitempty"`
+		ReleaseDate struct {
+			ComingSoon bool   `json:"coming_soon"`
+			Date       string `json:"date"`
+		} `json:"release_date"`
+	}
+	type details struct {
+		Success bool  `json:"success"`
+		Data    *data `json:"data,omitempty"`
+	}
+
+	res := map[string]details{}
+	for _, sid := range strings.Split(r.URL.Query().Get("packageids"), ",") {
+		subid, _ := strconv.Atoi(sid)
+		p, ok := s.packages[subid]
+		if !ok {
+			res[sid] = details{Success: false}
+			continue
+		}
+
+		d := &data{Name: p.Name, PageImage: p.Image, HeaderImage: p.Image, Apps: []app{}}
+		for _, appid := range p.Apps {
+			d.Apps = append(d.Apps, app{ID: appid, Name: s.apps[appid].Name})
+		}
+		d.ReleaseDate.ComingSoon = p.ComingSoon
+		if p.Initial != 0 {
+			d.Price = &price{
+				Currency: "USD",
+				Initial:  p.Initial,
+				Final:    p.Final,
+				Discount: p.Discount,
+			}
+		}
+		res[sid] = details{Success: true, Data: d}
+	}
+
+	return res
+}
+
+// resolveBundles gets the bundles of each comma separated bundleid of a
+// request. Unknown bundleids are left out, like on Steam.
+func (s *Server) resolveBundles(r *http.Request) any {
+	type bundle struct {
+		Bundleid           int    `json:"bundleid"`
+		Name               string `json:"name"`
+		URL                string `json:"url"`
+		HeaderImageURL     string `json:"header_image_url"`
+		Appids             []int  `json:"appids"`
+		BundleBaseDiscount int    `json:"bundle_base_discount"`
+		DiscountPercent    int    `json:"discount_percent"`
+		FormattedOrigPrice string `json:"formatted_orig_price"`
+		FormattedFinal     string `json:"formatted_final_price"`
+	}
+
+	res := []bundle{}
+	for _, bid := range strings.Split(r.URL.Query().Get("bundleids"), ",") {
+		bundleid, _ := strconv.Atoi(bid)
+		b, ok := s.bundles[bundleid]
+		if !ok {
+			continue
+		}
+
+		res = append(res, bundle{
+			Bundleid:           b.Bundleid,
+			Name:               b.Name,
+			URL:                "/bundle/" + bid + "/",
+			HeaderImageURL:     b.Image,
+			Appids:             b.Apps,
+			BundleBaseDiscount: b.BaseDiscount,
+			DiscountPercent:    b.Discount,
+			FormattedOrigPrice: b.Initial,
+			FormattedFinal:     b.Final,
+		})
+	}
+
+	return res
+}
+
+// searchApps gets the apps matching the query of a request.
+func (s *Server) searchApps(r *http.Request) any {
+	type result struct {
+		Appid string `json:"appid"`
+		Name  string `json:"name"`
+	}
+
+	query := r.PathValue("query")
+	apps, ok := s.searches[query]
+	if !ok {
+		for _, app := range s.apps {
+			if strings.Contains(strings.ToLower(app.Name), strings.ToLower(query)) {
+				apps = append(apps, app)
+			}
+		}
+		sort.Slice(apps, func(i, j int) bool { return apps[i].Appid < apps[j].Appid })
+	}
+
+	res := make([]result, 0, len(apps))
+	for _, app := range apps {
+		res = append(res, result{Appid: strconv.Itoa(app.Appid), Name: app.Name})
+	}
+
+	return res
+}
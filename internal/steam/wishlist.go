@@ -0,0 +1,192 @@
+package steam
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WishlistItem is an app on a Steam wishlist.
+type WishlistItem struct {
+	Appid      int
+	Name       string
+	Priority   int
+	ComingSoon bool
+	Free       bool
+}
+
+// rawWishlistItem is the form Steam API naturally returns
+type rawWishlistItem struct {
+	Name       string `json:"name"`
+	Priority   int    `json:"priority"`
+	Prerelease int    `json:"prerelease"`
+	IsFreeGame bool   `json:"is_free_game"`
+}
+
+// WishlistFetcher resolves Steam profiles and fetches their public wishlists.
+type WishlistFetcher interface {
+	// ResolveProfile finds the SteamID64 of a profile given as a
+	// SteamID64, a profile URL, or a custom (vanity) profile URL.
+	ResolveProfile(ctx context.Context, profile string) (int64, error)
+	// Wishlist fetches the public wishlist of the profile matching steamID.
+	Wishlist(ctx context.Context, steamID int64) ([]WishlistItem, error)
+}
+
+// StoreWishlist is a WishlistFetcher that uses the store's wishlist data
+// endpoint. The base URLs can be pointed elsewhere, e.g., for tests.
+type StoreWishlist struct {
+	StoreURL     string
+	CommunityURL string
+}
+
+// maxWishlistPages is the most pages of a wishlist that are fetched.
+const maxWishlistPages = 50
+
+var (
+	ErrPrivateWishlist = errors.New("wishlist is private or profile doesn't exist")
+	ErrInvalidProfile  = errors.New("invalid profile, expected a SteamID64 or profile URL")
+)
+
+var (
+	steamID64Regex  = regexp.MustCompile(`^\d{17}$`)
+	profilesRegex   = regexp.MustCompile(`steamcommunity\.com/profiles/(\d{17})`)
+	vanityRegex     = regexp.MustCompile(`steamcommunity\.com/id/([\w-]+)`)
+	errWishlistDone = errors.New("no more wishlist pages")
+)
+
+// NewStoreWishlist creates a StoreWishlist for the Steam API at StoreURL
+// and CommunityURL.
+func NewStoreWishlist() *StoreWishlist {
+	return &StoreWishlist{
+		StoreURL:     StoreURL,
+		CommunityURL: CommunityURL,
+	}
+}
+
+// ResolveProfile finds the SteamID64 of a profile given as a SteamID64,
+// a profile URL, or a custom (vanity) profile URL.
+func (w *StoreWishlist) ResolveProfile(ctx context.Context, profile string) (int64, error) {
+	profile = strings.TrimSpace(profile)
+
+	switch {
+	case steamID64Regex.MatchString(profile):
+		return strconv.ParseInt(profile, 10, 64)
+
+	case profilesRegex.MatchString(profile):
+		return strconv.ParseInt(profilesRegex.FindStringSubmatch(profile)[1], 10, 64)
+
+	case vanityRegex.MatchString(profile):
+		return w.resolveVanity(ctx, vanityRegex.FindStringSubmatch(profile)[1])
+	}
+
+	return 0, ErrInvalidProfile
+}
+
+// resolveVanity finds the SteamID64 of a custom profile URL name through
+// the XML form of the profile page.
+func (w *StoreWishlist) resolveVanity(ctx context.Context, name string) (int64, error) {
+	resp, err := get(ctx, w.CommunityURL+"/id/"+url.PathEscape(name)+"/?xml=1")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var profile struct {
+		SteamID64 string `xml:"steamID64"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&profile); err != nil || profile.SteamID64 == "" {
+		return 0, ErrInvalidProfile
+	}
+
+	return strconv.ParseInt(profile.SteamID64, 10, 64)
+}
+
+// Wishlist fetches the public wishlist of the profile matching steamID,
+// ordered by the user's priority.
+//
+// More information: https://github.com/Revadike/InternalSteamWebAPI/wiki/Get-Wishlist-Data
+func (w *StoreWishlist) Wishlist(ctx context.Context, steamID int64) ([]WishlistItem, error) {
+	items := []WishlistItem{}
+
+	for page := 0; page < maxWishlistPages; page++ {
+		pageItems, err := w.wishlistPage(ctx, steamID, page)
+		if err == errWishlistDone {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		items = append(items, pageItems...)
+	}
+
+	sort.Slice(items, func(a, b int) bool {
+		// Priority 0 means unranked, so it goes last
+		pa, pb := items[a].Priority, items[b].Priority
+		switch {
+		case (pa == 0) != (pb == 0):
+			return pb == 0
+		case pa != pb:
+			return pa < pb
+		default:
+			return items[a].Appid < items[b].Appid
+		}
+	})
+
+	return items, nil
+}
+
+// wishlistPage fetches a single page of a wishlist. errWishlistDone is
+// returned when page is past the last page.
+func (w *StoreWishlist) wishlistPage(ctx context.Context, steamID int64, page int) (
+	[]WishlistItem, error) {
+	endpoint := fmt.Sprintf("%s/wishlist/profiles/%d/wishlistdata/?p=%d",
+		w.StoreURL, steamID, page)
+
+	var raw json.RawMessage
+	if err := apiGet(ctx, endpoint, &raw); err != nil {
+		return nil, err
+	}
+
+	// Past the last page, an empty array is returned instead of an object
+	if len(raw) > 0 && raw[0] == '[' {
+		return nil, errWishlistDone
+	}
+
+	// Private wishlists return {"success": 2}
+	var status struct {
+		Success *int `json:"success"`
+	}
+	if err := json.Unmarshal(raw, &status); err == nil && status.Success != nil {
+		return nil, ErrPrivateWishlist
+	}
+
+	rawItems := map[string]rawWishlistItem{}
+	if err := json.Unmarshal(raw, &rawItems); err != nil {
+		return nil, err
+	}
+
+	items := make([]WishlistItem, 0, len(rawItems))
+	for aid, raw := range rawItems {
+		appid, err := strconv.Atoi(aid)
+		if err != nil {
+			continue
+		}
+
+		items = append(items, WishlistItem{
+			Appid:      appid,
+			Name:       raw.Name,
+			Priority:   raw.Priority,
+			ComingSoon: raw.Prerelease == 1,
+			Free:       raw.IsFreeGame,
+		})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,120 @@
+package steam
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type wishlistShould struct {
+	suite.Suite
+	server *httptest.Server
+	// pages are the bodies served for each wishlist page, in order.
+	// Pages past the end are served as an empty array.
+	pages    []string
+	wishlist *StoreWishlist
+}
+
+func (s *wishlistShould) SetupTest() {
+	s.pages = nil
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/wishlist/profiles/{id}/wishlistdata/", func(w http.ResponseWriter, r *http.Request) {
+		var page int
+		fmt.Sscan(r.URL.Query().Get("p"), &page)
+		if page >= len(s.pages) {
+			fmt.Fprint(w, "[]")
+			return
+		}
+		fmt.Fprint(w, s.pages[page])
+	})
+	mux.HandleFunc("/id/{name}/", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") != "vanity" {
+			fmt.Fprint(w, "<response><error>The specified profile could not be found.</error></response>")
+			return
+		}
+		fmt.Fprint(w, "<profile><steamID64>76561197960287930</steamID64></profile>")
+	})
+	s.server = httptest.NewServer(mux)
+
+	s.wishlist = &StoreWishlist{StoreURL: s.server.URL, CommunityURL: s.server.URL}
+	client = s.server.Client()
+	retryAt.Time = time.Time{}
+}
+
+func (s *wishlistShould) TearDownTest() {
+	s.server.Close()
+}
+
+func TestWishlistShould(t *testing.T) {
+	suite.Run(t, new(wishlistShould))
+}
+
+func (s *wishlistShould) TestFetchAllPagesInPriorityOrder() {
+	s.pages = []string{
+		`{"400":{"name":"Portal","priority":2},"440":{"name":"Team Fortress 2","priority":0,"is_free_game":true}}`,
+		`{"620":{"name":"Portal 2","priority":1,"prerelease":1}}`,
+	}
+
+	items, err := s.wishlist.Wishlist(context.Background(), 1)
+
+	s.Nil(err)
+	s.Equal([]WishlistItem{
+		{Appid: 620, Name: "Portal 2", Priority: 1, ComingSoon: true},
+		{Appid: 400, Name: "Portal", Priority: 2},
+		{Appid: 440, Name: "Team Fortress 2", Free: true},
+	}, items)
+}
+
+func (s *wishlistShould) TestEmptyOnEmptyWishlist() {
+	items, err := s.wishlist.Wishlist(context.Background(), 1)
+
+	s.Nil(err)
+	s.Empty(items)
+}
+
+func (s *wishlistShould) TestErrOnPrivateWishlist() {
+	s.pages = []string{`{"success":2}`}
+
+	_, err := s.wishlist.Wishlist(context.Background(), 1)
+
+	s.ErrorIs(err, ErrPrivateWishlist)
+}
+
+func (s *wishlistShould) TestResolveSteamID64() {
+	id, err := s.wishlist.ResolveProfile(context.Background(), "76561197960287930")
+
+	s.Nil(err)
+	s.Equal(int64(76561197960287930), id)
+}
+
+func (s *wishlistShould) TestResolveProfileURL() {
+	id, err := s.wishlist.ResolveProfile(context.Background(), "https://steamcommunity.com/profiles/76561197960287930/")
+
+	s.Nil(err)
+	s.Equal(int64(76561197960287930), id)
+}
+
+func (s *wishlistShould) TestResolveVanityURL() {
+	id, err := s.wishlist.ResolveProfile(context.Background(), "https://steamcommunity.com/id/vanity")
+
+	s.Nil(err)
+	s.Equal(int64(76561197960287930), id)
+}
+
+func (s *wishlistShould) TestErrOnUnknownVanityURL() {
+	_, err := s.wishlist.ResolveProfile(context.Background(), "https://steamcommunity.com/id/unknown")
+
+	s.ErrorIs(err, ErrInvalidProfile)
+}
+
+func (s *wishlistShould) TestErrOnInvalidProfile() {
+	_, err := s.wishlist.ResolveProfile(context.Background(), "not a profile")
+
+	s.ErrorIs(err, ErrInvalidProfile)
+}
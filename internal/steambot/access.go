@@ -0,0 +1,136 @@
+package steambot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/cmd"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+)
+
+// channelDeleteHandler unbinds a guild if its bound channel is deleted.
+func (b *SteamBot) channelDeleteHandler(s *discordgo.Session, c *discordgo.ChannelDelete) {
+	guildID, err := strconv.ParseInt(c.GuildID, 10, 64)
+	if err != nil {
+		return
+	}
+	channelID, err := strconv.ParseInt(c.ID, 10, 64)
+	if err != nil {
+		return
+	}
+
+	lostChannelAccess(b.ctx, s, guildID, channelID, "The bound channel was deleted.")
+}
+
+// channelUpdateHandler checks the bound channel is still sendable after
+// a channel's permission overwrites may have changed.
+func (b *SteamBot) channelUpdateHandler(s *discordgo.Session, c *discordgo.ChannelUpdate) {
+	verifyChannelAccess(b.ctx, s, c.GuildID)
+}
+
+// guildRoleUpdateHandler checks the bound channel is still sendable after
+// a role's permissions may have changed.
+func (b *SteamBot) guildRoleUpdateHandler(s *discordgo.Session, r *discordgo.GuildRoleUpdate) {
+	verifyChannelAccess(b.ctx, s, r.GuildID)
+}
+
+// guildRoleDeleteHandler checks the bound channel is still sendable after
+// a role, possibly one the bot had, is deleted.
+func (b *SteamBot) guildRoleDeleteHandler(s *discordgo.Session, r *discordgo.GuildRoleDelete) {
+	verifyChannelAccess(b.ctx, s, r.GuildID)
+}
+
+// guildMemberUpdateHandler checks the bound channel is still sendable after
+// the bot's roles may have changed.
+func (b *SteamBot) guildMemberUpdateHandler(s *discordgo.Session, m *discordgo.GuildMemberUpdate) {
+	if m.User == nil || m.User.ID != s.State.User.ID {
+		return
+	}
+
+	verifyChannelAccess(b.ctx, s, m.GuildID)
+}
+
+// verifyChannelAccess unbinds a guild if the bot can no longer send
+// alerts to its bound channel.
+func verifyChannelAccess(ctx context.Context, s *discordgo.Session, gid string) {
+	guildID, err := strconv.ParseInt(gid, 10, 64)
+	if err != nil {
+		return
+	}
+
+	guild, err := db.Guild(ctx, guildID)
+	if err != nil || guild.ChannelID == 0 {
+		return
+	}
+	channelID := strconv.FormatInt(guild.ChannelID, 10)
+
+	// An uncached channel isn't evidence of lost access. Deleted
+	// channels are handled by channelDeleteHandler.
+	if _, err := s.State.Channel(channelID); err != nil {
+		return
+	}
+	if cmd.CanSendAlerts(s.State, channelID) {
+		return
+	}
+
+	lostChannelAccess(ctx, s, guildID, guild.ChannelID,
+		"The bot is missing view channel, send message, or embed links "+
+			"permissions for the bound channel.")
+}
+
+// isLostAccess reports whether a failed send means the bot can no longer
+// deliver to the channel, i.e., Discord responded with 403 or 404.
+func isLostAccess(err error) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Response == nil {
+		return false
+	}
+
+	code := restErr.Response.StatusCode
+	return code == http.StatusForbidden || code == http.StatusNotFound
+}
+
+// lostChannelAccess unbinds a guild from channelID and tells the guild how
+// to bind again. Nothing happens if the guild is no longer bound to channelID.
+func lostChannelAccess(ctx context.Context, s *discordgo.Session, guildID, channelID int64,
+	reason string) {
+	guild, err := db.Guild(ctx, guildID)
+	if err != nil || guild.ChannelID != channelID {
+		return
+	}
+
+	if err := db.SetChannelID(ctx, guildID, 0); err != nil {
+		return
+	}
+
+	notifyGuild(s, strconv.FormatInt(guildID, 10), reason+
+		" Alerts are paused until a server manager uses "+
+		"**/bind <text_channel>** to choose a channel again.")
+}
+
+// notifyGuild tries to DM the owner of a guild. If that fails, it tries
+// posting in the guild's system channel instead.
+func notifyGuild(s *discordgo.Session, guildID, description string) {
+	g, err := s.State.Guild(guildID)
+	if err != nil {
+		return
+	}
+
+	em := &discordgo.MessageEmbed{
+		Title:       "Steam Sale Bot alerts paused in " + g.Name,
+		Description: description,
+	}
+
+	if dm, err := s.UserChannelCreate(g.OwnerID); err == nil {
+		if _, err := s.ChannelMessageSendEmbed(dm.ID, em); err == nil {
+			return
+		}
+	}
+
+	if g.SystemChannelID != "" && cmd.CanSendAlerts(s.State, g.SystemChannelID) {
+		s.ChannelMessageSendEmbed(g.SystemChannelID, em)
+	}
+}
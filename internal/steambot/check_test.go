@@ -0,0 +1,458 @@
+package steambot
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/clock/clocktest"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/cmd"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
+	"github.com/stretchr/testify/suite"
+)
+
+// dailyCheckShould runs the daily check end-to-end, against a fake Steam API
+// and an in-memory store.
+type dailyCheckShould struct {
+	suite.Suite
+	server     *steamtest.Server
+	store      *memStore
+	sender     *recordingSender
+	clk        *clocktest.Clock
+	lostAccess []int64 // Guilds that lost access to their channel
+	checker    *checker
+
+	storeURL, communityURL string
+}
+
+func (s *dailyCheckShould) SetupTest() {
+	s.server = steamtest.NewServer(steamtest.CannedApps...)
+	s.server.SetApps(
+		steamtest.App{Appid: 700, Name: "Released", Initial: "$19.99", Final: "$19.99"},
+		steamtest.App{Appid: 800, Name: "Small Sale", Discount: 10, Initial: "$10.00", Final: "$9.00"},
+	)
+	s.storeURL, s.communityURL = steam.StoreURL, steam.CommunityURL
+	steam.StoreURL, steam.CommunityURL = s.server.URL, s.server.URL
+
+	// Guild 1 alerts on sales of at least 50%
+	s.store = newMemStore()
+	s.store.addGuild(1, 10, 50)
+	s.store.track(1, 400, "Portal", db.JunctionInfo{})
+	s.store.track(1, 620, "Portal 2", db.JunctionInfo{})
+	s.store.track(1, 700, "Released", db.JunctionInfo{ComingSoon: true})
+	s.store.track(1, 800, "Small Sale", db.JunctionInfo{})
+	s.store.track(1, 1868140, "DAVE THE DIVER", db.JunctionInfo{ComingSoon: true})
+	// Guild 2 was already alerted about Portal, and alerts on Small Sale at 10%
+	s.store.addGuild(2, 20, 95)
+	s.store.track(2, 400, "Portal", db.JunctionInfo{TrailingSaleDay: true})
+	s.store.track(2, 800, "Small Sale", db.JunctionInfo{SaleThreshold: 10})
+	// Guild 3 isn't bound to a channel
+	s.store.addGuild(3, 0, 1)
+	s.store.track(3, 400, "Portal", db.JunctionInfo{})
+
+	s.sender = &recordingSender{}
+	s.clk = clocktest.NewClock(time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC))
+	s.lostAccess = nil
+	s.checker = &checker{
+		store:  s.store,
+		sender: s.sender,
+		clk:    s.clk,
+		lostAccess: func(_ context.Context, guildID, _ int64, _ string) {
+			s.lostAccess = append(s.lostAccess, guildID)
+		},
+	}
+}
+
+func (s *dailyCheckShould) TearDownTest() {
+	steam.StoreURL, steam.CommunityURL = s.storeURL, s.communityURL
+	s.server.Close()
+}
+
+func TestDailyCheckShould(t *testing.T) {
+	suite.Run(t, new(dailyCheckShould))
+}
+
+func (s *dailyCheckShould) TestSendAlertsAppsWarrant() {
+	err := s.checker.checkAllApps(context.Background())
+
+	s.Nil(err)
+	s.Equal([]string{
+		"Portal is on sale for 90% off!",
+		"Released has released on Steam!",
+	}, s.sender.titles("10"))
+	s.Equal([]string{"Small Sale is on sale for 10% off!"}, s.sender.titles("20"))
+	s.Empty(s.sender.titles("0"))
+	s.Equal(5, s.server.Requests(steamtest.AppDetailsPath))
+}
+
+func (s *dailyCheckShould) TestUpdateTrackedState() {
+	s.checker.checkAllApps(context.Background())
+
+	s.True(s.store.junction(1, 400).TrailingSaleDay)
+	s.False(s.store.junction(1, 620).TrailingSaleDay)
+	s.True(s.store.junction(1, 800).TrailingSaleDay)
+	s.True(s.store.junction(3, 400).TrailingSaleDay)
+	s.False(s.store.junction(1, 700).ComingSoon)
+	s.True(s.store.junction(1, 1868140).ComingSoon)
+}
+
+func (s *dailyCheckShould) TestRecordDeliveredAlerts() {
+	s.checker.checkAllApps(context.Background())
+
+	s.Len(s.store.outbox, 3)
+	for _, alert := range s.store.outbox {
+		s.Equal(db.AlertSent, alert.State)
+		s.Len(alert.Attempts, 1)
+	}
+	s.Len(s.store.history, 3)
+	for _, history := range s.store.history {
+		s.Equal(db.AlertSent, history.State)
+	}
+}
+
+func (s *dailyCheckShould) TestNotRepeatAlertsOnNextDay() {
+	s.checker.checkAllApps(context.Background())
+	s.checker.checkAllApps(context.Background())
+
+	s.Equal([]string{
+		"Portal is on sale for 90% off!",
+		"Released has released on Steam!",
+	}, s.sender.titles("10"))
+	s.Len(s.sender.titles("20"), 1)
+}
+
+func (s *dailyCheckShould) TestAlertAgainOnceSaleEnds() {
+	s.checker.checkAllApps(context.Background())
+	s.server.SetApps(steamtest.App{Appid: 400, Name: "Portal", Initial: "$9.99", Final: "$9.99"})
+	s.checker.checkAllApps(context.Background())
+	s.server.SetApps(steamtest.CannedApps[0])
+	s.checker.checkAllApps(context.Background())
+
+	s.Equal([]string{
+		"Portal is on sale for 90% off!",
+		"Released has released on Steam!",
+		"Portal is on sale for 90% off!",
+	}, s.sender.titles("10"))
+}
+
+func (s *dailyCheckShould) TestAbortOnInvalidApp() {
+	s.server.RemoveApp(620)
+
+	err := s.checker.checkAllApps(context.Background())
+
+	// Apps are checked in order of appid, so only Portal was checked
+	s.Error(err)
+	s.Equal([]string{"Portal is on sale for 90% off!"}, s.sender.titles("10"))
+	s.False(s.store.junction(1, 800).TrailingSaleDay)
+}
+
+func (s *dailyCheckShould) TestAbortOnMalformedResponse() {
+	s.server.Queue(steamtest.AppDetailsPath, steamtest.MalformedJSON)
+
+	err := s.checker.checkAllApps(context.Background())
+
+	s.Error(err)
+	s.Empty(s.sender.titles("10"))
+}
+
+func (s *dailyCheckShould) TestDeadLetterAlertsToLostChannels() {
+	s.sender.err = &discordgo.RESTError{
+		Response: &http.Response{StatusCode: http.StatusForbidden},
+	}
+
+	s.checker.checkAllApps(context.Background())
+
+	for _, alert := range s.store.outbox {
+		s.Equal(db.AlertDead, alert.State)
+	}
+	s.ElementsMatch([]int64{1, 1, 2}, s.lostAccess)
+}
+
+func (s *dailyCheckShould) TestRetryFailedDeliveriesInBackground() {
+	s.sender.errs = []error{&discordgo.RESTError{
+		Response: &http.Response{StatusCode: http.StatusServiceUnavailable},
+	}}
+
+	err := s.checker.checkAllApps(context.Background())
+
+	// The check finished without waiting out the backoff
+	s.Require().Nil(err)
+	s.Len(s.sender.titles("10"), 1)
+	s.Len(s.sender.titles("20"), 1)
+	pending, _ := s.store.PendingAlerts(context.Background())
+	s.Require().Len(pending, 1)
+	s.Len(pending[0].Attempts, 1)
+
+	s.clk.Advance(firstDeliveryBackoff)
+
+	s.Eventually(func() bool {
+		pending, _ := s.store.PendingAlerts(context.Background())
+		return len(pending) == 0
+	}, time.Second, time.Millisecond)
+	s.Len(s.sender.titles("10"), 2)
+}
+
+func (s *dailyCheckShould) TestSkipGuildsBotWasRemovedFrom() {
+	guild := s.store.guilds[1]
+	guild.MissingSince = time.Now()
+	s.store.guilds[1] = guild
+
+	s.checker.checkAllApps(context.Background())
+	summary, err := s.checker.checkGuild(context.Background(), 1, func(int, int) {})
+
+	s.Empty(s.sender.titles("10"))
+	s.Len(s.sender.titles("20"), 1)
+	s.Nil(err)
+	s.Zero(summary.Checked)
+}
+
+func (s *dailyCheckShould) TestStopWhenCancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := s.checker.checkAllApps(ctx)
+
+	s.ErrorIs(err, context.Canceled)
+	s.Empty(s.store.outbox)
+}
+
+func (s *dailyCheckShould) TestShowLowestAlertedOfEarlierAlerts() {
+	s.checker.checkAllApps(context.Background())
+	s.server.SetApps(steamtest.App{Appid: 400, Name: "Portal", Initial: "$9.99", Final: "$9.99"})
+	s.checker.checkAllApps(context.Background())
+	s.server.SetApps(steamtest.CannedApps[0])
+	s.checker.checkAllApps(context.Background())
+
+	sent := s.sender.sent["10"]
+	s.NotContains(fieldNames(sent[0]), "Lowest Alerted")
+	s.Contains(fieldNames(sent[2]), "Lowest Alerted")
+}
+
+func (s *dailyCheckShould) TestLeaveOutFieldsGuildsHid() {
+	shown, err := s.checker.previewAlert(context.Background(), 1, 400)
+	s.Require().Nil(err)
+	s.Subset(fieldNames(shown), []string{"Reviews", "Description"})
+	guild := s.store.guilds[1]
+	guild.HiddenFields = []string{cmd.FieldReviews, cmd.FieldDescription}
+	s.store.guilds[1] = guild
+
+	s.checker.checkAllApps(context.Background())
+
+	s.NotContains(fieldNames(s.sender.sent["10"][0]), "Reviews")
+	s.NotContains(fieldNames(s.sender.sent["10"][0]), "Description")
+}
+
+func (s *dailyCheckShould) TestShowReviewSummary() {
+	s.checker.checkAllApps(context.Background())
+
+	s.Equal("Overwhelmingly Positive, 98%", s.sender.sent["10"][0].Fields[2].Value)
+	s.Equal(2, s.server.Requests(steamtest.AppReviewsPath))
+}
+
+func (s *dailyCheckShould) TestSkipSalesOfPoorlyReviewedApps() {
+	guild := s.store.guilds[1]
+	guild.MinReviewScore = 99
+	s.store.guilds[1] = guild
+
+	s.checker.checkAllApps(context.Background())
+
+	s.Equal([]string{"Released has released on Steam!"}, s.sender.titles("10"))
+	s.True(s.store.junction(1, 400).TrailingSaleDay)
+	// Small Sale has no reviews, so it's still alerted
+	s.Equal([]string{"Small Sale is on sale for 10% off!"}, s.sender.titles("20"))
+}
+
+func (s *dailyCheckShould) TestAlertWellReviewedApps() {
+	for _, guildID := range []int64{1, 2} {
+		guild := s.store.guilds[guildID]
+		guild.MinReviewScore = 98
+		s.store.guilds[guildID] = guild
+	}
+	s.server.SetApps(steamtest.App{
+		Appid: 800, Name: "Small Sale", Discount: 10, Initial: "$10.00", Final: "$9.00",
+		Positive: 1, Negative: 1, ReviewDesc: "Mixed",
+	})
+
+	s.checker.checkAllApps(context.Background())
+
+	s.Contains(s.sender.titles("10"), "Portal is on sale for 90% off!")
+	s.Empty(s.sender.titles("20"))
+}
+
+func (s *dailyCheckShould) TestAlertWhenReviewsAreUnknown() {
+	guild := s.store.guilds[1]
+	guild.MinReviewScore = 100
+	s.store.guilds[1] = guild
+	s.server.Queue(steamtest.AppReviewsPath, steamtest.MalformedJSON)
+
+	s.checker.checkAllApps(context.Background())
+
+	s.Contains(s.sender.titles("10"), "Portal is on sale for 90% off!")
+}
+
+func (s *dailyCheckShould) TestAlertOnPackagesAndBundles() {
+	s.server.SetPackages(steamtest.CannedPackages...)
+	s.server.SetBundles(steamtest.CannedBundles...)
+	s.store.trackItem(1, steam.Item{Kind: steam.KindSub, ID: 469}, "The Orange Box",
+		db.JunctionInfo{})
+	s.store.trackItem(1, steam.Item{Kind: steam.KindBundle, ID: 232}, "Valve Complete Pack",
+		db.JunctionInfo{})
+	s.store.trackItem(1, steam.Item{Kind: steam.KindBundle, ID: 234}, "Portal Bundle",
+		db.JunctionInfo{})
+
+	err := s.checker.checkAllApps(context.Background())
+
+	s.Nil(err)
+	s.Equal([]string{
+		"Portal is on sale for 90% off!",
+		"Released has released on Steam!",
+		"Valve Complete Pack is on sale for 60% off!",
+		"The Orange Box is on sale for 75% off!",
+	}, s.sender.titles("10"))
+	s.True(s.store.itemJunction(1, steam.Item{Kind: steam.KindSub, ID: 469}).TrailingSaleDay)
+	s.False(s.store.itemJunction(1, steam.Item{Kind: steam.KindBundle, ID: 234}).TrailingSaleDay)
+	s.Equal(1, s.server.Requests(steamtest.PackagesPath))
+	s.Equal(2, s.server.Requests(steamtest.BundlesPath))
+}
+
+func (s *dailyCheckShould) TestKeepHistoryOfPackagesApartFromApps() {
+	s.server.SetPackages(steamtest.CannedPackages...)
+	s.store.trackItem(1, steam.Item{Kind: steam.KindSub, ID: 469}, "The Orange Box",
+		db.JunctionInfo{})
+
+	s.checker.checkAllApps(context.Background())
+	_, ok, err := s.store.LowestAlerted(context.Background(), 469)
+
+	s.Nil(err)
+	s.False(ok)
+	s.Require().NotEmpty(s.store.history)
+	last := s.store.history[len(s.store.history)-1]
+	s.Equal(steam.Item{Kind: steam.KindSub, ID: 469}, last.Item())
+	s.Equal(db.AlertSale, last.Kind)
+}
+
+func (s *dailyCheckShould) TestNotRepeatAlertsOfOverlappingChecks() {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.checker.checkAllApps(context.Background())
+	}()
+	go func() {
+		defer wg.Done()
+		s.checker.checkGuild(context.Background(), 1, func(int, int) {})
+	}()
+	wg.Wait()
+
+	s.Equal(1, countOf(s.sender.titles("10"), "Portal is on sale for 90% off!"))
+	s.Equal(1, countOf(s.sender.titles("10"), "Released has released on Steam!"))
+}
+
+// trackDLC has guild 1 track the DLC of Portal 2, knowing of known.
+func (s *dailyCheckShould) trackDLC(known ...int) {
+	j := s.store.junction(1, 620)
+	j.TrackDLC, j.KnownDLC = true, known
+}
+
+func (s *dailyCheckShould) TestAddAndAlertNewDLC() {
+	s.server.SetApps(steamtest.App{Appid: 323180, Name: "Portal 2 Soundtrack", Type: "dlc",
+		Initial: "$4.99", Final: "$4.99"})
+	s.trackDLC()
+
+	s.checker.checkAllApps(context.Background())
+
+	s.Contains(s.sender.titles("10"), "New DLC released for Portal 2!")
+	s.Require().NotNil(s.store.junction(1, 323180))
+	s.Equal(620, s.store.junction(1, 323180).DLCOf)
+	s.Equal([]int{323180}, s.store.junction(1, 620).KnownDLC)
+}
+
+func (s *dailyCheckShould) TestNotAlertKnownDLC() {
+	s.server.SetApps(steamtest.App{Appid: 323180, Name: "Portal 2 Soundtrack", Type: "dlc",
+		Initial: "$4.99", Final: "$4.99"})
+	s.trackDLC(323180)
+
+	s.checker.checkAllApps(context.Background())
+
+	s.NotContains(s.sender.titles("10"), "New DLC released for Portal 2!")
+	s.Nil(s.store.junction(1, 323180))
+}
+
+func (s *dailyCheckShould) TestNotAddDLCOfGamesNotTrackingIt() {
+	s.server.SetApps(steamtest.App{Appid: 323180, Name: "Portal 2 Soundtrack", Type: "dlc",
+		Initial: "$4.99", Final: "$4.99"})
+
+	s.checker.checkAllApps(context.Background())
+
+	s.NotContains(s.sender.titles("10"), "New DLC released for Portal 2!")
+	s.Nil(s.store.junction(1, 323180))
+	s.Equal(5, s.server.Requests(steamtest.AppDetailsPath))
+}
+
+func (s *dailyCheckShould) TestAlertButNotAddFreeDLC() {
+	s.server.SetApps(steamtest.App{Appid: 323180, Name: "Portal 2 Soundtrack", Type: "dlc",
+		Free: true})
+	s.trackDLC()
+
+	s.checker.checkAllApps(context.Background())
+
+	s.Contains(s.sender.titles("10"), "New DLC released for Portal 2!")
+	s.Nil(s.store.junction(1, 323180))
+	s.Equal([]int{323180}, s.store.junction(1, 620).KnownDLC)
+}
+
+func (s *dailyCheckShould) TestRetryDLCThatFailedToFetch() {
+	s.trackDLC()
+
+	s.checker.checkAllApps(context.Background())
+	s.server.SetApps(steamtest.App{Appid: 323180, Name: "Portal 2 Soundtrack", Type: "dlc",
+		Initial: "$4.99", Final: "$4.99"})
+	s.checker.checkAllApps(context.Background())
+
+	s.Equal(1, countOf(s.sender.titles("10"), "New DLC released for Portal 2!"))
+	s.NotNil(s.store.junction(1, 323180))
+}
+
+// countOf counts the occurrences of v in vs.
+func countOf(vs []string, v string) (n int) {
+	for _, other := range vs {
+		if other == v {
+			n++
+		}
+	}
+	return n
+}
+
+// fieldNames gets the names of em's fields.
+func fieldNames(em *discordgo.MessageEmbed) []string {
+	names := []string{}
+	for _, field := range em.Fields {
+		names = append(names, field.Name)
+	}
+	return names
+}
+
+func (s *dailyCheckShould) TestPreviewAlertWithoutChangingState() {
+	sale, err := s.checker.previewAlert(context.Background(), 1, 400)
+	s.Require().Nil(err)
+	release, err := s.checker.previewAlert(context.Background(), 1, 700)
+	s.Require().Nil(err)
+
+	s.Equal("Portal is on sale for 90% off!", sale.Title)
+	s.Equal("Released has released on Steam!", release.Title)
+	s.Empty(s.sender.sent)
+	s.Empty(s.store.outbox)
+	s.False(s.store.junction(1, 400).TrailingSaleDay)
+	s.True(s.store.junction(1, 700).ComingSoon)
+}
+
+func (s *dailyCheckShould) TestErrPreviewingUnknownApp() {
+	_, err := s.checker.previewAlert(context.Background(), 1, 1)
+
+	s.Error(err)
+}
@@ -0,0 +1,156 @@
+package steambot
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxDeliveryAttempts is how many times an alert is sent before
+// it's dead-lettered.
+const maxDeliveryAttempts = 4
+
+// firstDeliveryBackoff is the wait after the first failed attempt.
+// It doubles after every subsequent failed attempt.
+const firstDeliveryBackoff = 2 * time.Second
+
+// maxPendingAge is how old a pending alert may be before it's no longer
+// worth redelivering.
+const maxPendingAge = 24 * time.Hour
+
+// sendAlert writes an alert about app for guild to the outbox and the
+// history, then delivers it.
+func (c *checker) sendAlert(ctx context.Context, guild db.GuildInfo, app steam.App,
+	kind db.AlertKind, embed *discordgo.MessageEmbed) {
+	history := db.HistoryInfo{
+		ServerID:  guild.ServerID,
+		ChannelID: guild.ChannelID,
+		Appid:     app.Appid,
+		ItemKind:  app.Item().Kind,
+		AppName:   app.Name,
+		Kind:      kind,
+		Discount:  app.Discount,
+		Price:     app.Final,
+		State:     db.AlertPending,
+		SentAt:    time.Now(),
+	}
+
+	alert, err := c.store.QueueAlert(ctx, guild.ServerID, guild.ChannelID, guild.Appid, kind, embed)
+	if err != nil {
+		// Outbox is unavailable, but the alert can still be attempted
+		log.Println("Failed to queue alert:", err)
+		_, err = c.sender.ChannelMessageSendEmbed(strconv.FormatInt(guild.ChannelID, 10), embed)
+
+		history.ID = primitive.NewObjectID()
+		history.State = db.AlertSent
+		if err != nil {
+			history.State = db.AlertDead
+		}
+		c.recordHistory(ctx, history)
+		return
+	}
+
+	history.ID = alert.ID
+	c.recordHistory(ctx, history)
+	c.deliver(ctx, alert)
+}
+
+// recordHistory adds an alert to the history, logging any failure since
+// it shouldn't hold up delivery.
+func (c *checker) recordHistory(ctx context.Context, history db.HistoryInfo) {
+	if err := c.store.RecordHistory(ctx, history); err != nil {
+		log.Println("Failed to record alert history:", err)
+	}
+}
+
+// deliver sends alert to its channel, recording the attempt in the outbox.
+// Transient failures leave the alert pending in the outbox and are retried
+// with backoff in the background, on c.clk, so a flaky channel doesn't hold
+// up the check. Alerts that fail permanently or run out of attempts are
+// dead-lettered. Retrying stops once ctx is done, leaving the alert pending
+// for redeliverPending().
+func (c *checker) deliver(ctx context.Context, alert db.OutboxInfo) {
+	channelID := strconv.FormatInt(alert.ChannelID, 10)
+	attempt := len(alert.Attempts) + 1
+
+	_, err := c.sender.ChannelMessageSendEmbed(channelID, alert.Embed)
+	if err == nil {
+		c.store.RecordAttempt(ctx, alert.ID, db.DeliveryAttempt{Time: time.Now()}, db.AlertSent)
+		return
+	}
+
+	state := db.AlertPending
+	if !isTransient(err) || attempt >= maxDeliveryAttempts {
+		state = db.AlertDead
+	}
+	failed := db.DeliveryAttempt{Time: time.Now(), Error: err.Error()}
+	c.store.RecordAttempt(ctx, alert.ID, failed, state)
+
+	if state == db.AlertDead {
+		log.Printf("Dead-lettered alert %s for guild %d: %v",
+			alert.ID.Hex(), alert.ServerID, err)
+		if isLostAccess(err) {
+			c.lostAccess(ctx, alert.ServerID, alert.ChannelID,
+				"An alert could not be delivered to the bound channel.")
+		}
+		return
+	}
+
+	alert.Attempts = append(slices.Clone(alert.Attempts), failed)
+	c.clk.AfterFunc(deliveryBackoff(attempt), func() {
+		if ctx.Err() == nil {
+			c.deliver(ctx, alert)
+		}
+	})
+}
+
+// deliveryBackoff is the wait after the failed attempt numbered attempt,
+// starting from 1.
+func deliveryBackoff(attempt int) time.Duration {
+	return firstDeliveryBackoff << (attempt - 1)
+}
+
+// isTransient reports whether a failed send is worth retrying. Discord
+// 5xx and 429 responses, as well as network errors, are transient. Other
+// responses, like a missing permission or unknown channel, are not.
+func isTransient(err error) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Response == nil {
+		return true
+	}
+
+	code := restErr.Response.StatusCode
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// redeliverPending delivers alerts left pending in the outbox, e.g., when
+// the bot was stopped mid-delivery. Alerts too stale to be useful are
+// dead-lettered instead. It's slow, so it should be run in its own
+// goroutine.
+func (c *checker) redeliverPending(ctx context.Context) {
+	alerts, err := c.store.PendingAlerts(ctx)
+	if err != nil {
+		log.Println("Failed to get pending alerts:", err)
+		return
+	}
+
+	for _, alert := range alerts {
+		if time.Since(alert.CreatedAt) > maxPendingAge {
+			c.store.RecordAttempt(ctx, alert.ID,
+				db.DeliveryAttempt{Time: time.Now(), Error: "expired before delivery"},
+				db.AlertDead)
+			continue
+		}
+
+		c.deliver(ctx, alert)
+	}
+}
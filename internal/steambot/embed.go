@@ -0,0 +1,235 @@
+package steambot
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/cmd"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+)
+
+// alertFilters are the parts of appdetails shown in alerts on top of the
+// basic info. See steam.NewApp().
+var alertFilters = []steam.Filter{
+	steam.FilterDevelopers,
+	steam.FilterPublishers,
+	steam.FilterGenres,
+	steam.FilterPlatforms,
+	steam.FilterMetacritic,
+	steam.FilterScreenshots,
+}
+
+func releaseEmbed(app steam.App) *discordgo.MessageEmbed {
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:  "Price",
+			Value: app.Final,
+		},
+	}
+	if fields[0].Value == "" {
+		fields[0].Value = "Free"
+	}
+	// Discord rejects embeds with empty fields
+	if app.Description != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  "Description",
+			Value: app.Description,
+		})
+	}
+
+	em := &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("%s has released on Steam!", app.Name),
+		URL:    app.Url(),
+		Color:  0xFFFFFF,
+		Fields: fields,
+	}
+	setImage(em, app)
+	return em
+}
+
+// maxDLCListed is the most DLC a dlcEmbed() lists before summing up the
+// rest, so it fits in an embed description.
+const maxDLCListed = 20
+
+// dlcEmbed alerts that dlc of app has released, along with each DLC's
+// price.
+func dlcEmbed(app steam.App, dlc []*steam.App) *discordgo.MessageEmbed {
+	sb := strings.Builder{}
+	for i, d := range dlc {
+		if i == maxDLCListed {
+			sb.WriteString(fmt.Sprintf("and %d more", len(dlc)-i))
+			break
+		}
+
+		price := d.Final
+		if price == "" {
+			price = "Free"
+		}
+		sb.WriteString(fmt.Sprintf("[%s](%s) - %s\n", d.Name, d.Url(), price))
+	}
+
+	em := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("New DLC released for %s!", app.Name),
+		URL:         app.Url(),
+		Description: sb.String(),
+		Color:       0xFFFFFF,
+	}
+	setImage(em, app)
+	return em
+}
+
+// saleDetails are what sale alerts show of an app outside of its
+// appdetails. Each is nil if it's unknown.
+type saleDetails struct {
+	reviews *steam.ReviewSummary
+	low     *db.HistoryInfo // The sale alert with the deepest discount
+}
+
+// saleEmbed creates the sale alert of app. hidden are the keys of the
+// cmd.AlertFields left out.
+func saleEmbed(app steam.App, details saleDetails, hidden []string) *discordgo.MessageEmbed {
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:   "Original Price",
+			Value:  app.Initial,
+			Inline: true,
+		},
+		{
+			Name:   "Sale Price",
+			Value:  app.Final,
+			Inline: true,
+		},
+	}
+	// Optional fields are left out when hidden or empty, since Discord
+	// rejects embeds with empty fields
+	add := func(key, name, value string, inline bool) {
+		if value == "" || slices.Contains(hidden, key) {
+			return
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   name,
+			Value:  value,
+			Inline: inline,
+		})
+	}
+
+	// Fall back to the number of recommendations if the summary is unknown
+	if details.reviews != nil && details.reviews.Total() > 0 {
+		add(cmd.FieldReviews, "Reviews", details.reviews.String(), true)
+	} else if app.Reviews > 0 {
+		add(cmd.FieldReviews, "Reviews", strconv.Itoa(app.Reviews), true)
+	}
+	if details.low != nil {
+		add(cmd.FieldLowestAlerted, "Lowest Alerted", lowestAlerted(app, *details.low), true)
+	}
+	add(cmd.FieldDeveloper, "Developer", strings.Join(app.Developers, ", "), true)
+	if !slices.Equal(app.Publishers, app.Developers) {
+		add(cmd.FieldDeveloper, "Publisher", strings.Join(app.Publishers, ", "), true)
+	}
+	add(cmd.FieldGenres, "Genres", strings.Join(app.Genres, ", "), true)
+	add(cmd.FieldPlatforms, "Platforms", platforms(app.Platforms), true)
+	if app.Metacritic.Score > 0 {
+		add(cmd.FieldMetacritic, "Metacritic", metacritic(app.Metacritic), true)
+	}
+	add(cmd.FieldReleaseDate, "Release Date", app.ReleaseDate, true)
+	add(cmd.FieldDescription, "Description", app.Description, false)
+
+	em := &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("%s is on sale for %d%% off!", app.Name, app.Discount),
+		URL:    app.Url(),
+		Color:  discountColor(app.Discount),
+		Fields: fields,
+	}
+	setImage(em, app)
+	return em
+}
+
+// setImage shows the header image of app on em. If app has none, the
+// first screenshot is shown as a thumbnail instead, or the store capsule
+// if there are no screenshots either. Only apps have a known capsule.
+func setImage(em *discordgo.MessageEmbed, app steam.App) {
+	switch {
+	case app.Image != "":
+		em.Image = &discordgo.MessageEmbedImage{URL: app.Image}
+	case len(app.Screenshots) > 0:
+		em.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: app.Screenshots[0].Thumbnail}
+	case app.Item().Kind == steam.KindApp:
+		em.Thumbnail = &discordgo.MessageEmbedThumbnail{
+			URL: fmt.Sprintf("https://shared.akamai.steamstatic.com/store_item_assets/"+
+				"steam/apps/%d/capsule_231x87.jpg", app.Appid),
+		}
+	}
+}
+
+// lowestAlerted describes low, the sale alert of app with the deepest
+// discount, e.g., "$0.99 (-90%) on <t:1700000000:D>".
+func lowestAlerted(app steam.App, low db.HistoryInfo) string {
+	seen := fmt.Sprintf("%s (-%d%%) on <t:%d:D>", low.Price, low.Discount, low.SentAt.Unix())
+	if app.Discount > low.Discount {
+		return "Lowest alerted yet! Previously " + seen
+	}
+	return seen
+}
+
+// platforms describes p, e.g., "Windows, macOS".
+func platforms(p steam.Platforms) string {
+	strs := []string{}
+	if p.Windows {
+		strs = append(strs, "Windows")
+	}
+	if p.Mac {
+		strs = append(strs, "macOS")
+	}
+	if p.Linux {
+		strs = append(strs, "Linux")
+	}
+	return strings.Join(strs, ", ")
+}
+
+// metacritic describes m, linking to its review page if there is one.
+func metacritic(m steam.Metacritic) string {
+	if m.URL == "" {
+		return strconv.Itoa(m.Score)
+	}
+	return fmt.Sprintf("[%d](%s)", m.Score, m.URL)
+}
+
+func discountColor(discount int) int {
+	atMost := func(high int) bool {
+		return discount <= high
+	}
+	switch {
+	case atMost(5):
+		return 0x0bff33
+	case atMost(10):
+		return 0x44fdd2
+	case atMost(15):
+		return 0x44fdfd
+	case atMost(20):
+		return 0x44dbfd
+	case atMost(25):
+		return 0x44b6fd
+	case atMost(30):
+		return 0x448bfd
+	case atMost(35):
+		return 0x445afd
+	case atMost(40):
+		return 0x8544fd
+	case atMost(45):
+		return 0xb044fd
+	case atMost(50):
+		return 0xe144fd
+	case atMost(55):
+		return 0xfd44de
+	case atMost(60):
+		return 0xff23a7
+	case atMost(99):
+		return 0xff0000
+	default:
+		return 0xFFFFFF
+	}
+}
@@ -0,0 +1,227 @@
+package steambot
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/cmd"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/stretchr/testify/suite"
+)
+
+// update rewrites the golden files with the embeds currently rendered:
+//
+//	go test ./internal/steambot -run TestEmbedShould -update
+var update = flag.Bool("update", false, "update golden files")
+
+// embedApps are apps in the states that change how alerts render.
+var embedApps = map[string]steam.App{
+	"typical": {
+		Name:        "Portal",
+		Appid:       400,
+		Description: "Portal is a new single player game from Valve.",
+		Image:       "https://example.com/400/header.jpg",
+		Reviews:     100000,
+		Price:       steam.Price{Discount: 90, Initial: "$9.99", Final: "$0.99"},
+	},
+	"no_reviews": {
+		Name:        "Unreviewed",
+		Appid:       1,
+		Description: "Nobody has reviewed this yet.",
+		Image:       "https://example.com/1/header.jpg",
+		Price:       steam.Price{Discount: 25, Initial: "$20.00", Final: "$15.00"},
+	},
+	"empty_description": {
+		Name:    "Undescribed",
+		Appid:   2,
+		Image:   "https://example.com/2/header.jpg",
+		Reviews: 10,
+		Price:   steam.Price{Discount: 50, Initial: "$10.00", Final: "$5.00"},
+	},
+	"free": {
+		Name:        "Team Fortress 2",
+		Appid:       440,
+		Free:        true,
+		Description: "Nine distinct classes provide a broad range of tactical abilities.",
+		Image:       "https://example.com/440/header.jpg",
+		Reviews:     1000000,
+	},
+	"100_percent_off": {
+		Name:        "Giveaway",
+		Appid:       3,
+		Description: "Free to keep for a limited time.",
+		Image:       "https://example.com/3/header.jpg",
+		Reviews:     500,
+		Price:       steam.Price{Discount: 100, Initial: "$14.99", Final: "$0.00"},
+	},
+}
+
+// detailedApp is an app with every part of appdetails alerts show.
+var detailedApp = steam.App{
+	Name:        "Cyberpunk 2077",
+	Appid:       1091500,
+	Type:        "game",
+	Description: "Cyberpunk 2077 is an open-world, action-adventure RPG.",
+	Image:       "https://example.com/1091500/header.jpg",
+	Reviews:     744131,
+	ReleaseDate: "Dec 9, 2020",
+	Price:       steam.Price{Discount: 50, Initial: "$59.99", Final: "$29.99"},
+	Developers:  []string{"CD PROJEKT RED"},
+	Publishers:  []string{"CD PROJEKT RED", "WB Games"},
+	Genres:      []string{"RPG", "Action"},
+	Platforms:   steam.Platforms{Windows: true, Mac: true},
+	Metacritic:  steam.Metacritic{Score: 86, URL: "https://www.metacritic.com/game/cyberpunk-2077"},
+	Screenshots: []steam.Screenshot{
+		{ID: 0, Thumbnail: "https://example.com/1091500/ss_0.600x338.jpg"},
+	},
+}
+
+// detailedLow is the lowest alerted of detailedApp.
+var detailedLow = db.HistoryInfo{
+	Appid:    1091500,
+	Kind:     db.AlertSale,
+	Discount: 65,
+	Price:    "$20.99",
+	State:    db.AlertSent,
+	SentAt:   time.Date(2024, 11, 26, 18, 0, 0, 0, time.UTC),
+}
+
+// detailedDetails are the saleDetails of detailedApp.
+var detailedDetails = saleDetails{
+	reviews: &steam.ReviewSummary{Positive: 650000, Negative: 94131, ScoreDesc: "Very Positive"},
+	low:     &detailedLow,
+}
+
+type embedShould struct {
+	suite.Suite
+}
+
+func TestEmbedShould(t *testing.T) {
+	suite.Run(t, new(embedShould))
+}
+
+// assertGolden compares em serialized to the golden file matching name.
+func (s *embedShould) assertGolden(name string, em *discordgo.MessageEmbed) {
+	got, err := json.MarshalIndent(em, "", "  ")
+	s.Require().Nil(err)
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		s.Require().Nil(os.MkdirAll(filepath.Dir(path), 0o755))
+		s.Require().Nil(os.WriteFile(path, got, 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	s.Require().Nil(err, "missing golden file, run with -update to create it")
+	s.Equal(string(want), string(got), name)
+}
+
+func (s *embedShould) TestRenderSaleEmbeds() {
+	for name, app := range embedApps {
+		s.assertGolden("sale_"+name, saleEmbed(app, saleDetails{}, nil))
+	}
+}
+
+func (s *embedShould) TestRenderDetailedSaleEmbed() {
+	s.assertGolden("sale_detailed", saleEmbed(detailedApp, detailedDetails, nil))
+}
+
+func (s *embedShould) TestRenderSaleEmbedAtLowestAlertedYet() {
+	low := detailedLow
+	low.Discount, low.Price = 40, "$35.99"
+
+	s.assertGolden("sale_lowest_alerted_yet", saleEmbed(detailedApp, saleDetails{low: &low}, nil))
+}
+
+func (s *embedShould) TestLeaveOutHiddenFields() {
+	hidden := []string{}
+	for _, field := range cmd.AlertFields {
+		hidden = append(hidden, field.Key)
+	}
+
+	em := saleEmbed(detailedApp, detailedDetails, hidden)
+
+	// Only the prices are left
+	s.Equal(saleEmbed(detailedApp, detailedDetails, nil).Fields[:2], em.Fields)
+}
+
+func (s *embedShould) TestFallBackToScreenshotThumbnail() {
+	app := detailedApp
+	app.Image = ""
+
+	em := saleEmbed(app, saleDetails{}, nil)
+
+	s.Nil(em.Image)
+	s.Equal(app.Screenshots[0].Thumbnail, em.Thumbnail.URL)
+}
+
+func (s *embedShould) TestFallBackToCapsuleThumbnail() {
+	app := embedApps["typical"]
+	app.Image = ""
+
+	em := releaseEmbed(app)
+
+	s.Nil(em.Image)
+	s.Equal("https://shared.akamai.steamstatic.com/store_item_assets/steam/apps/400/"+
+		"capsule_231x87.jpg", em.Thumbnail.URL)
+}
+
+func (s *embedShould) TestRenderReleaseEmbeds() {
+	for name, app := range embedApps {
+		s.assertGolden("release_"+name, releaseEmbed(app))
+	}
+}
+
+func (s *embedShould) TestRenderDLCEmbed() {
+	dlc := []*steam.App{
+		{
+			Name:  "Portal Soundtrack",
+			Appid: 323180,
+			Price: steam.Price{Initial: "$4.99", Final: "$4.99"},
+		},
+		{Name: "Portal Art Pack", Appid: 206480, Free: true},
+	}
+
+	s.assertGolden("dlc", dlcEmbed(embedApps["typical"], dlc))
+}
+
+func (s *embedShould) TestSumUpLongListsOfDLC() {
+	dlc := []*steam.App{}
+	for appid := 1; appid <= maxDLCListed+5; appid++ {
+		dlc = append(dlc, &steam.App{Name: "DLC", Appid: appid})
+	}
+
+	em := dlcEmbed(embedApps["typical"], dlc)
+
+	s.Contains(em.Description, "[DLC](https://store.steampowered.com/app/20) - Free\n")
+	s.NotContains(em.Description, "store.steampowered.com/app/21)")
+	s.True(strings.HasSuffix(em.Description, "and 5 more"))
+}
+
+func (s *embedShould) TestColorDiscountsByBracket() {
+	for _, tt := range []struct {
+		discount int
+		color    int
+	}{
+		{0, 0x0bff33},
+		{5, 0x0bff33},
+		{6, 0x44fdd2},
+		{10, 0x44fdd2},
+		{50, 0xe144fd},
+		{51, 0xfd44de},
+		{60, 0xff23a7},
+		{61, 0xff0000},
+		{99, 0xff0000},
+		{100, 0xFFFFFF},
+	} {
+		s.Equal(tt.color, discountColor(tt.discount), "discount %d", tt.discount)
+	}
+}
@@ -0,0 +1,75 @@
+package steambot
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+)
+
+// staleGuildGracePeriod is how long a guild the bot is no longer a member
+// of is kept before it and its apps are removed. This leaves room for
+// the bot being re-added shortly after.
+const staleGuildGracePeriod = 7 * 24 * time.Hour
+
+// reconcileGuilds compares the guilds in the database with the guilds the
+// bot is currently a member of, as known by the session state. This catches
+// guilds that removed the bot while it was offline, which guildDeleteHandler
+// never sees. Guilds that are missing get marked, and removed once they've
+// been missing for longer than staleGuildGracePeriod. Guilds that reappear
+// get unmarked.
+func reconcileGuilds(ctx context.Context, s *discordgo.Session) {
+	members := map[int64]bool{}
+	s.State.RLock()
+	for _, g := range s.State.Guilds {
+		if guildID, err := strconv.ParseInt(g.ID, 10, 64); err == nil {
+			members[guildID] = true
+		}
+	}
+	s.State.RUnlock()
+
+	// An empty state most likely means it isn't populated, not that the
+	// bot was removed from every guild.
+	if len(members) == 0 {
+		return
+	}
+
+	guilds, err := db.Guilds(ctx)
+	if err != nil {
+		log.Println("Failed to reconcile guilds:", err)
+		return
+	}
+
+	var marked, removed, restored int
+	now := time.Now()
+	for _, guild := range guilds {
+		missing := !guild.MissingSince.IsZero()
+
+		switch {
+		case members[guild.ServerID] && missing:
+			if db.ClearGuildMissing(ctx, guild.ServerID) == nil {
+				restored++
+			}
+
+		case members[guild.ServerID]:
+
+		case !missing:
+			if db.MarkGuildMissing(ctx, guild.ServerID, now) == nil {
+				marked++
+			}
+
+		case now.Sub(guild.MissingSince) > staleGuildGracePeriod:
+			if db.RemoveGuild(ctx, guild.ServerID) == nil {
+				removed++
+				log.Printf("Removed guild %d, missing since %s",
+					guild.ServerID, guild.MissingSince.Format(time.DateOnly))
+			}
+		}
+	}
+
+	log.Printf("Reconciled %d guilds: %d newly missing, %d removed, %d restored",
+		len(guilds), marked, removed, restored)
+}
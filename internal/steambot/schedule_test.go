@@ -0,0 +1,280 @@
+package steambot
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/clock"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/clock/clocktest"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/schedule"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam/steamtest"
+	"github.com/stretchr/testify/suite"
+)
+
+// scheduledCheckShould runs the scheduled check on a fake clock, checking
+// daily at 10:05 AM America/Los_Angeles.
+type scheduledCheckShould struct {
+	suite.Suite
+	loc     *time.Location
+	sched   schedule.Schedule
+	clk     *clocktest.Clock
+	server  *steamtest.Server
+	sender  *recordingSender
+	checker *checker
+	ctx     context.Context
+	cancel  context.CancelFunc
+	upkeeps int // Guarded by clk, see upkeep()
+
+	storeURL, communityURL string
+}
+
+func (s *scheduledCheckShould) SetupTest() {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	s.Require().NoError(err)
+	s.loc = loc
+	s.sched = schedule.Default()
+
+	s.server = steamtest.NewServer(steamtest.CannedApps...)
+	s.storeURL, s.communityURL = steam.StoreURL, steam.CommunityURL
+	steam.StoreURL, steam.CommunityURL = s.server.URL, s.server.URL
+
+	store := newMemStore()
+	store.addGuild(1, 10, 50)
+	store.track(1, 400, "Portal", db.JunctionInfo{})
+	store.track(1, 620, "Portal 2", db.JunctionInfo{})
+	s.sender = &recordingSender{}
+	s.checker = &checker{
+		store:      store,
+		sender:     s.sender,
+		lostAccess: func(context.Context, int64, int64, string) {},
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.upkeeps = 0
+}
+
+func (s *scheduledCheckShould) TearDownTest() {
+	s.cancel()
+	steam.SetClock(clock.Real())
+	steam.StoreURL, steam.CommunityURL = s.storeURL, s.communityURL
+	s.server.Close()
+}
+
+func TestScheduledCheckShould(t *testing.T) {
+	suite.Run(t, new(scheduledCheckShould))
+}
+
+// startAt sets the clock to t for the scheduler and Steam.
+func (s *scheduledCheckShould) startAt(t time.Time) {
+	s.clk = clocktest.NewClock(t)
+	steam.SetClock(s.clk)
+	s.checker.clk = s.clk
+}
+
+// start starts checking in the background. The returned channel is closed
+// once the first check returns.
+func (s *scheduledCheckShould) start() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		periodicallyCheckApps(s.ctx, s.clk, s.checker, s.sched, s.upkeep)
+	}()
+	return done
+}
+
+// upkeep counts the checks that finished without aborting. Each check
+// reschedules after upkeep, so the count can be read once s.clk has a
+// timer again.
+func (s *scheduledCheckShould) upkeep(context.Context) {
+	s.upkeeps++
+}
+
+// next gets when the next check is scheduled.
+func (s *scheduledCheckShould) next() time.Time {
+	next, ok := s.clk.Next()
+	s.Require().True(ok, "no check is scheduled")
+	return next
+}
+
+func (s *scheduledCheckShould) TestCheckRightAwayThenAtScheduledTime() {
+	s.startAt(time.Date(2024, 6, 1, 9, 0, 0, 0, s.loc))
+
+	<-s.start()
+
+	s.Equal(1, s.upkeeps)
+	s.Equal([]string{"Portal is on sale for 90% off!"}, s.sender.titles("10"))
+	s.Equal(time.Date(2024, 6, 1, 10, 5, 0, 0, s.loc), s.next())
+}
+
+func (s *scheduledCheckShould) TestKeepTimeOfDayWhenDSTStarts() {
+	start := time.Date(2024, 3, 9, 10, 5, 0, 0, s.loc)
+	s.startAt(start)
+	<-s.start()
+
+	// The night clocks spring forward is an hour short
+	s.Equal(23*time.Hour, s.next().Sub(start))
+	s.clk.Advance(23*time.Hour - time.Minute)
+	s.Equal(1, s.upkeeps)
+
+	s.clk.Advance(time.Minute)
+	s.clk.BlockUntil(1)
+
+	s.Equal(2, s.upkeeps)
+	s.Equal(time.Date(2024, 3, 10, 10, 5, 0, 0, s.loc), s.clk.Now())
+	s.Equal(time.Date(2024, 3, 11, 10, 5, 0, 0, s.loc), s.next())
+	s.Equal(24*time.Hour, s.next().Sub(s.clk.Now()))
+}
+
+func (s *scheduledCheckShould) TestKeepTimeOfDayWhenDSTEnds() {
+	start := time.Date(2024, 11, 2, 10, 5, 0, 0, s.loc)
+	s.startAt(start)
+	<-s.start()
+
+	// The night clocks fall back is an hour long
+	s.Equal(25*time.Hour, s.next().Sub(start))
+	s.clk.Advance(25 * time.Hour)
+	s.clk.BlockUntil(1)
+
+	s.Equal(2, s.upkeeps)
+	s.Equal(time.Date(2024, 11, 4, 10, 5, 0, 0, s.loc), s.next())
+}
+
+func (s *scheduledCheckShould) TestResumeAfterRateLimit() {
+	start := time.Date(2024, 6, 1, 10, 5, 0, 0, s.loc)
+	s.startAt(start)
+	s.server.Queue(steamtest.AppDetailsPath, steamtest.TooManyRequests)
+
+	done := s.start()
+	// The check waits on the clock until the rate limit is lifted
+	s.clk.BlockUntil(1)
+	s.Equal(start.Add(steam.RateLimitCooldown), s.next())
+	s.Equal(0, s.upkeeps)
+	s.Empty(s.sender.titles("10"))
+
+	s.clk.Advance(steam.RateLimitCooldown)
+	<-done
+
+	s.Equal(1, s.upkeeps)
+	s.Equal([]string{"Portal is on sale for 90% off!"}, s.sender.titles("10"))
+	s.Equal(time.Date(2024, 6, 2, 10, 5, 0, 0, s.loc), s.next())
+}
+
+func (s *scheduledCheckShould) TestSkipUpkeepButRescheduleWhenAborted() {
+	s.startAt(time.Date(2024, 6, 1, 10, 5, 0, 0, s.loc))
+	s.server.RemoveApp(620)
+
+	<-s.start()
+
+	s.Equal(0, s.upkeeps)
+	s.Equal(time.Date(2024, 6, 2, 10, 5, 0, 0, s.loc), s.next())
+}
+
+func (s *scheduledCheckShould) TestStopWhenCancelled() {
+	s.startAt(time.Date(2024, 6, 1, 10, 5, 0, 0, s.loc))
+	s.cancel()
+
+	<-s.start()
+
+	s.Equal(0, s.upkeeps)
+	s.Zero(s.clk.Timers())
+}
+
+func (s *scheduledCheckShould) TestStopWhenCancelledWhileRateLimited() {
+	s.startAt(time.Date(2024, 6, 1, 10, 5, 0, 0, s.loc))
+	s.server.Queue(steamtest.AppDetailsPath, steamtest.TooManyRequests)
+
+	done := s.start()
+	s.clk.BlockUntil(1)
+	s.cancel()
+	<-done
+	s.clk.Advance(48 * time.Hour)
+
+	s.Equal(0, s.upkeeps)
+	s.Zero(s.clk.Timers())
+	s.Empty(s.sender.titles("10"))
+}
+
+// recordingStatus is a statusUpdater that records each status.
+type recordingStatus struct {
+	mu       sync.Mutex
+	statuses []string
+}
+
+func (r *recordingStatus) UpdateCustomStatus(state string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = append(r.statuses, state)
+	return nil
+}
+
+func (r *recordingStatus) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.statuses[len(r.statuses)-1]
+}
+
+type statusShould struct {
+	suite.Suite
+	loc    *time.Location
+	status *recordingStatus
+}
+
+func (s *statusShould) SetupTest() {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	s.Require().NoError(err)
+	s.loc = loc
+	s.status = &recordingStatus{}
+}
+
+func TestStatusShould(t *testing.T) {
+	suite.Run(t, new(statusShould))
+}
+
+func (s *statusShould) TestCountWholeHoursLeft() {
+	clk := clocktest.NewClock(time.Date(2024, 6, 1, 5, 0, 0, 0, s.loc))
+
+	periodicallyUpdateStatus(clk, s.status, schedule.Default())
+
+	s.Equal("5 hours until check", s.status.last())
+}
+
+func (s *statusShould) TestUseSingularHour() {
+	s.Equal("1 hour until check",
+		statusText(time.Date(2024, 6, 1, 9, 0, 0, 0, s.loc), schedule.Default()))
+	s.Equal("0 hours until check",
+		statusText(time.Date(2024, 6, 1, 9, 30, 0, 0, s.loc), schedule.Default()))
+}
+
+func (s *statusShould) TestUpdateEveryWholeHour() {
+	clk := clocktest.NewClock(time.Date(2024, 6, 1, 5, 30, 0, 0, s.loc))
+	periodicallyUpdateStatus(clk, s.status, schedule.Default())
+
+	next, ok := clk.Next()
+	s.Require().True(ok)
+	s.Equal(time.Date(2024, 6, 1, 6, 0, 0, 0, s.loc), next)
+
+	clk.Advance(30 * time.Minute)
+	clk.BlockUntil(1)
+
+	s.Equal("4 hours until check", s.status.last())
+}
+
+func (s *statusShould) TestCountElapsedHoursAcrossDST() {
+	// Clocks spring forward from 2 AM to 3 AM, so 10:05 AM is an hour closer
+	clk := clocktest.NewClock(time.Date(2024, 3, 10, 0, 30, 0, 0, s.loc))
+	periodicallyUpdateStatus(clk, s.status, schedule.Default())
+	s.Equal("8 hours until check", s.status.last())
+
+	clk.Advance(30 * time.Minute)
+	clk.BlockUntil(1)
+	s.Equal("8 hours until check", s.status.last())
+
+	clk.Advance(time.Hour)
+	clk.BlockUntil(1)
+	s.Equal(time.Date(2024, 3, 10, 3, 0, 0, 0, s.loc), clk.Now())
+	s.Equal("7 hours until check", s.status.last())
+}
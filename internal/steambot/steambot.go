@@ -2,24 +2,38 @@
 package steambot
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/clock"
 	"github.com/jasonly027/steam_sale_discord_bot_go/internal/cmd"
 	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/schedule"
 	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
 )
 
 type SteamBot struct {
 	*discordgo.Session
+	// ctx is done once the bot starts shutting down. Everything the bot
+	// does outside of an interaction runs with it.
+	ctx          context.Context
+	cancel       context.CancelFunc
 	gid          string
+	clk          clock.Clock
+	sched        schedule.Schedule
+	wishlists    steam.WishlistFetcher
+	checker      *checker
+	startLoops   sync.Once // Starts the loops of the bot on the first Ready
 	cmds         map[string]cmd.Cmd
 	compHandlers map[string]cmd.Handler
 }
@@ -27,25 +41,44 @@ type SteamBot struct {
 // New creates a new Steam bot with a given Discord API bot token.
 // An optional Guild ID can be supplied to exclusively register commands to.
 // Otherwise, "" can be used to register the commands globally.
-// Use b.Start() to start the bot.
-func New(token, guild string) (b *SteamBot) {
+// Sale checks run on sched. Use b.Start() to start the bot.
+func New(token, guild string, sched schedule.Schedule) (b *SteamBot) {
 	dg, err := discordgo.New("Bot " + token)
 	if err != nil {
 		log.Fatal("Invalid bot parameters:", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	b = &SteamBot{
 		Session:      dg,
+		ctx:          ctx,
+		cancel:       cancel,
 		gid:          guild,
+		clk:          clock.Real(),
+		sched:        sched,
+		wishlists:    steam.NewStoreWishlist(),
 		cmds:         map[string]cmd.Cmd{},
 		compHandlers: map[string]cmd.Handler{},
 	}
+	b.checker = &checker{
+		store:  dbStore{},
+		sender: dg,
+		clk:    b.clk,
+		lostAccess: func(ctx context.Context, guildID, channelID int64, reason string) {
+			lostChannelAccess(ctx, dg, guildID, channelID, reason)
+		},
+	}
 
 	b.registerHandlers([]interface{}{
 		b.commandHandler,
-		guildCreateHandler,
-		guildDeleteHandler,
-		readyHandler,
+		b.channelDeleteHandler,
+		b.channelUpdateHandler,
+		b.guildCreateHandler,
+		b.guildDeleteHandler,
+		b.guildMemberUpdateHandler,
+		b.guildRoleDeleteHandler,
+		b.guildRoleUpdateHandler,
+		b.readyHandler,
 	})
 
 	return b
@@ -58,39 +91,68 @@ func (b *SteamBot) Start() {
 		log.Fatal("Failed to open session:", err)
 	}
 
-	b.registerCommands([]cmd.Cmd{
+	cmds := []cmd.Cmd{
 		cmd.NewAddApps(),
+		cmd.NewAddAppsFile(),
+		cmd.NewAddDLC(),
+		cmd.NewAlertFields(),
+		cmd.NewAuditLog(),
 		cmd.NewBind(),
+		cmd.NewCheckNow(b.checker.checkGuild),
 		cmd.NewClearApps(),
-		cmd.NewHelp(),
+		cmd.NewExport(),
+		cmd.NewFailedAlerts(),
+		cmd.NewHelp(b.sched),
+		cmd.NewHistory(),
+		cmd.NewImport(),
+		cmd.NewImportWishlist(b.wishlists),
 		cmd.NewListApps(),
 		cmd.NewRemoveApps(),
+		cmd.NewRestoreApps(),
 		cmd.NewSearch(),
+		cmd.NewSetAuditChannel(),
 		cmd.NewSetDiscountThreshold(),
-	})
+		cmd.NewSetManagerRole(),
+		cmd.NewSetMinReviewScore(),
+		cmd.NewStatus(b.sched),
+	}
+	// Dev only cmds
+	if b.gid != "" {
+		cmds = append(cmds, cmd.NewPreviewAlert(b.checker.previewAlert))
+	}
+	b.registerCommands(cmds)
 
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
 
+	// Stop in-flight checks, deliveries, and interactions before closing
+	b.cancel()
 	b.Close()
 }
 
 // registerCommands registers commands for the bot.
 func (b *SteamBot) registerCommands(cmds []cmd.Cmd) {
-	// Map cmds and their component handlers
-	for _, cmd := range cmds {
-		_, exists := b.cmds[cmd.Name]
+	// Map cmds and their component handlers. Manager only cmds have
+	// their handlers guarded so the restriction is enforced server-side.
+	for _, c := range cmds {
+		_, exists := b.cmds[c.Name]
 		if exists {
-			log.Fatal("Command [" + cmd.Name + "] already exists")
+			log.Fatal("Command [" + c.Name + "] already exists")
+		}
+		if c.ManagerOnly {
+			c.Handle = cmd.RequireManager(c.Handle)
 		}
-		b.cmds[cmd.Name] = cmd
+		b.cmds[c.Name] = c
 
-		for _, handler := range cmd.CompHandlers {
+		for _, handler := range c.CompHandlers {
 			_, exists := b.compHandlers[handler.Name]
 			if exists {
 				log.Fatal("Command handler [" + handler.Name + "] already exists")
 			}
+			if c.ManagerOnly {
+				handler.Handle = cmd.RequireManager(handler.Handle)
+			}
 			b.compHandlers[handler.Name] = handler.Handle
 		}
 	}
@@ -115,6 +177,10 @@ func (b *SteamBot) registerHandlers(handlers []interface{}) {
 }
 
 func (b *SteamBot) commandHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	ctx, cancel := cmd.InteractionContext(b.ctx, i)
+	defer cancel()
+	session := cmd.NewSession(s)
+
 	switch i.Type {
 	case discordgo.InteractionApplicationCommand:
 		// Bot only works in guilds
@@ -127,16 +193,16 @@ func (b *SteamBot) commandHandler(s *discordgo.Session, i *discordgo.Interaction
 		}
 
 		if cmd, ok := b.cmds[i.ApplicationCommandData().Name]; ok {
-			cmd.Handle(s, i)
+			cmd.Handle(ctx, session, i)
 		}
 	case discordgo.InteractionMessageComponent:
-		if handle, ok := b.compHandlers[i.MessageComponentData().CustomID]; ok {
-			handle(s, i)
+		if handle, ok := b.compHandlers[cmd.CustomIDName(i.MessageComponentData().CustomID)]; ok {
+			handle(ctx, session, i)
 		}
 	}
 }
 
-func guildCreateHandler(s *discordgo.Session, g *discordgo.GuildCreate) {
+func (b *SteamBot) guildCreateHandler(s *discordgo.Session, g *discordgo.GuildCreate) {
 	guildID, err := strconv.ParseInt(g.ID, 10, 64)
 	if err != nil {
 		return
@@ -147,7 +213,8 @@ func guildCreateHandler(s *discordgo.Session, g *discordgo.GuildCreate) {
 		channelID = 0
 	}
 
-	db.AddGuild(guildID, channelID)
+	db.AddGuild(b.ctx, guildID, channelID)
+	db.ClearGuildMissing(b.ctx, guildID)
 }
 
 func defaultTextChannel(s *discordgo.Session, chs []*discordgo.Channel) (int64, error) {
@@ -175,7 +242,7 @@ func defaultTextChannel(s *discordgo.Session, chs []*discordgo.Channel) (int64,
 	return 0, errors.New("no sendable text channel")
 }
 
-func guildDeleteHandler(s *discordgo.Session, g *discordgo.GuildDelete) {
+func (b *SteamBot) guildDeleteHandler(s *discordgo.Session, g *discordgo.GuildDelete) {
 	// Do nothing if network outage, otherwise it means bot was removed from server
 	if g.Unavailable {
 		return
@@ -186,281 +253,359 @@ func guildDeleteHandler(s *discordgo.Session, g *discordgo.GuildDelete) {
 		return
 	}
 
-	db.RemoveGuild(guildID)
+	db.RemoveGuild(b.ctx, guildID)
+}
+
+func (b *SteamBot) readyHandler(s *discordgo.Session, r *discordgo.Ready) {
+	// Ready lists every guild the bot is a member of, so the state
+	// already has the full membership to reconcile against.
+	reconcileGuilds(b.ctx, s)
+	// The status is lost on reconnecting, so it's set again
+	now := b.clk.Now()
+	s.UpdateCustomStatus(statusText(now, b.sched))
+
+	// Ready is sent again on every reconnect, but the loops outlive
+	// the connection, so they're only started once
+	b.startLoops.Do(func() {
+		b.clk.AfterFunc(nextHour(now).Sub(now), func() {
+			periodicallyUpdateStatus(b.clk, s, b.sched)
+		})
+		// Delivering and checking are slow, so they're kept off the event loop
+		go b.checker.redeliverPending(b.ctx)
+		go periodicallyCheckApps(b.ctx, b.clk, b.checker, b.sched, func(ctx context.Context) {
+			reconcileGuilds(ctx, s)
+			syncWishlists(ctx, b.wishlists)
+		})
+	})
 }
 
-func readyHandler(s *discordgo.Session, r *discordgo.Ready) {
-	periodicallyUpdateStatus(s)
-	periodicallyCheckApps(s)
+// statusUpdater is what periodicallyUpdateStatus() uses of the Discord
+// session. It's implemented by *discordgo.Session.
+type statusUpdater interface {
+	UpdateCustomStatus(state string) error
 }
 
 // periodicallyUpdateStatus will update the Discord status of the bot
 // to the number of hours left until a sale check is done. Once called,
-// it will call itself every whole hour.
-func periodicallyUpdateStatus(s *discordgo.Session) {
+// it will call itself every whole hour of clk.
+func periodicallyUpdateStatus(clk clock.Clock, s statusUpdater, sched schedule.Schedule) {
 	var fn func()
 
 	fn = func() {
-		hrs := int(time.Until(nextCheck()).Truncate(time.Hour).Hours())
-		var plural string
-		if hrs == 1 {
-			plural = ""
-		} else {
-			plural = "s"
-		}
+		now := clk.Now()
+		s.UpdateCustomStatus(statusText(now, sched))
 
-		s.UpdateCustomStatus(fmt.Sprintf("%d hour%s until check", hrs, plural))
-
-		time.AfterFunc(time.Until(nextHour()), fn)
+		clk.AfterFunc(nextHour(now).Sub(now), fn)
 	}
 
 	fn()
 }
 
-func loc_PDT() *time.Location {
-	loc, err := time.LoadLocation("America/Los_Angeles")
-	if err != nil {
-		log.Fatal("Failed to load location")
-	}
-	return loc
-}
-
-// nextCheck gets when the next sale check is as a Time object.
-func nextCheck() time.Time {
-	loc := loc_PDT()
-	now := time.Now().In(loc)
-	timeOfCheck := time.Date(now.Year(), now.Month(), now.Day(), 10, 5, 0, 0, loc)
-	if now.After(timeOfCheck) {
-		timeOfCheck = timeOfCheck.Add(24 * time.Hour)
+// statusText describes the whole hours left at now until the next check
+// in sched, e.g., "5 hours until check".
+func statusText(now time.Time, sched schedule.Schedule) string {
+	hrs := int(sched.Next(now).Sub(now).Truncate(time.Hour).Hours())
+	var plural string
+	if hrs == 1 {
+		plural = ""
+	} else {
+		plural = "s"
 	}
 
-	return timeOfCheck
+	return fmt.Sprintf("%d hour%s until check", hrs, plural)
 }
 
-// nextHour gets when the next whole hour is as a Time object.
-func nextHour() time.Time {
-	return time.Now().Add(time.Hour).Truncate(time.Hour)
+// nextHour gets when the next whole hour after now is as a Time object.
+func nextHour(now time.Time) time.Time {
+	return now.Add(time.Hour).Truncate(time.Hour)
 }
 
 // periodicallyCheckApps will go through all globally added apps to the bot
 // and send sale alerts to all the servers tracking that app if the sale discount
 // is at least that server's discount threshold. Once called, it will call itself
-// daily at 10:05 AM PDT. Due to external API rate limiting when getting app info,
-// the time it takes to finish checking may take a while but not long enough to
-// miss the next daily check. Calls to the external API are done until a rate limit
-// is hit, then this fn waits a period before trying to continue.
-func periodicallyCheckApps(s *discordgo.Session) {
+// at the next time in sched. Due to external API rate limiting when getting app
+// info, the time it takes to finish checking may take a while. If checking runs
+// past a scheduled time, the next check is the first scheduled time after it
+// finishes. See c.checkAllApps(). After each check that isn't aborted, upkeep is
+// done, like reconciling guilds. Scheduled times are told by clk. Checking stops
+// for good once ctx is done.
+func periodicallyCheckApps(ctx context.Context, clk clock.Clock, c *checker,
+	sched schedule.Schedule, upkeep func(ctx context.Context)) {
 	// This is the fn that will be periodically called to check apps for sales.
 	var checkApps func()
 
-	// This fn will attempt to fetch an App by appid and check for a sale,
-	// If an error occurs fetching the App (like an inevitable rate limit error
-	// from Steam), a wait out period will be put in place, then checkApps will
-	// be called again to resume checking. Returns whether or not the calling fn
-	// (checkApps) needs to exit for a cooldown.
-	var tryCheckApp func(appid int) bool
-
-	// The id of the App we are fetching. Populated through nextAppid().
-	var currAppid *int
-
-	// This fn will return appids we need to check. When there are no more
-	// appids to check, this will only return nil.
-	var nextAppid func() *int
+	checkApps = func() {
+		err := c.checkAllApps(ctx)
+		if ctx.Err() != nil {
+			return
+		}
 
-	// This fn must be called to release resources when we're done calling
-	// nextAppid().
-	var close func()
+		// An aborted check skips upkeep until the next check
+		if err != nil {
+			log.Println("Aborted sale check:", err)
+		} else {
+			upkeep(ctx)
+		}
 
-	// This fn clears the state of checkApps so that the next call to it
-	// is not considered a resuming check.
-	reset := func() {
-		close()
-		currAppid = nil
-		nextAppid = nil
+		now := clk.Now()
+		clk.AfterFunc(sched.Next(now).Sub(now), checkApps)
 	}
 
-	checkApps = func() {
-		if nextAppid == nil { // Get fresh apps if non-resuming check
-			nextAppid, close = db.Apps()
-		}
+	checkApps()
+}
 
-		// Not nil means we are resuming from the previous check that we
-		// were rate limited on. This was the appid we failed to create
-		// an App from, so we retry it.
-		if currAppid != nil {
-			if exit := tryCheckApp(*currAppid); exit {
-				return
-			}
-		}
+// alertSender is what the sale check uses of the Discord session. It's
+// implemented by *discordgo.Session.
+type alertSender interface {
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed,
+		options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
 
-		currAppid = nextAppid()
-		for currAppid != nil {
-			if exit := tryCheckApp(*currAppid); exit {
-				return
-			}
-			currAppid = nextAppid()
-		}
+// checker checks apps for sales and delivers the alerts they warrant.
+type checker struct {
+	// mu is held for the whole of each check, so a guild's check never
+	// overlaps the scheduled check and both alert the same sale.
+	mu     sync.Mutex
+	store  checkStore
+	sender alertSender
+	clk    clock.Clock // Tells when to retry failed deliveries
+	// lostAccess is called when an alert can't be delivered because the
+	// bot lost access to the channel. See lostChannelAccess().
+	lostAccess func(ctx context.Context, guildID, channelID int64, reason string)
+}
 
-		// At this point, we have checked all apps, now schedule tomorrow's check
-		reset()
-		time.AfterFunc(time.Until(nextCheck()), checkApps)
-	}
+// checkAllApps checks every app in c.store, packages and bundles included,
+// like c.checkApp(). Calls to the external API are done until a rate limit
+// is hit, then this fn waits until steam.RetryAt() before continuing with
+// the app it was rate limited on. Checking is aborted on any other error.
+func (c *checker) checkAllApps(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	tryCheckApp = func(appid int) (exit bool) {
-		app, err := steam.NewApp(appid)
+	nextItem, close := c.store.Apps(ctx)
+	defer close()
+
+	for item := nextItem(); item != nil; item = nextItem() {
+		app, err := steam.WaitForLimit(ctx, func() (steam.App, error) {
+			return steam.NewItem(ctx, *item, alertFilters...)
+		})
 		if err != nil {
-			// On rate-limit, wait then resume
-			if err == steam.ErrNetTryAgainLater {
-				time.AfterFunc(5*time.Minute, checkApps)
-				// On any other error, just abort today's check
-			} else {
-				reset()
-				time.AfterFunc(time.Until(nextCheck()), checkApps)
-			}
-			return true
+			return err
 		}
 
-		checkApp(s, app)
-		return false
+		c.checkApp(ctx, app)
 	}
 
-	checkApps()
+	return nil
 }
 
 // checkApp goes through every guild tracking app and sends a sale alert
 // to that guild if there is a sale discount that is at least equal to
 // the server's discount threshold.
-func checkApp(s *discordgo.Session, app steam.App) {
-	guilds, err := db.GuildsOf(app.Appid)
+func (c *checker) checkApp(ctx context.Context, app steam.App) {
+	guilds, err := c.store.GuildsOf(ctx, app.Item())
 	if err != nil {
 		return
 	}
 
-	for _, guild := range guilds {
-		updateGuildOnApp(s, app, guild)
+	details := c.saleDetails(ctx, app)
+	fetched := map[int]*steam.App{}
+	for _, guild := range presentGuilds(guilds) {
+		c.updateGuildOnApp(ctx, app, details, guild)
+		c.syncDLC(ctx, app, guild, fetched)
+	}
+}
+
+// presentGuilds filters out the guilds the bot was removed from, which are
+// kept for a grace period. See reconcileGuilds().
+func presentGuilds(guilds []db.GuildInfo) []db.GuildInfo {
+	return slices.DeleteFunc(guilds, func(guild db.GuildInfo) bool {
+		return !guild.MissingSince.IsZero()
+	})
+}
+
+// saleDetails looks up the saleDetails of app. Reviews are only fetched if
+// app is on sale. The lowest alerted is looked up before any alerts are
+// sent, so it's the lowest before today. Packages and bundles have neither.
+func (c *checker) saleDetails(ctx context.Context, app steam.App) saleDetails {
+	if app.Item().Kind != steam.KindApp {
+		return saleDetails{}
+	}
+
+	details := saleDetails{low: c.lowestAlerted(ctx, app.Appid)}
+	if app.Discount == 0 {
+		return details
+	}
+
+	reviews, err := steam.WaitForLimit(ctx, func() (steam.ReviewSummary, error) {
+		return steam.Reviews(ctx, app.Appid)
+	})
+	if err == nil {
+		details.reviews = &reviews
+	}
+	return details
+}
+
+// lowestAlerted gets the sale alert with the deepest discount sent for the
+// app matching appid, or nil if there wasn't one. See db.LowestAlerted().
+func (c *checker) lowestAlerted(ctx context.Context, appid int) *db.HistoryInfo {
+	low, ok, err := c.store.LowestAlerted(ctx, appid)
+	if err != nil || !ok {
+		return nil
 	}
+	return &low
 }
 
-func updateGuildOnApp(s *discordgo.Session, app steam.App, guild db.GuildInfo) {
-	db.SetTrailingSaleDay(guild.ServerID, guild.Appid, app.Discount > 0)
-	db.SetComingSoon(guild.ServerID, guild.Appid, app.ComingSoon)
+// updateGuildOnApp updates the state guild keeps on app and sends
+// it any alerts app warrants. Returns the number of alerts sent.
+func (c *checker) updateGuildOnApp(ctx context.Context, app steam.App, details saleDetails,
+	guild db.GuildInfo) (alerts int) {
+	c.store.SetTrailingSaleDay(ctx, guild.ServerID, guild.Item(), app.Discount > 0)
+	c.store.SetComingSoon(ctx, guild.ServerID, guild.Item(), app.ComingSoon)
 
 	if guild.ChannelID == 0 {
-		return
+		return 0
 	}
-	channelID := strconv.FormatInt(guild.ChannelID, 10)
 
 	if !app.ComingSoon && guild.ComingSoon {
-		s.ChannelMessageSendEmbed(channelID, releaseEmbed(app))
+		c.sendAlert(ctx, guild, app, db.AlertRelease, releaseEmbed(app))
+		alerts++
 	}
 
-	if guild.TrailingSaleDay {
-		return
+	if guild.TrailingSaleDay || !reviewedEnough(details.reviews, guild.MinReviewScore) {
+		return alerts
 	}
 
 	// If app has specific threshold, compare with it.
 	if guild.AppSaleThreshold != 0 {
 		if app.Discount >= guild.AppSaleThreshold {
-			s.ChannelMessageSendEmbed(channelID, saleEmbed(app))
+			c.sendAlert(ctx, guild, app, db.AlertSale, saleEmbed(app, details, guild.HiddenFields))
+			alerts++
 		}
 		// Otherwise, compare with server's general threshold.
 	} else if app.Discount >= guild.SaleThreshold {
-		s.ChannelMessageSendEmbed(channelID, saleEmbed(app))
+		c.sendAlert(ctx, guild, app, db.AlertSale, saleEmbed(app, details, guild.HiddenFields))
+		alerts++
 	}
+
+	return alerts
 }
 
-func releaseEmbed(app steam.App) *discordgo.MessageEmbed {
-	fields := []*discordgo.MessageEmbedField{
-		{
-			Name:  "Price",
-			Value: app.Final,
-		},
-		{
-			Name:  "Description",
-			Value: app.Description,
-		},
+// syncDLC adds the DLC app gained since guild last checked it under guild,
+// and alerts guild about it, if guild tracks the DLC of app. fetched caches
+// the DLC fetched from Steam across guilds. Returns the number of alerts
+// sent.
+func (c *checker) syncDLC(ctx context.Context, app steam.App, guild db.GuildInfo,
+	fetched map[int]*steam.App) (alerts int) {
+	if !guild.TrackDLC || guild.Item().Kind != steam.KindApp {
+		return 0
+	}
+
+	known := slices.Clone(guild.KnownDLC)
+	released := []*steam.App{}
+	for _, appid := range app.DLC {
+		if slices.Contains(guild.KnownDLC, appid) {
+			continue
+		}
+
+		dlc, ok := fetched[appid]
+		if !ok {
+			fetchedDLC, err := steam.WaitForLimit(ctx, func() (steam.App, error) {
+				return steam.NewApp(ctx, appid)
+			})
+			if err != nil {
+				// Left unknown so it's tried again on the next check
+				continue
+			}
+			dlc = &fetchedDLC
+			fetched[appid] = dlc
+		}
+		known = append(known, appid)
+		released = append(released, dlc)
 	}
-	if fields[0].Value == "" {
-		fields[0].Value = "Free"
+	if len(released) == 0 {
+		return 0
 	}
 
-	return &discordgo.MessageEmbed{
-		Title:  fmt.Sprintf("%s has released on Steam!", app.Name),
-		URL:    app.Url(),
-		Image:  &discordgo.MessageEmbedImage{URL: app.Image},
-		Color:  0xFFFFFF,
-		Fields: fields,
+	// Free DLC is still alerted, but there's no sale to track
+	trackable := []*steam.App{}
+	for _, dlc := range released {
+		if dlc.Trackable() {
+			trackable = append(trackable, dlc)
+		}
 	}
+	c.store.AddDLC(ctx, guild.ServerID, app.Appid, trackable)
+	c.store.SetKnownDLC(ctx, guild.ServerID, app.Appid, known)
+
+	if guild.ChannelID == 0 {
+		return 0
+	}
+	c.sendAlert(ctx, guild, app, db.AlertDLC, dlcEmbed(app, released))
+	return 1
 }
 
-func saleEmbed(app steam.App) *discordgo.MessageEmbed {
-	fields := []*discordgo.MessageEmbedField{
-		{
-			Name:   "Original Price",
-			Value:  app.Initial,
-			Inline: true,
-		},
-		{
-			Name:   "Sale Price",
-			Value:  app.Final,
-			Inline: true,
-		},
+// reviewedEnough reports whether reviews meet min, the percent of positive
+// reviews required for sale alerts. Apps without reviews, or whose reviews
+// are unknown, aren't held back.
+func reviewedEnough(reviews *steam.ReviewSummary, min int) bool {
+	if reviews == nil || reviews.Total() == 0 {
+		return true
 	}
-	if app.Reviews > 0 {
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "Reviews",
-			Value:  strconv.Itoa(app.Reviews),
-			Inline: true,
-		})
+	return reviews.Percent() >= min
+}
+
+// checkGuild checks every app tracked by a guild, outside of the schedule.
+// It shares the Steam API rate limit with the scheduled check, waiting until
+// steam.RetryAt() whenever it's rate limited. A check that's already running
+// is waited for first.
+func (c *checker) checkGuild(ctx context.Context, guildID int64,
+	progress func(checked, total int)) (cmd.CheckSummary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	guilds, err := c.store.AppsOf(ctx, guildID)
+	if err != nil {
+		return cmd.CheckSummary{}, err
 	}
-	if app.Description != "" {
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:  "Description",
-			Value: app.Description,
+	guilds = presentGuilds(guilds)
+
+	var summary cmd.CheckSummary
+	for _, guild := range guilds {
+		app, err := steam.WaitForLimit(ctx, func() (steam.App, error) {
+			return steam.NewItem(ctx, guild.Item(), alertFilters...)
 		})
-	}
 
-	return &discordgo.MessageEmbed{
-		Title:  fmt.Sprintf("%s is on sale for %d%% off!", app.Name, app.Discount),
-		URL:    app.Url(),
-		Image:  &discordgo.MessageEmbedImage{URL: app.Image},
-		Color:  discountColor(app.Discount),
-		Fields: fields,
+		if err != nil {
+			summary.Failed++
+		} else {
+			details := c.saleDetails(ctx, app)
+			summary.Alerts += c.updateGuildOnApp(ctx, app, details, guild)
+			summary.Alerts += c.syncDLC(ctx, app, guild, map[int]*steam.App{})
+		}
+		summary.Checked++
+		progress(summary.Checked, len(guilds))
 	}
+
+	return summary, nil
 }
 
-func discountColor(discount int) int {
-	atMost := func(high int) bool {
-		return discount <= high
-	}
-	switch {
-	case atMost(5):
-		return 0x0bff33
-	case atMost(10):
-		return 0x44fdd2
-	case atMost(15):
-		return 0x44fdfd
-	case atMost(20):
-		return 0x44dbfd
-	case atMost(25):
-		return 0x44b6fd
-	case atMost(30):
-		return 0x448bfd
-	case atMost(35):
-		return 0x445afd
-	case atMost(40):
-		return 0x8544fd
-	case atMost(45):
-		return 0xb044fd
-	case atMost(50):
-		return 0xe144fd
-	case atMost(55):
-		return 0xfd44de
-	case atMost(60):
-		return 0xff23a7
-	case atMost(99):
-		return 0xff0000
-	default:
-		return 0xFFFFFF
+// previewAlert renders the alert the guild matching guildID would receive
+// for the app matching appid: a sale alert if it's discounted, otherwise a
+// release alert. Nothing is sent, and no sale state is changed.
+func (c *checker) previewAlert(ctx context.Context, guildID int64,
+	appid int) (*discordgo.MessageEmbed, error) {
+	guild, err := c.store.Guild(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := steam.NewApp(ctx, appid, alertFilters...)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.Discount > 0 {
+		return saleEmbed(app, c.saleDetails(ctx, app), guild.HiddenFields), nil
 	}
+	return releaseEmbed(app), nil
 }
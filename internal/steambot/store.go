@@ -0,0 +1,92 @@
+package steambot
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// checkStore is the data the sale check reads and writes. Each method
+// behaves like the db function of the same name.
+type checkStore interface {
+	Guild(ctx context.Context, guildID int64) (db.DiscordInfo, error)
+	Apps(ctx context.Context) (nextApp func() *steam.Item, close func())
+	AppsOf(ctx context.Context, guildID int64) ([]db.GuildInfo, error)
+	GuildsOf(ctx context.Context, item steam.Item) ([]db.GuildInfo, error)
+	SetTrailingSaleDay(ctx context.Context, guildID int64, item steam.Item, sale bool) error
+	SetComingSoon(ctx context.Context, guildID int64, item steam.Item, comingSoon bool) error
+	AddDLC(ctx context.Context, guildID int64, appid int, dlc []*steam.App) (
+		succ []*steam.App, fail []*steam.App)
+	SetKnownDLC(ctx context.Context, guildID int64, appid int, known []int) error
+
+	QueueAlert(ctx context.Context, guildID, channelID int64, appid int, kind db.AlertKind,
+		embed *discordgo.MessageEmbed) (db.OutboxInfo, error)
+	RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt db.DeliveryAttempt,
+		state db.AlertState) error
+	PendingAlerts(ctx context.Context) ([]db.OutboxInfo, error)
+	RecordHistory(ctx context.Context, info db.HistoryInfo) error
+	LowestAlerted(ctx context.Context, appid int) (low db.HistoryInfo, ok bool, err error)
+}
+
+// dbStore is the checkStore backed by the database.
+type dbStore struct{}
+
+func (dbStore) Guild(ctx context.Context, guildID int64) (db.DiscordInfo, error) {
+	return db.Guild(ctx, guildID)
+}
+
+func (dbStore) Apps(ctx context.Context) (func() *steam.Item, func()) {
+	return db.Apps(ctx)
+}
+
+func (dbStore) AppsOf(ctx context.Context, guildID int64) ([]db.GuildInfo, error) {
+	return db.AppsOf(ctx, guildID)
+}
+
+func (dbStore) GuildsOf(ctx context.Context, item steam.Item) ([]db.GuildInfo, error) {
+	return db.GuildsOf(ctx, item)
+}
+
+func (dbStore) SetTrailingSaleDay(ctx context.Context, guildID int64, item steam.Item,
+	sale bool) error {
+	return db.SetTrailingSaleDay(ctx, guildID, item, sale)
+}
+
+func (dbStore) SetComingSoon(ctx context.Context, guildID int64, item steam.Item,
+	comingSoon bool) error {
+	return db.SetComingSoon(ctx, guildID, item, comingSoon)
+}
+
+func (dbStore) AddDLC(ctx context.Context, guildID int64, appid int, dlc []*steam.App) (
+	[]*steam.App, []*steam.App) {
+	return db.AddDLC(ctx, guildID, appid, dlc)
+}
+
+func (dbStore) SetKnownDLC(ctx context.Context, guildID int64, appid int, known []int) error {
+	return db.SetKnownDLC(ctx, guildID, appid, known)
+}
+
+func (dbStore) QueueAlert(ctx context.Context, guildID, channelID int64, appid int,
+	kind db.AlertKind, embed *discordgo.MessageEmbed) (db.OutboxInfo, error) {
+	return db.QueueAlert(ctx, guildID, channelID, appid, kind, embed)
+}
+
+func (dbStore) RecordAttempt(ctx context.Context, id primitive.ObjectID,
+	attempt db.DeliveryAttempt, state db.AlertState) error {
+	return db.RecordAttempt(ctx, id, attempt, state)
+}
+
+func (dbStore) PendingAlerts(ctx context.Context) ([]db.OutboxInfo, error) {
+	return db.PendingAlerts(ctx)
+}
+
+func (dbStore) RecordHistory(ctx context.Context, info db.HistoryInfo) error {
+	return db.RecordHistory(ctx, info)
+}
+
+func (dbStore) LowestAlerted(ctx context.Context, appid int) (db.HistoryInfo, bool, error) {
+	return db.LowestAlerted(ctx, appid)
+}
@@ -0,0 +1,322 @@
+package steambot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memStore is a checkStore kept in memory.
+type memStore struct {
+	mu        sync.Mutex
+	apps      map[steam.Item]string // App names keyed by item
+	guilds    map[int64]db.DiscordInfo
+	junctions []*db.JunctionInfo
+	outbox    []*db.OutboxInfo
+	history   []db.HistoryInfo
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		apps:   map[steam.Item]string{},
+		guilds: map[int64]db.DiscordInfo{},
+	}
+}
+
+// addGuild adds a guild bound to channelID with a general threshold.
+func (m *memStore) addGuild(guildID, channelID int64, threshold int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.guilds[guildID] = db.DiscordInfo{
+		ServerID:      guildID,
+		ChannelID:     channelID,
+		SaleThreshold: threshold,
+	}
+}
+
+// track adds an app to a guild like db.AddApps(...) does.
+func (m *memStore) track(guildID int64, appid int, name string, junction db.JunctionInfo) {
+	m.trackItem(guildID, steam.AppItem(appid), name, junction)
+}
+
+// trackItem adds an app, package, or bundle to a guild like db.AddApps(...)
+// does.
+func (m *memStore) trackItem(guildID int64, item steam.Item, name string,
+	junction db.JunctionInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apps[item] = name
+	junction.ServerID, junction.Appid, junction.Kind = guildID, item.ID, item.Kind
+	m.junctions = append(m.junctions, &junction)
+}
+
+// junction gets the junction of a guild and app, or nil if there isn't one.
+func (m *memStore) junction(guildID int64, appid int) *db.JunctionInfo {
+	return m.itemJunction(guildID, steam.AppItem(appid))
+}
+
+// itemJunction gets the junction of a guild and item, or nil if there isn't
+// one.
+func (m *memStore) itemJunction(guildID int64, item steam.Item) *db.JunctionInfo {
+	for _, j := range m.junctions {
+		if j.ServerID == guildID && j.Appid == item.ID && j.Kind == item.Kind {
+			return j
+		}
+	}
+	return nil
+}
+
+// guildInfo joins a junction with its app and guild.
+func (m *memStore) guildInfo(j *db.JunctionInfo) (db.GuildInfo, bool) {
+	guild, ok := m.guilds[j.ServerID]
+	if !ok {
+		return db.GuildInfo{}, false
+	}
+	return db.GuildInfo{
+		ServerID:         guild.ServerID,
+		ChannelID:        guild.ChannelID,
+		Appid:            j.Appid,
+		Kind:             j.Kind,
+		AppName:          m.apps[steam.Item{Kind: j.Kind, ID: j.Appid}],
+		AppSaleThreshold: j.SaleThreshold,
+		SaleThreshold:    guild.SaleThreshold,
+		TrailingSaleDay:  j.TrailingSaleDay,
+		ComingSoon:       j.ComingSoon,
+		HiddenFields:     guild.HiddenFields,
+		MinReviewScore:   guild.MinReviewScore,
+		MissingSince:     guild.MissingSince,
+		DLCOf:            j.DLCOf,
+		TrackDLC:         j.TrackDLC,
+		KnownDLC:         j.KnownDLC,
+	}, true
+}
+
+func (m *memStore) Guild(_ context.Context, guildID int64) (db.DiscordInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	guild, ok := m.guilds[guildID]
+	if !ok {
+		return db.DiscordInfo{}, errors.New("no such guild")
+	}
+	return guild, nil
+}
+
+func (m *memStore) Apps(context.Context) (func() *steam.Item, func()) {
+	m.mu.Lock()
+	items := make([]steam.Item, 0, len(m.apps))
+	for item := range m.apps {
+		items = append(items, item)
+	}
+	m.mu.Unlock()
+	slices.SortFunc(items, func(a, b steam.Item) int {
+		if a.Kind != b.Kind {
+			return strings.Compare(string(a.Kind), string(b.Kind))
+		}
+		return a.ID - b.ID
+	})
+
+	next := func() *steam.Item {
+		if len(items) == 0 {
+			return nil
+		}
+		item := items[0]
+		items = items[1:]
+		return &item
+	}
+	return next, func() {}
+}
+
+func (m *memStore) AppsOf(_ context.Context, guildID int64) (guildInfos []db.GuildInfo,
+	err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.guilds[guildID]; !ok {
+		return nil, errors.New("no such guild")
+	}
+	for _, j := range m.junctions {
+		if info, ok := m.guildInfo(j); ok && j.ServerID == guildID {
+			guildInfos = append(guildInfos, info)
+		}
+	}
+	return guildInfos, nil
+}
+
+func (m *memStore) GuildsOf(_ context.Context, item steam.Item) (guildInfos []db.GuildInfo,
+	err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.junctions {
+		if info, ok := m.guildInfo(j); ok && info.Item() == item {
+			guildInfos = append(guildInfos, info)
+		}
+	}
+	return guildInfos, nil
+}
+
+func (m *memStore) SetTrailingSaleDay(_ context.Context, guildID int64, item steam.Item,
+	sale bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if j := m.itemJunction(guildID, item); j != nil {
+		j.TrailingSaleDay = sale
+	}
+	return nil
+}
+
+func (m *memStore) SetComingSoon(_ context.Context, guildID int64, item steam.Item,
+	comingSoon bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if j := m.itemJunction(guildID, item); j != nil {
+		j.ComingSoon = comingSoon
+	}
+	return nil
+}
+
+func (m *memStore) AddDLC(_ context.Context, guildID int64, appid int, dlc []*steam.App) (
+	succ []*steam.App, fail []*steam.App) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, app := range dlc {
+		if m.itemJunction(guildID, app.Item()) == nil {
+			m.apps[app.Item()] = app.Name
+			m.junctions = append(m.junctions, &db.JunctionInfo{
+				Appid:      app.Appid,
+				Kind:       app.Item().Kind,
+				ServerID:   guildID,
+				ComingSoon: app.ComingSoon,
+				DLCOf:      appid,
+			})
+		}
+		succ = append(succ, app)
+	}
+	return succ, nil
+}
+
+func (m *memStore) SetKnownDLC(_ context.Context, guildID int64, appid int,
+	known []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if j := m.junction(guildID, appid); j != nil {
+		j.TrackDLC, j.KnownDLC = true, known
+	}
+	return nil
+}
+
+func (m *memStore) QueueAlert(_ context.Context, guildID, channelID int64, appid int,
+	kind db.AlertKind, embed *discordgo.MessageEmbed) (db.OutboxInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	alert := &db.OutboxInfo{
+		ID:        primitive.NewObjectID(),
+		ServerID:  guildID,
+		ChannelID: channelID,
+		Appid:     appid,
+		Kind:      kind,
+		Embed:     embed,
+		State:     db.AlertPending,
+		CreatedAt: time.Now(),
+	}
+	m.outbox = append(m.outbox, alert)
+	return *alert, nil
+}
+
+func (m *memStore) RecordAttempt(_ context.Context, id primitive.ObjectID,
+	attempt db.DeliveryAttempt, state db.AlertState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, alert := range m.outbox {
+		if alert.ID == id {
+			alert.Attempts = append(alert.Attempts, attempt)
+			alert.State = state
+		}
+	}
+	for i := range m.history {
+		if m.history[i].ID == id {
+			m.history[i].State = state
+		}
+	}
+	return nil
+}
+
+func (m *memStore) PendingAlerts(context.Context) (alerts []db.OutboxInfo, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, alert := range m.outbox {
+		if alert.State == db.AlertPending {
+			alerts = append(alerts, *alert)
+		}
+	}
+	return alerts, nil
+}
+
+func (m *memStore) RecordHistory(_ context.Context, info db.HistoryInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = append(m.history, info)
+	return nil
+}
+
+func (m *memStore) LowestAlerted(_ context.Context, appid int) (low db.HistoryInfo, ok bool,
+	err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range m.history {
+		if h.Item() != steam.AppItem(appid) || h.Kind != db.AlertSale ||
+			h.State != db.AlertSent {
+			continue
+		}
+		if !ok || h.Discount > low.Discount ||
+			(h.Discount == low.Discount && h.SentAt.After(low.SentAt)) {
+			low, ok = h, true
+		}
+	}
+	return low, ok, nil
+}
+
+// recordingSender is an alertSender that records the embeds sent to each
+// channel. Sends fail with err if it's set.
+type recordingSender struct {
+	mu   sync.Mutex
+	sent map[string][]*discordgo.MessageEmbed
+	err  error
+	errs []error // Failures of the next sends, in order, before err applies
+}
+
+func (r *recordingSender) ChannelMessageSendEmbed(channelID string,
+	embed *discordgo.MessageEmbed, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.errs) > 0 {
+		err := r.errs[0]
+		r.errs = r.errs[1:]
+		return nil, err
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.sent == nil {
+		r.sent = map[string][]*discordgo.MessageEmbed{}
+	}
+	r.sent[channelID] = append(r.sent[channelID], embed)
+	return &discordgo.Message{}, nil
+}
+
+// titles gets the titles of the embeds sent to channelID, in order.
+func (r *recordingSender) titles(channelID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	titles := []string{}
+	for _, em := range r.sent[channelID] {
+		titles = append(titles, em.Title)
+	}
+	return titles
+}
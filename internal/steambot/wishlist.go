@@ -0,0 +1,75 @@
+package steambot
+
+import (
+	"context"
+	"log"
+
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
+)
+
+// syncWishlists adds the apps newly added to each synced wishlist to the
+// guild syncing it. Apps that were on the wishlist at the previous sync
+// aren't added again, so removing an app from the tracker sticks. Apps are
+// added as Steam has them, and free apps are skipped since they never go on
+// sale. Apps that failed to be added are tried again on the next sync.
+func syncWishlists(ctx context.Context, wishlists steam.WishlistFetcher) {
+	guilds, err := db.WishlistSyncs(ctx)
+	if err != nil {
+		log.Println("Failed to get synced wishlists:", err)
+		return
+	}
+
+	var added int
+	for _, guild := range guilds {
+		items, err := steam.WaitForLimit(ctx, func() ([]steam.WishlistItem, error) {
+			return wishlists.Wishlist(ctx, guild.WishlistSteamID)
+		})
+		if err != nil {
+			log.Printf("Failed to sync wishlist %d of guild %d: %v",
+				guild.WishlistSteamID, guild.ServerID, err)
+			continue
+		}
+
+		seen := make(map[int]bool, len(guild.WishlistSeen))
+		for _, appid := range guild.WishlistSeen {
+			seen[appid] = true
+		}
+
+		apps := []*steam.App{}
+		current := make([]int, 0, len(items))
+		for _, item := range items {
+			if seen[item.Appid] || item.Free {
+				current = append(current, item.Appid)
+				continue
+			}
+
+			app, err := steam.WaitForLimit(ctx, func() (steam.App, error) {
+				return steam.NewApp(ctx, item.Appid)
+			})
+			if err != nil {
+				log.Printf("Failed to get app %d of wishlist %d: %v",
+					item.Appid, guild.WishlistSteamID, err)
+				continue
+			}
+			if !app.Trackable() {
+				current = append(current, item.Appid)
+				continue
+			}
+			apps = append(apps, &app)
+		}
+
+		if len(apps) > 0 {
+			succ, _ := db.AddApps(ctx, guild.ServerID, apps)
+			added += len(succ)
+			for _, app := range succ {
+				current = append(current, app.Appid)
+			}
+		}
+		if len(current) > 0 {
+			db.SetWishlistSeen(ctx, guild.ServerID, current)
+		}
+	}
+
+	log.Printf("Synced %d wishlists: %d apps added", len(guilds), added)
+}
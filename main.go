@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
 	"github.com/jasonly027/steam_sale_discord_bot_go/internal/db"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/schedule"
+	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steam"
 	"github.com/jasonly027/steam_sale_discord_bot_go/internal/steambot"
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -20,8 +23,20 @@ func main() {
 		log.Fatal("MONGODB_DBNAME not set as env variable or in .env")
 	}
 
-	db.Init(uri, dbName)
-	defer db.Close()
+	ctx := context.Background()
+	db.Init(ctx, uri, dbName)
+	defer db.Close(ctx)
+
+	// `migrate` only brings the database up to date, without starting the bot
+	migrateOnly := len(os.Args) > 1 && os.Args[1] == "migrate"
+	applied, err := db.Migrate(ctx)
+	if err != nil {
+		log.Fatal("Failed to migrate database: ", err)
+	}
+	if migrateOnly {
+		fmt.Printf("Applied %d migration(s), the database is up to date\n", len(applied))
+		return
+	}
 
 	token := os.Getenv("DISCORD_TOKEN")
 	if token == "" {
@@ -34,5 +49,31 @@ func main() {
 		fmt.Println("Dev Mode - Registering commands to test guild", guild)
 	}
 
-	steambot.New(token, guild).Start()
+	sched := schedule.Default()
+	times := os.Getenv("CHECK_TIMES")
+	tz := os.Getenv("CHECK_TIMEZONE")
+	if times != "" || tz != "" {
+		if times == "" {
+			times = "10:05"
+		}
+		if tz == "" {
+			tz = "America/Los_Angeles"
+		}
+
+		sched, err = schedule.Parse(times, tz)
+		if err != nil {
+			log.Fatal("Invalid CHECK_TIMES or CHECK_TIMEZONE: ", err)
+		}
+	}
+	fmt.Println("Checking for sales daily at", sched)
+
+	// Steam can be swapped out, e.g., for a steamtest.Server
+	if url := os.Getenv("STEAM_STORE_URL"); url != "" {
+		steam.StoreURL = url
+	}
+	if url := os.Getenv("STEAM_COMMUNITY_URL"); url != "" {
+		steam.CommunityURL = url
+	}
+
+	steambot.New(token, guild, sched).Start()
 }